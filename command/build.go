@@ -86,6 +86,14 @@ func writeDiags(ui packersdk.Ui, files map[string]*hcl.File, diags hcl.Diagnosti
 }
 
 func (c *BuildCommand) RunContext(buildCtx context.Context, cla *BuildArgs) int {
+	if isBundle(cla.Path) {
+		cleanup, ret := c.openBundle(&cla.MetaArgs)
+		if ret != 0 {
+			return ret
+		}
+		defer cleanup()
+	}
+
 	// Set the release only flag if specified as argument
 	//
 	// This deactivates the capacity for Packer to load development binaries.
@@ -460,6 +468,10 @@ Usage: packer build [options] TEMPLATE
   Will execute multiple builds in parallel as defined in the template.
   The various artifacts created by the template will be outputted.
 
+  TEMPLATE can also be a bundle created by 'packer bundle', in which case
+  the bundled files are extracted in the current directory and only the
+  bundled plugins are used.
+
 Options:
 
   -color=false                  Disable color output. (Default: color)
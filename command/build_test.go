@@ -15,6 +15,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/packer/version"
 )
 
 var (
@@ -174,8 +175,10 @@ func TestBuild(t *testing.T) {
 				testFixture("build-name-and-type"),
 			},
 			fileCheck: fileCheck{
+				expected: []string{"manifest.json.lock"},
 				expectedContent: map[string]string{
-					"manifest.json": `{
+					"manifest.json": fmt.Sprintf(`{
+  "schema_version": 1,
   "builds": [
     {
       "name": "test",
@@ -183,7 +186,12 @@ func TestBuild(t *testing.T) {
       "files": null,
       "artifact_id": "Null",
       "packer_run_uuid": "",
-      "custom_data": null
+      "custom_data": null,
+      "status": "success",
+      "packer_version": %q,
+      "generated_data": {
+        "ID": "Null"
+      }
     },
     {
       "name": "potato",
@@ -191,11 +199,16 @@ func TestBuild(t *testing.T) {
       "files": null,
       "artifact_id": "Null",
       "packer_run_uuid": "",
-      "custom_data": null
+      "custom_data": null,
+      "status": "success",
+      "packer_version": %q,
+      "generated_data": {
+        "ID": "Null"
+      }
     }
   ],
   "last_run_uuid": ""
-}`,
+}`, version.FormattedVersion(), version.FormattedVersion()),
 				},
 			},
 		},
@@ -211,9 +224,11 @@ func TestBuild(t *testing.T) {
 				expected: []string{
 					"null.test.txt",
 					"null.potato.txt",
+					"manifest.json.lock",
 				},
 				expectedContent: map[string]string{
-					"manifest.json": `{
+					"manifest.json": fmt.Sprintf(`{
+  "schema_version": 1,
   "builds": [
     {
       "name": "test",
@@ -221,11 +236,16 @@ func TestBuild(t *testing.T) {
       "files": null,
       "artifact_id": "Null",
       "packer_run_uuid": "",
-      "custom_data": null
+      "custom_data": null,
+      "status": "success",
+      "packer_version": %q,
+      "generated_data": {
+        "ID": "Null"
+      }
     }
   ],
   "last_run_uuid": ""
-}`,
+}`, version.FormattedVersion()),
 				},
 			},
 		},
@@ -238,8 +258,10 @@ func TestBuild(t *testing.T) {
 				filepath.Join(testFixture("build-name-and-type"), "all.json"),
 			},
 			fileCheck: fileCheck{
+				expected: []string{"manifest.json.lock"},
 				expectedContent: map[string]string{
-					"manifest.json": `{
+					"manifest.json": fmt.Sprintf(`{
+  "schema_version": 1,
   "builds": [
     {
       "name": "potato",
@@ -247,11 +269,16 @@ func TestBuild(t *testing.T) {
       "files": null,
       "artifact_id": "Null",
       "packer_run_uuid": "",
-      "custom_data": null
+      "custom_data": null,
+      "status": "success",
+      "packer_version": %q,
+      "generated_data": {
+        "ID": "Null"
+      }
     }
   ],
   "last_run_uuid": ""
-}`,
+}`, version.FormattedVersion()),
 				},
 			},
 		},
@@ -840,8 +867,10 @@ func TestHCL2PostProcessorForceFlag(t *testing.T) {
 		filepath.Join(testFixture("hcl"), "force.pkr.hcl"),
 	}
 	fCheck := fileCheck{
+		expected: []string{"manifest.json.lock"},
 		expectedContent: map[string]string{
 			"manifest.json": fmt.Sprintf(`{
+  "schema_version": 1,
   "builds": [
     {
       "name": "potato",
@@ -849,11 +878,16 @@ func TestHCL2PostProcessorForceFlag(t *testing.T) {
       "files": null,
       "artifact_id": "Null",
       "packer_run_uuid": %q,
-      "custom_data": null
+      "custom_data": null,
+      "status": "success",
+      "packer_version": %q,
+      "generated_data": {
+        "ID": "Null"
+      }
     }
   ],
   "last_run_uuid": %q
-}`, UUID, UUID),
+}`, UUID, version.FormattedVersion(), UUID),
 		},
 	}
 	defer fCheck.cleanup(t)
@@ -875,8 +909,10 @@ func TestHCL2PostProcessorForceFlag(t *testing.T) {
 		filepath.Join(testFixture("hcl"), "force.pkr.hcl"),
 	}
 	fCheck = fileCheck{
+		expected: []string{"manifest.json.lock"},
 		expectedContent: map[string]string{
 			"manifest.json": fmt.Sprintf(`{
+  "schema_version": 1,
   "builds": [
     {
       "name": "potato",
@@ -884,11 +920,16 @@ func TestHCL2PostProcessorForceFlag(t *testing.T) {
       "files": null,
       "artifact_id": "Null",
       "packer_run_uuid": %q,
-      "custom_data": null
+      "custom_data": null,
+      "status": "success",
+      "packer_version": %q,
+      "generated_data": {
+        "ID": "Null"
+      }
     }
   ],
   "last_run_uuid": %q
-}`, UUID, UUID),
+}`, UUID, version.FormattedVersion(), UUID),
 		},
 	}
 
@@ -1158,6 +1199,110 @@ func TestBuildCommand_ParseArgs(t *testing.T) {
 	}
 }
 
+func TestProvisionerOnlyIf(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		expected    []string
+		notExpected []string
+	}{
+		{
+			"only_if on source name",
+			[]string{
+				testFixture("provisioners", "provisioner-only-if.pkr.hcl"),
+			},
+			[]string{
+				"conditional provisioner packer",
+				"null instance provisioner packer",
+				"null instance provisioner other",
+				"Skipping the shell-local provisioner: its only_if condition is false",
+			},
+			[]string{
+				"conditional provisioner other",
+				"other instance provisioner",
+			},
+		},
+		{
+			"only_if on variable",
+			[]string{
+				"-var", "run_other=true",
+				testFixture("provisioners", "provisioner-only-if.pkr.hcl"),
+			},
+			[]string{
+				"conditional provisioner packer",
+				"conditional provisioner other",
+			},
+			[]string{
+				"other instance provisioner",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &BuildCommand{
+				Meta: TestMetaFile(t),
+			}
+
+			if code := c.Run(tt.args); code != 0 {
+				fatalCommand(t, c.Meta)
+			}
+
+			out, _ := GetStdoutAndErrFromTestMeta(t, c.Meta)
+			for _, s := range tt.expected {
+				if !strings.Contains(out, s) {
+					t.Errorf("expected output to contain %q, got:\n%s", s, out)
+				}
+			}
+			for _, s := range tt.notExpected {
+				if strings.Contains(out, s) {
+					t.Errorf("expected output to not contain %q, got:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
+
+func TestProvisionerLogFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the provisioner runs a shell script")
+	}
+
+	fixture, err := filepath.Abs(testFixture("provisioners", "provisioner-log-file.pkr.hcl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(t.TempDir())
+
+	c := &BuildCommand{
+		Meta: TestMetaFile(t),
+	}
+	if code := c.Run([]string{fixture}); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	// build.ID is only known once the builder ran.
+	content, err := os.ReadFile(filepath.Join("logs", "harden-packer-Null.log"))
+	if err != nil {
+		t.Fatalf("failed to read log file: %s", err)
+	}
+	log := string(content)
+	// shell-local runs its commands itself, their output is only seen
+	// through the UI.
+	for _, expected := range []string{
+		"[ui] Running local shell script",
+		"[ui] using <sensitive>",
+		"[ui-error] oops",
+	} {
+		if !strings.Contains(log, expected) {
+			t.Errorf("expected log file to contain %q, got:\n%s", expected, log)
+		}
+	}
+	if strings.Contains(log, "s3cr3t-t0k3n") {
+		t.Errorf("expected the sensitive value to be scrubbed, got:\n%s", log)
+	}
+}
+
 // TestProvisionerOnlyExcept checks that only/except blocks in provisioners/post-processors behave as expected
 func TestProvisionerAndPostProcessorOnlyExcept(t *testing.T) {
 	tests := []struct {
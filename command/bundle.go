@@ -0,0 +1,560 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/posener/complete"
+)
+
+const (
+	// bundleManifestName is the name of the file describing the content of
+	// a bundle, at the root of the archive.
+	bundleManifestName = "bundle.json"
+	// bundleSourcesDir holds the files of the bundle, relative to the
+	// directory packer bundle was called from.
+	bundleSourcesDir = "src"
+	// bundlePluginsDir holds the plugin binaries of the bundle, relative to
+	// the plugin directory.
+	bundlePluginsDir = "plugins"
+)
+
+// bundleManifest describes what a bundle contains and how to build it.
+type bundleManifest struct {
+	// Template is the path to the template or directory that was bundled.
+	Template string `json:"template"`
+	// VarFiles are the var files passed with -var-file.
+	VarFiles []string `json:"var_files,omitempty"`
+	// Vars are the variables passed with -var.
+	Vars map[string]string `json:"vars,omitempty"`
+	// Platforms are the os_arch pairs plugins were bundled for.
+	Platforms []string `json:"platforms"`
+	// Plugins are the bundled plugin binaries.
+	Plugins []string `json:"plugins"`
+}
+
+type BundleCommand struct {
+	Meta
+}
+
+func (c *BundleCommand) Run(args []string) int {
+	ctx := context.Background()
+
+	cfg, ret := c.ParseArgs(args)
+	if ret != 0 {
+		return ret
+	}
+
+	return c.RunContext(ctx, cfg)
+}
+
+func (c *BundleCommand) ParseArgs(args []string) (*BundleArgs, int) {
+	var cfg BundleArgs
+	flags := c.Meta.FlagSet("bundle")
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	cfg.AddFlagSets(flags)
+	if err := flags.Parse(args); err != nil {
+		return &cfg, 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		flags.Usage()
+		return &cfg, 1
+	}
+	cfg.Path = args[0]
+	if len(cfg.Platforms) == 0 {
+		cfg.Platforms = []string{runtime.GOOS + "_" + runtime.GOARCH}
+	}
+	return &cfg, 0
+}
+
+func (c *BundleCommand) RunContext(ctx context.Context, cla *BundleArgs) int {
+	cfgType, err := cla.GetConfigType()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("%q: %s", cla.Path, err))
+		return 1
+	}
+	if cfgType != ConfigTypeHCL2 {
+		c.Ui.Error("packer bundle only supports HCL2 templates")
+		return 1
+	}
+
+	cfg, ret := c.GetConfigFromHCL(&cla.MetaArgs)
+	if ret != 0 {
+		return ret
+	}
+
+	diags := cfg.DetectPluginBinaries()
+	ret = writeDiags(c.Ui, nil, diags)
+	if ret != 0 {
+		return ret
+	}
+
+	// Data sources are not executed: they will be when the bundle is built,
+	// and paths depending on them are reported.
+	diags = cfg.Initialize(packer.InitializeOptions{
+		SkipDatasourcesExecution: true,
+		UseSequential:            cla.UseSequential,
+	})
+	ret = writeDiags(c.Ui, nil, diags)
+	if ret != 0 {
+		return ret
+	}
+
+	var plugins []string
+	for _, platform := range cla.Platforms {
+		goos, goarch, found := strings.Cut(platform, "_")
+		if !found || goos == "" || goarch == "" {
+			c.Ui.Error(fmt.Sprintf("invalid platform %q, expected os_arch, for example linux_amd64", platform))
+			return 1
+		}
+		paths, diags := cfg.PluginBinaries(goos, goarch)
+		ret = writeDiags(c.Ui, nil, diags)
+		if ret != 0 {
+			return ret
+		}
+		plugins = append(plugins, paths...)
+	}
+
+	files, diags := cfg.BundleFiles()
+	ret = writeDiags(c.Ui, nil, diags)
+	if ret != 0 {
+		return ret
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to get current directory: %s", err))
+		return 1
+	}
+
+	manifest := bundleManifest{
+		Vars:      cla.Vars,
+		Platforms: cla.Platforms,
+	}
+	manifest.Template, err = bundleRelPath(wd, cla.Path)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	for _, varFile := range cla.VarFiles {
+		rel, err := bundleRelPath(wd, varFile)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+		manifest.VarFiles = append(manifest.VarFiles, rel)
+	}
+
+	output, err := filepath.Abs(cla.Output)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to resolve output path: %s", err))
+		return 1
+	}
+
+	bw, err := newBundleWriter(output)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to create bundle: %s", err))
+		return 1
+	}
+	defer bw.Close()
+
+	pluginDir := c.CoreConfig.Components.PluginConfig.PluginDirectory
+	for _, plugin := range plugins {
+		rel, err := filepath.Rel(pluginDir, plugin)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to bundle plugin %q: %s", plugin, err))
+			return 1
+		}
+		if err := bw.Add(plugin, path.Join(bundlePluginsDir, filepath.ToSlash(rel))); err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to bundle plugin %q: %s", plugin, err))
+			return 1
+		}
+		if !strings.HasSuffix(plugin, "SUM") {
+			manifest.Plugins = append(manifest.Plugins, filepath.ToSlash(rel))
+		}
+	}
+
+	for _, file := range files {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			c.Ui.Say(fmt.Sprintf("Warning: %q does not exist and was not bundled", file))
+			continue
+		}
+		rel, err := bundleRelPath(wd, file)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+		if err := bw.Add(file, path.Join(bundleSourcesDir, rel)); err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to bundle %q: %s", file, err))
+			return 1
+		}
+	}
+
+	manifestContent, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to encode bundle manifest: %s", err))
+		return 1
+	}
+	if err := bw.AddContent(bundleManifestName, manifestContent); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to write bundle manifest: %s", err))
+		return 1
+	}
+
+	if err := bw.Close(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to write bundle: %s", err))
+		return 1
+	}
+
+	c.Ui.Say(fmt.Sprintf("Bundled %s with %d plugin(s) for %s into %s",
+		cla.Path, len(manifest.Plugins), strings.Join(cla.Platforms, ", "), cla.Output))
+	return 0
+}
+
+// bundleRelPath returns p relative to wd, using forward slashes. Files
+// outside of wd cannot be bundled.
+func bundleRelPath(wd, p string) (string, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("cannot bundle %q: only files within the current directory %q can be bundled", p, wd)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// bundleWriter writes files to a gzipped tarball.
+type bundleWriter struct {
+	output string
+	f      *os.File
+	gz     *gzip.Writer
+	tw     *tar.Writer
+	added  map[string]bool
+}
+
+func newBundleWriter(output string) (*bundleWriter, error) {
+	f, err := os.Create(output)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(f)
+	return &bundleWriter{
+		output: output,
+		f:      f,
+		gz:     gz,
+		tw:     tar.NewWriter(gz),
+		added:  map[string]bool{},
+	}, nil
+}
+
+// Add adds the file or directory at src to the bundle under name.
+// Directories are added recursively.
+func (bw *bundleWriter) Add(src, name string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == bw.output {
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		entry := path.Join(name, filepath.ToSlash(rel))
+		if bw.added[entry] {
+			return nil
+		}
+		bw.added[entry] = true
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			log.Printf("[WARN] skipping %q: not a regular file", p)
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = entry
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := bw.tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(bw.tw, f)
+		return err
+	})
+}
+
+// AddContent adds a file with content to the bundle under name.
+func (bw *bundleWriter) AddContent(name string, content []byte) error {
+	err := bw.tw.WriteHeader(&tar.Header{
+		Name: name,
+		Mode: 0644,
+		Size: int64(len(content)),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = bw.tw.Write(content)
+	return err
+}
+
+func (bw *bundleWriter) Close() error {
+	if bw.f == nil {
+		return nil
+	}
+	f := bw.f
+	bw.f = nil
+	if err := bw.tw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.gz.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// isBundle tells whether path points to an archive created by packer bundle.
+func isBundle(path string) bool {
+	if !strings.HasSuffix(path, ".tar.gz") && !strings.HasSuffix(path, ".tgz") {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// openBundle extracts the bundle at cla.Path so it can be built without
+// network access: the bundled files are extracted in the current directory,
+// where packer bundle was run from, and the plugins in a temporary plugin
+// directory that replaces the configured one. cla is updated to point to
+// the bundled template.
+//
+// The returned function removes the temporary plugin directory.
+func (m *Meta) openBundle(cla *MetaArgs) (func(), int) {
+	pluginDir, err := os.MkdirTemp("", "packer-bundle-plugins")
+	if err != nil {
+		m.Ui.Error(fmt.Sprintf("Failed to create plugin directory: %s", err))
+		return nil, 1
+	}
+	cleanup := func() { os.RemoveAll(pluginDir) }
+
+	wd, err := os.Getwd()
+	if err != nil {
+		cleanup()
+		m.Ui.Error(fmt.Sprintf("Failed to get current directory: %s", err))
+		return nil, 1
+	}
+
+	manifest, err := extractBundle(cla.Path, wd, pluginDir)
+	if err != nil {
+		cleanup()
+		m.Ui.Error(fmt.Sprintf("Failed to extract bundle %q: %s", cla.Path, err))
+		return nil, 1
+	}
+
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+	if !slices.Contains(manifest.Platforms, platform) {
+		cleanup()
+		m.Ui.Error(fmt.Sprintf("Bundle %q does not contain plugins for %s, only for: %s",
+			cla.Path, platform, strings.Join(manifest.Platforms, ", ")))
+		return nil, 1
+	}
+
+	log.Printf("[INFO] building bundled template %q with plugins from %q", manifest.Template, pluginDir)
+	cla.Path = filepath.FromSlash(manifest.Template)
+	var varFiles []string
+	for _, varFile := range manifest.VarFiles {
+		varFiles = append(varFiles, filepath.FromSlash(varFile))
+	}
+	cla.VarFiles = append(varFiles, cla.VarFiles...)
+	// Variables passed to packer build take precedence over the bundled
+	// ones, like var files.
+	vars := map[string]string{}
+	for k, v := range manifest.Vars {
+		vars[k] = v
+	}
+	for k, v := range cla.Vars {
+		vars[k] = v
+	}
+	cla.Vars = vars
+
+	pluginConfig := m.CoreConfig.Components.PluginConfig
+	pluginConfig.PluginDirectory = pluginDir
+	pluginConfig.DevOverrides = nil
+
+	return cleanup, 0
+}
+
+// extractBundle extracts the sources of the bundle at bundlePath into
+// srcDir, and its plugins into pluginDir. Existing files are only accepted
+// if they have the same content as the bundled ones.
+func extractBundle(bundlePath, srcDir, pluginDir string) (*bundleManifest, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var manifest *bundleManifest
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean(hdr.Name)
+		if name == bundleManifestName {
+			manifest = &bundleManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", bundleManifestName, err)
+			}
+			continue
+		}
+
+		dir, rel, _ := strings.Cut(name, "/")
+		if rel == "" || !filepath.IsLocal(filepath.FromSlash(rel)) {
+			if dir == bundleSourcesDir || dir == bundlePluginsDir {
+				continue
+			}
+			return nil, fmt.Errorf("unexpected entry %q", hdr.Name)
+		}
+		var target string
+		switch dir {
+		case bundleSourcesDir:
+			target = filepath.Join(srcDir, filepath.FromSlash(rel))
+		case bundlePluginsDir:
+			target = filepath.Join(pluginDir, filepath.FromSlash(rel))
+		default:
+			return nil, fmt.Errorf("unexpected entry %q", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if err := extractBundleFile(tr, target, hdr.FileInfo().Mode().Perm()); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected entry type for %q", hdr.Name)
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("%s not found, this is not a bundle created by packer bundle", bundleManifestName)
+	}
+	return manifest, nil
+}
+
+func extractBundleFile(r io.Reader, target string, mode os.FileMode) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	existing, err := os.ReadFile(target)
+	if err == nil {
+		if !bytes.Equal(existing, content) {
+			return fmt.Errorf("%q already exists and differs from the bundled file", target)
+		}
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, content, mode)
+}
+
+func (*BundleCommand) Help() string {
+	helpText := `
+Usage: packer bundle [options] TEMPLATE
+
+  Packages an HCL2 template with everything needed to build it without
+  network access: the config and variable files, the installed plugin
+  binaries required by the template, and the local files read by file
+  functions, file provisioners and provisioner scripts.
+
+  All bundled files must be within the current directory. The bundle can
+  then be built with 'packer build bundle.tar.gz', which extracts these
+  files in the current directory.
+
+  The -var and -var-file options are recorded in the bundle and used to
+  build it. Variables passed to packer build take precedence over them.
+
+Options:
+
+  -o=bundle.tar.gz              Path of the bundle to create. (Default: bundle.tar.gz)
+  -platform=linux_amd64         Platforms to bundle plugins for, separated by commas. (Default: the current platform)
+  -var 'key=value'              Variable for templates, recorded in the bundle, can be used multiple times.
+  -var-file=path                JSON or HCL2 file containing user variables, bundled, can be used multiple times.
+  -use-sequential-evaluation    Fallback to using a sequential approach for local/datasource evaluation.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*BundleCommand) Synopsis() string {
+	return "package a template with its plugins and files"
+}
+
+func (*BundleCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (*BundleCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-o":        complete.PredictNothing,
+		"-platform": complete.PredictNothing,
+		"-var":      complete.PredictNothing,
+		"-var-file": complete.PredictNothing,
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func listBundle(t *testing.T, bundle string) []string {
+	f, err := os.Open(bundle)
+	if err != nil {
+		t.Fatalf("failed to open bundle: %s", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read bundle: %s", err)
+	}
+	var entries []string
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read bundle: %s", err)
+		}
+		entries = append(entries, hdr.Name)
+	}
+	slices.Sort(entries)
+	return entries
+}
+
+func TestBundle(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the bundled provisioner runs a shell script")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "bundle.tar.gz")
+
+	c := &BundleCommand{
+		Meta: TestMetaFile(t),
+	}
+	if code := c.Run([]string{"-o", bundle, testFixture("bundle", "build.pkr.hcl")}); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	expected := []string{
+		"bundle.json",
+		"src/test-fixtures/bundle/build.pkr.hcl",
+		"src/test-fixtures/bundle/data.txt",
+		"src/test-fixtures/bundle/script.sh",
+	}
+	if entries := listBundle(t, bundle); !slices.Equal(entries, expected) {
+		t.Fatalf("unexpected bundle content, expected %v, got %v", expected, entries)
+	}
+
+	// Build the bundle from an empty directory
+	t.Chdir(t.TempDir())
+
+	b := &BuildCommand{
+		Meta: TestMetaFile(t),
+	}
+	if code := b.Run([]string{bundle}); code != 0 {
+		fatalCommand(t, b.Meta)
+	}
+
+	fc := fileCheck{
+		expectedContent: map[string]string{
+			"bundled.txt": "bundled content\n",
+		},
+	}
+	fc.verify(t, ".")
+
+	out, _ := GetStdoutAndErrFromTestMeta(t, b.Meta)
+	if !strings.Contains(out, "hello from the bundle") {
+		t.Errorf("expected the bundled script to run, got:\n%s", out)
+	}
+}
+
+func TestBundle_vars(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the bundled provisioner runs a shell script")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "bundle.tar.gz")
+
+	c := &BundleCommand{
+		Meta: TestMetaFile(t),
+	}
+	args := []string{
+		"-o", bundle,
+		"-var-file", testFixture("bundle", "greeting.pkrvars.hcl"),
+		"-var", "target=vars.txt",
+		testFixture("bundle", "build.pkr.hcl"),
+	}
+	if code := c.Run(args); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	t.Chdir(t.TempDir())
+
+	// The variables are recorded in the bundle.
+	b := &BuildCommand{
+		Meta: TestMetaFile(t),
+	}
+	if code := b.Run([]string{bundle}); code != 0 {
+		fatalCommand(t, b.Meta)
+	}
+	fc := fileCheck{
+		expected:    []string{"vars.txt"},
+		notExpected: []string{"bundled.txt"},
+	}
+	fc.verify(t, ".")
+	out, _ := GetStdoutAndErrFromTestMeta(t, b.Meta)
+	if !strings.Contains(out, "hi from the bundle") {
+		t.Errorf("expected the bundled var file to be used, got:\n%s", out)
+	}
+
+	// The variables passed to packer build take precedence.
+	b = &BuildCommand{
+		Meta: TestMetaFile(t),
+	}
+	if code := b.Run([]string{"-force", "-var", "greeting=bonjour", bundle}); code != 0 {
+		fatalCommand(t, b.Meta)
+	}
+	out, _ = GetStdoutAndErrFromTestMeta(t, b.Meta)
+	if !strings.Contains(out, "bonjour from the bundle") {
+		t.Errorf("expected the variables passed to packer build to be used, got:\n%s", out)
+	}
+}
+
+func TestBundle_outsideCwd(t *testing.T) {
+	c := &BundleCommand{
+		Meta: TestMetaFile(t),
+	}
+	fixture, err := filepath.Abs(testFixture("bundle", "build.pkr.hcl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Chdir(t.TempDir())
+	if code := c.Run([]string{"-o", "bundle.tar.gz", fixture}); code == 0 {
+		t.Fatalf("expected bundling files outside of the current directory to fail")
+	}
+}
+
+func TestBuild_bundleConflict(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	c := &BundleCommand{
+		Meta: TestMetaFile(t),
+	}
+	if code := c.Run([]string{"-o", bundle, testFixture("bundle", "build.pkr.hcl")}); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.MkdirAll(filepath.Join("test-fixtures", "bundle"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("test-fixtures", "bundle", "data.txt"), []byte("local changes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &BuildCommand{
+		Meta: TestMetaFile(t),
+	}
+	if code := b.Run([]string{bundle}); code == 0 {
+		t.Fatalf("expected building over a modified file to fail")
+	}
+	content, _ := os.ReadFile(filepath.Join("test-fixtures", "bundle", "data.txt"))
+	if string(content) != "local changes\n" {
+		t.Errorf("local file was overwritten: %q", content)
+	}
+}
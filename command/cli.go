@@ -141,6 +141,21 @@ type BuildArgs struct {
 	SkipEnforcement                     bool
 }
 
+func (ba *BundleArgs) AddFlagSets(flags *flag.FlagSet) {
+	flags.StringVar(&ba.Output, "o", "bundle.tar.gz", "")
+	flags.Var((*sliceflag.StringFlag)(&ba.Platforms), "platform", "")
+	flags.BoolVar(&ba.MetaArgs.UseSequential, "use-sequential-evaluation", false, "Fallback to using a sequential approach for local/datasource evaluation.")
+
+	ba.MetaArgs.AddFlagSets(flags)
+}
+
+// BundleArgs represents a parsed cli line for a `packer bundle`
+type BundleArgs struct {
+	MetaArgs
+	Output    string
+	Platforms []string
+}
+
 func (ia *InitArgs) AddFlagSets(flags *flag.FlagSet) {
 	flags.BoolVar(&ia.Upgrade, "upgrade", false, "upgrade any present plugin to the highest allowed version.")
 	flags.BoolVar(&ia.Force, "force", false, "force installation of a plugin, even if already installed")
@@ -15,6 +15,8 @@ import (
 
 	filebuilder "github.com/hashicorp/packer/builder/file"
 	nullbuilder "github.com/hashicorp/packer/builder/null"
+	externaldatasource "github.com/hashicorp/packer/datasource/external"
+	gitdatasource "github.com/hashicorp/packer/datasource/git"
 	hcppackerartifactdatasource "github.com/hashicorp/packer/datasource/hcp-packer-artifact"
 	hcppackerimagedatasource "github.com/hashicorp/packer/datasource/hcp-packer-image"
 	hcppackeriterationdatasource "github.com/hashicorp/packer/datasource/hcp-packer-iteration"
@@ -24,13 +26,18 @@ import (
 	artificepostprocessor "github.com/hashicorp/packer/post-processor/artifice"
 	checksumpostprocessor "github.com/hashicorp/packer/post-processor/checksum"
 	compresspostprocessor "github.com/hashicorp/packer/post-processor/compress"
+	localexportpostprocessor "github.com/hashicorp/packer/post-processor/local-export"
 	manifestpostprocessor "github.com/hashicorp/packer/post-processor/manifest"
+	ocipushpostprocessor "github.com/hashicorp/packer/post-processor/oci-push"
 	provenancepostprocessor "github.com/hashicorp/packer/post-processor/provenance"
+	s3uploadpostprocessor "github.com/hashicorp/packer/post-processor/s3-upload"
 	shelllocalpostprocessor "github.com/hashicorp/packer/post-processor/shell-local"
 	breakpointprovisioner "github.com/hashicorp/packer/provisioner/breakpoint"
 	fileprovisioner "github.com/hashicorp/packer/provisioner/file"
 	hcpsbomprovisioner "github.com/hashicorp/packer/provisioner/hcp-sbom"
+	packagesprovisioner "github.com/hashicorp/packer/provisioner/packages"
 	powershellprovisioner "github.com/hashicorp/packer/provisioner/powershell"
+	restartprovisioner "github.com/hashicorp/packer/provisioner/restart"
 	shellprovisioner "github.com/hashicorp/packer/provisioner/shell"
 	shelllocalprovisioner "github.com/hashicorp/packer/provisioner/shell-local"
 	sleepprovisioner "github.com/hashicorp/packer/provisioner/sleep"
@@ -51,7 +58,9 @@ var Provisioners = map[string]packersdk.Provisioner{
 	"breakpoint":      new(breakpointprovisioner.Provisioner),
 	"file":            new(fileprovisioner.Provisioner),
 	"hcp-sbom":        new(hcpsbomprovisioner.Provisioner),
+	"packages":        new(packagesprovisioner.Provisioner),
 	"powershell":      new(powershellprovisioner.Provisioner),
+	"restart":         new(restartprovisioner.Provisioner),
 	"shell":           new(shellprovisioner.Provisioner),
 	"shell-local":     new(shelllocalprovisioner.Provisioner),
 	"sleep":           new(sleepprovisioner.Provisioner),
@@ -60,15 +69,20 @@ var Provisioners = map[string]packersdk.Provisioner{
 }
 
 var PostProcessors = map[string]packersdk.PostProcessor{
-	"artifice":    new(artificepostprocessor.PostProcessor),
-	"checksum":    new(checksumpostprocessor.PostProcessor),
-	"compress":    new(compresspostprocessor.PostProcessor),
-	"manifest":    new(manifestpostprocessor.PostProcessor),
-	"provenance":  new(provenancepostprocessor.PostProcessor),
-	"shell-local": new(shelllocalpostprocessor.PostProcessor),
+	"artifice":     new(artificepostprocessor.PostProcessor),
+	"checksum":     new(checksumpostprocessor.PostProcessor),
+	"compress":     new(compresspostprocessor.PostProcessor),
+	"local-export": new(localexportpostprocessor.PostProcessor),
+	"manifest":     new(manifestpostprocessor.PostProcessor),
+	"oci-push":     new(ocipushpostprocessor.PostProcessor),
+	"provenance":   new(provenancepostprocessor.PostProcessor),
+	"s3-upload":    new(s3uploadpostprocessor.PostProcessor),
+	"shell-local":  new(shelllocalpostprocessor.PostProcessor),
 }
 
 var Datasources = map[string]packersdk.Datasource{
+	"external":             new(externaldatasource.Datasource),
+	"git":                  new(gitdatasource.Datasource),
 	"hcp-packer-artifact":  new(hcppackerartifactdatasource.Datasource),
 	"hcp-packer-image":     new(hcppackerimagedatasource.Datasource),
 	"hcp-packer-iteration": new(hcppackeriterationdatasource.Datasource),
@@ -0,0 +1,21 @@
+variable "greeting" {
+  default = "hello"
+}
+
+variable "target" {
+  default = "bundled.txt"
+}
+
+source "file" "bundle" {
+  content = file("data.txt")
+  target  = var.target
+}
+
+build {
+  sources = ["source.file.bundle"]
+
+  provisioner "shell-local" {
+    environment_vars = ["GREETING=${var.greeting}"]
+    script           = "${path.root}/script.sh"
+  }
+}
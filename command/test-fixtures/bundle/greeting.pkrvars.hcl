@@ -0,0 +1 @@
+greeting = "hi"
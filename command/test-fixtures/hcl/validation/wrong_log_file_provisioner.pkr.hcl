@@ -0,0 +1,13 @@
+source "null" "example1" {
+  communicator = "none"
+}
+
+build {
+  sources = ["source.null.example1"]
+
+  provisioner "file" {
+    content     = "hello"
+    destination = "/tmp/hello"
+    log_file    = "logs/file.log"
+  }
+}
@@ -0,0 +1,12 @@
+source "null" "example1" {
+  communicator = "none"
+}
+
+build {
+  sources = ["source.null.example1"]
+
+  provisioner "shell-local" {
+    only_if = var.missing
+    inline  = ["echo Should I run?"]
+  }
+}
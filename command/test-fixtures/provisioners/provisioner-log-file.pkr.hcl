@@ -0,0 +1,19 @@
+variable "token" {
+	type      = string
+	default   = "s3cr3t-t0k3n"
+	sensitive = true
+}
+
+source "null" "packer" {
+	communicator = "none"
+}
+
+build {
+	name    = "harden"
+	sources = ["null.packer"]
+
+	provisioner "shell-local" {
+		inline   = ["echo using ${var.token}", "echo oops 1>&2"]
+		log_file = "logs/${build.name}-${source.name}-${build.ID}.log"
+	}
+}
@@ -0,0 +1,31 @@
+variable "run_other" {
+	type    = bool
+	default = false
+}
+
+source "null" "packer" {
+	communicator = "none"
+}
+
+source "null" "other" {
+	communicator = "none"
+}
+
+build {
+	sources = ["null.packer", "null.other"]
+
+	provisioner "shell-local" {
+		inline  = ["echo conditional provisioner {{build_name}}"]
+		only_if = source.name == "packer" || var.run_other
+	}
+
+	provisioner "shell-local" {
+		inline  = ["echo null instance provisioner {{build_name}}"]
+		only_if = build.ID == "Null"
+	}
+
+	provisioner "shell-local" {
+		inline  = ["echo other instance provisioner {{build_name}}"]
+		only_if = build.ID != "Null"
+	}
+}
@@ -28,6 +28,11 @@ func TestValidateCommand(t *testing.T) {
 		{path: filepath.Join(testFixture("validate"), "var_foo_with_no_default.pkr.hcl"), exitCode: 1},
 
 		{path: testFixture("hcl", "validation", "wrong_pause_before.pkr.hcl"), exitCode: 1},
+		{path: testFixture("hcl", "validation", "wrong_only_if_reference.pkr.hcl"), exitCode: 1},
+		{path: testFixture("hcl", "validation", "wrong_only_if_type.pkr.hcl"), exitCode: 1},
+		{path: testFixture("hcl", "validation", "wrong_log_file_provisioner.pkr.hcl"), exitCode: 1},
+		{path: testFixture("provisioners", "provisioner-log-file.pkr.hcl")},
+		{path: testFixture("provisioners", "provisioner-only-if.pkr.hcl")},
 
 		// wrong version fails
 		{path: filepath.Join(testFixture("version_req", "base_failure")), exitCode: 1},
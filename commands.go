@@ -23,6 +23,9 @@ func init() {
 		"build": func() (cli.Command, error) {
 			return &command.BuildCommand{Meta: *CommandMeta}, nil
 		},
+		"bundle": func() (cli.Command, error) {
+			return &command.BundleCommand{Meta: *CommandMeta}, nil
+		},
 		"console": func() (cli.Command, error) {
 			return &command.ConsoleCommand{
 				Meta: *CommandMeta,
@@ -22,6 +22,9 @@ type config struct {
 	RawBuilders                map[string]string `json:"builders"`
 	RawProvisioners            map[string]string `json:"provisioners"`
 	RawPostProcessors          map[string]string `json:"post-processors"`
+	// DevOverrides maps plugin sources to locally built plugin binaries
+	// used instead of the installed ones, for plugin development.
+	DevOverrides map[string]string `json:"dev_overrides"`
 
 	Plugins *packer.PluginConfig
 }
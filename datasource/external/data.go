@@ -0,0 +1,215 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type DatasourceOutput,Config
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// ResultKey is the output holding the JSON object printed by the program.
+// Its type depends on the object, so it is not part of the output spec and is
+// added to the output value by Execute.
+const ResultKey = "result"
+
+// The external data source runs a local program, sends it `query` as a JSON
+// object on its standard input, and exposes the JSON object the program
+// prints on its standard output as `result`.
+//
+// ```hcl
+//
+//	data "external" "cmdb" {
+//	  program = ["python3", "${path.root}/scripts/cmdb.py"]
+//	  query = {
+//	    hostname = var.hostname
+//	  }
+//	}
+//
+// ```
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	// The program to run and its arguments. A relative program path is
+	// looked up in `working_dir`.
+	Program []string `mapstructure:"program" required:"true"`
+	// The query sent to the program on its standard input, as a JSON object
+	// of strings.
+	Query map[string]string `mapstructure:"query" required:"false"`
+	// The directory the program runs in. Defaults to the directory Packer
+	// runs in, use `path.root` for the directory of the template.
+	WorkingDir string `mapstructure:"working_dir" required:"false"`
+	// How long the program may run before it is killed. Defaults to `1m`.
+	Timeout time.Duration `mapstructure:"timeout" required:"false"`
+	// Environment variables set for the program.
+	Env map[string]string `mapstructure:"env" required:"false"`
+	// The names of the environment variables of Packer passed to the
+	// program. By default, the program inherits the whole environment of
+	// Packer, setting this restricts it to the listed variables and `env`.
+	PassEnvironment []string `mapstructure:"pass_environment" required:"false"`
+	// Hide the string values of `result` from the output and logs of
+	// Packer. Numbers and booleans are not hidden, have the program print
+	// them as strings to hide them. Defaults to false.
+	Sensitive bool `mapstructure:"sensitive" required:"false"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+// DatasourceOutput holds the outputs of the datasource. The object printed by
+// the program is also exposed as `result`, its values can be of any JSON
+// type.
+type DatasourceOutput struct {
+	// The standard error of the program, which is often used for
+	// diagnostics.
+	Stderr string `mapstructure:"stderr"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	var errs *packersdk.MultiError
+
+	if len(d.config.Program) == 0 || d.config.Program[0] == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("the `program` must be specified"))
+	}
+
+	if d.config.Timeout == 0 {
+		d.config.Timeout = time.Minute
+	}
+	if d.config.Timeout < 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("the `timeout` must not be negative"))
+	}
+
+	if d.config.WorkingDir != "" {
+		if info, err := os.Stat(d.config.WorkingDir); err != nil || !info.IsDir() {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("the `working_dir` %q is not a directory", d.config.WorkingDir))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	query := d.config.Query
+	if query == nil {
+		query = map[string]string{}
+	}
+	stdin, err := json.Marshal(query)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), d.config.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, d.config.Program[0], d.config.Program[1:]...)
+	cmd.Dir = d.config.WorkingDir
+	cmd.Env = d.environment()
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	program := strings.Join(d.config.Program, " ")
+	err = cmd.Run()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("program %q timed out after %s%s",
+			program, d.config.Timeout, formatStderr(stderr.String()))
+	case err != nil:
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("program %q failed: %s%s",
+			program, err, formatStderr(stderr.String()))
+	}
+
+	result, err := decodeResult(stdout.Bytes())
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("program %q did not print a JSON object: %s%s",
+			program, err, formatStderr(stderr.String()))
+	}
+
+	output := DatasourceOutput{
+		Stderr: stderr.String(),
+	}
+	values := hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()).AsValueMap()
+	values[ResultKey] = result
+	return cty.ObjectVal(values), nil
+}
+
+// environment returns the environment of the program, nil meaning the
+// environment of Packer.
+func (d *Datasource) environment() []string {
+	if len(d.config.PassEnvironment) == 0 && len(d.config.Env) == 0 {
+		return nil
+	}
+
+	var env []string
+	if len(d.config.PassEnvironment) == 0 {
+		env = os.Environ()
+	}
+	for _, name := range d.config.PassEnvironment {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	for name, value := range d.config.Env {
+		env = append(env, name+"="+value)
+	}
+	if env == nil {
+		// An empty, non nil, environment keeps the program from inheriting
+		// the one of Packer.
+		env = []string{}
+	}
+	return env
+}
+
+// decodeResult decodes the JSON object printed by the program.
+func decodeResult(stdout []byte) (cty.Value, error) {
+	ty, err := ctyjson.ImpliedType(stdout)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if !ty.IsObjectType() {
+		return cty.NilVal, fmt.Errorf("got a %s", ty.FriendlyName())
+	}
+	return ctyjson.Unmarshal(stdout, ty)
+}
+
+func formatStderr(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+	return "\n\nstderr:\n" + stderr
+}
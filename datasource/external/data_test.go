@@ -0,0 +1,193 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// helperProgram returns the program running TestHelperProcess with cmd.
+func helperProgram(cmd string) []string {
+	return []string{os.Args[0], "-test.run=TestHelperProcess", "--", cmd}
+}
+
+// This is not a real test. This is just a helper process kicked off by
+// tests.
+func TestHelperProcess(*testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	defer os.Exit(0)
+
+	args := os.Args
+	for len(args) > 0 {
+		if args[0] == "--" {
+			args = args[1:]
+			break
+		}
+		args = args[1:]
+	}
+
+	switch args[0] {
+	case "echo":
+		query := map[string]string{}
+		if err := json.NewDecoder(os.Stdin).Decode(&query); err != nil {
+			fmt.Fprintf(os.Stderr, "bad query: %s", err)
+			os.Exit(1)
+		}
+		wd, _ := os.Getwd()
+		fmt.Fprint(os.Stderr, "looking up host")
+		_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+			"query":   query,
+			"wd":      wd,
+			"token":   os.Getenv("CMDB_TOKEN"),
+			"home":    os.Getenv("HOME"),
+			"rack":    42,
+			"enabled": true,
+		})
+	case "fail":
+		fmt.Fprint(os.Stderr, "host not found in CMDB")
+		os.Exit(3)
+	case "array":
+		fmt.Print(`["a", "b"]`)
+	case "sleep":
+		time.Sleep(time.Minute)
+	}
+}
+
+func testDatasource(t *testing.T, config map[string]interface{}) *Datasource {
+	env, _ := config["env"].(map[string]string)
+	if env == nil {
+		env = map[string]string{}
+	}
+	env["GO_WANT_HELPER_PROCESS"] = "1"
+	config["env"] = env
+
+	d := new(Datasource)
+	if err := d.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return d
+}
+
+func TestDatasourceConfigure(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"valid", map[string]interface{}{"program": []string{"cmdb", "lookup"}}, false},
+		{"no program", map[string]interface{}{}, true},
+		{"missing working dir", map[string]interface{}{"program": []string{"cmdb"}, "working_dir": "does-not-exist"}, true},
+		{"negative timeout", map[string]interface{}{"program": []string{"cmdb"}, "timeout": "-1s"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d Datasource
+			err := d.Configure(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Configure() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDatasourceExecute(t *testing.T) {
+	t.Setenv("CMDB_TOKEN", "s3cr3t")
+	wd := t.TempDir()
+
+	d := testDatasource(t, map[string]interface{}{
+		"program":     helperProgram("echo"),
+		"query":       map[string]string{"hostname": "web-1"},
+		"working_dir": wd,
+	})
+	value, err := d.Execute()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result := value.GetAttr(ResultKey)
+	if host := result.GetAttr("query").GetAttr("hostname"); !host.RawEquals(cty.StringVal("web-1")) {
+		t.Errorf("the query was not sent to the program, got %#v", result)
+	}
+	if rack := result.GetAttr("rack"); !rack.RawEquals(cty.NumberIntVal(42)) {
+		t.Errorf("unexpected rack %#v", rack)
+	}
+	if gotWd := result.GetAttr("wd").AsString(); !strings.HasSuffix(gotWd, wd) {
+		t.Errorf("the program should run in %s, got %s", wd, gotWd)
+	}
+	if token := result.GetAttr("token"); !token.RawEquals(cty.StringVal("s3cr3t")) {
+		t.Errorf("the environment should be inherited, got %#v", token)
+	}
+	if stderr := value.GetAttr("stderr"); !stderr.RawEquals(cty.StringVal("looking up host")) {
+		t.Errorf("unexpected stderr %#v", stderr)
+	}
+}
+
+func TestDatasourceExecute_passEnvironment(t *testing.T) {
+	t.Setenv("CMDB_TOKEN", "s3cr3t")
+	t.Setenv("HOME", "/home/packer")
+
+	d := testDatasource(t, map[string]interface{}{
+		"program":          helperProgram("echo"),
+		"pass_environment": []string{"CMDB_TOKEN"},
+	})
+	value, err := d.Execute()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result := value.GetAttr(ResultKey)
+	if token := result.GetAttr("token"); !token.RawEquals(cty.StringVal("s3cr3t")) {
+		t.Errorf("CMDB_TOKEN should be passed, got %#v", token)
+	}
+	if home := result.GetAttr("home"); !home.RawEquals(cty.StringVal("")) {
+		t.Errorf("HOME should not be passed, got %#v", home)
+	}
+}
+
+func TestDatasourceExecute_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr []string
+	}{
+		{
+			"exit status",
+			map[string]interface{}{"program": helperProgram("fail")},
+			[]string{"failed: exit status 3", "host not found in CMDB"},
+		},
+		{
+			"not an object",
+			map[string]interface{}{"program": helperProgram("array")},
+			[]string{"did not print a JSON object"},
+		},
+		{
+			"timeout",
+			map[string]interface{}{"program": helperProgram("sleep"), "timeout": "100ms"},
+			[]string{"timed out after 100ms"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testDatasource(t, tt.config).Execute()
+			if err == nil {
+				t.Fatal("should have errored")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error to contain %q, got: %s", want, err)
+				}
+			}
+		})
+	}
+}
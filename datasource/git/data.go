@@ -0,0 +1,215 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type DatasourceOutput,Config
+package git
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	gt "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer/internal/provenance"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// shortSHALength is the length of the abbreviated commit SHA, as printed by
+// `git log --oneline` by default.
+const shortSHALength = 7
+
+// The git data source exposes metadata about the git repository holding a
+// path, like the commit checked out, to stamp image names and labels.
+//
+// ```hcl
+//
+//	data "git" "repo" {
+//	  path = path.root
+//	}
+//
+//	locals {
+//	  image_name = "base-${data.git.repo.short_sha}"
+//	}
+//
+// ```
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	// A path in the repository, the repository is found in its parent
+	// directories. Defaults to the directory Packer runs in.
+	Path string `mapstructure:"path" required:"false"`
+	// The name of the remote whose URL is returned. Defaults to `origin`.
+	Remote string `mapstructure:"remote" required:"false"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	// The SHA of the commit checked out.
+	CommitSHA string `mapstructure:"commit_sha"`
+	// The SHA of the commit checked out, abbreviated to 7 characters.
+	ShortSHA string `mapstructure:"short_sha"`
+	// The branch checked out, empty when HEAD is detached.
+	Branch string `mapstructure:"branch"`
+	// The tag pointing at the commit checked out, empty when there is none.
+	// When several tags point at it, the first one in alphabetical order.
+	Tag string `mapstructure:"tag"`
+	// Whether the worktree has uncommitted changes, including untracked
+	// files. It is computed by running `git status --porcelain`, which
+	// honors all the ignore rules of git. When the `git` command is not
+	// installed, it falls back to a built-in implementation that ignores
+	// `core.excludesFile` and the global gitignore file.
+	Dirty bool `mapstructure:"dirty"`
+	// The URL of `remote`, stripped from credentials. Empty when the remote
+	// does not exist.
+	RemoteURL string `mapstructure:"remote_url"`
+	// The commit date of the commit checked out, in RFC3339 format, in UTC.
+	CommitTimestamp string `mapstructure:"commit_timestamp"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	if d.config.Path == "" {
+		d.config.Path = "."
+	}
+	if d.config.Remote == "" {
+		d.config.Remote = "origin"
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	repo, err := gt.PlainOpenWithOptions(d.config.Path, &gt.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("failed to open the git repository of %s: %s", d.config.Path, err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("failed to resolve HEAD: %s", err)
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("failed to read the commit of HEAD: %s", err)
+	}
+
+	output := DatasourceOutput{
+		CommitSHA:       commit.Hash.String(),
+		ShortSHA:        commit.Hash.String()[:shortSHALength],
+		CommitTimestamp: commit.Committer.When.UTC().Format(time.RFC3339),
+	}
+
+	if head.Name().IsBranch() {
+		output.Branch = head.Name().Short()
+	}
+
+	output.Tag, err = headTag(repo, commit.Hash)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("failed to list tags: %s", err)
+	}
+
+	output.Dirty, err = d.dirty(repo)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("failed to get the git worktree status: %s", err)
+	}
+
+	remote, err := repo.Remote(d.config.Remote)
+	switch {
+	case errors.Is(err, gt.ErrRemoteNotFound):
+	case err != nil:
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("failed to read remote %s: %s", d.config.Remote, err)
+	case len(remote.Config().URLs) > 0:
+		output.RemoteURL = provenance.SanitizeGitRemoteURL(remote.Config().URLs[0])
+	}
+
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// dirty reports whether the worktree of repo has uncommitted changes, with
+// the git command when it is installed.
+func (d *Datasource) dirty(repo *gt.Repository) (bool, error) {
+	dir := d.config.Path
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	dirty, err := provenance.GitWorktreeDirty(dir)
+	if !errors.Is(err, exec.ErrNotFound) {
+		return dirty, err
+	}
+
+	log.Printf("git is not installed, the ignore rules of core.excludesFile and the global gitignore are not applied")
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return false, err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return false, err
+	}
+	return !status.IsClean(), nil
+}
+
+// headTag returns the first tag, in alphabetical order, pointing at commit.
+func headTag(repo *gt.Repository, commit plumbing.Hash) (string, error) {
+	tags, err := repo.Tags()
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		target := ref.Hash()
+		// Annotated tags point at a tag object, lightweight ones at the
+		// commit directly.
+		tag, err := repo.TagObject(target)
+		switch {
+		case err == nil:
+			tagged, err := tag.Commit()
+			if err != nil {
+				return nil
+			}
+			target = tagged.Hash
+		case !errors.Is(err, plumbing.ErrObjectNotFound):
+			return err
+		}
+		if target == commit {
+			names = append(names, ref.Name().Short())
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(names) == 0 {
+		return "", nil
+	}
+	sort.Strings(names)
+	return names[0], nil
+}
@@ -7,12 +7,18 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"mime"
 	"net/http"
+	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/common"
@@ -20,8 +26,14 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
+// ResponseJSONKey is the output holding the response body decoded from JSON.
+// Its type depends on the body, so it is not part of the output spec and is
+// added to the output value by Execute.
+const ResponseJSONKey = "response_json"
+
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 	// The URL to request data from. This URL must respond with a `2xx` range response code and a `text/*` or `application/json` Content-Type.
@@ -32,20 +44,52 @@ type Config struct {
 	RequestHeaders map[string]string `mapstructure:"request_headers" required:"false"`
 	// HTTP request payload send with the request. Default is empty.
 	RequestBody string `mapstructure:"request_body" required:"false"`
+	// How many times to retry the request when it fails to be sent, or when
+	// the server responds with a `429` or `5xx` response code that is not
+	// expected. Default is 0.
+	Retry int `mapstructure:"retry" required:"false"`
+	// How long to wait before the first retry, the delay doubles with every
+	// retry up to 30 seconds. Default is `1s`.
+	RetryDelay time.Duration `mapstructure:"retry_delay" required:"false"`
+	// How long to wait for each request to complete, including reading the
+	// response body. Default is no timeout.
+	Timeout time.Duration `mapstructure:"timeout" required:"false"`
+	// The path to a PEM file holding the certificate authorities trusted to
+	// verify the server certificate, in addition to the system ones.
+	CAFile string `mapstructure:"ca_file" required:"false"`
+	// The path to a PEM client certificate to authenticate with. Requires
+	// `client_key`.
+	ClientCert string `mapstructure:"client_cert" required:"false"`
+	// The path to the PEM private key of `client_cert`.
+	ClientKey string `mapstructure:"client_key" required:"false"`
+	// Skip the verification of the server certificate. This is insecure, and
+	// should only be used for testing. Default is false.
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify" required:"false"`
+	// The response codes that are considered successful. Default is any
+	// `2xx` response code.
+	ExpectedStatusCodes []int `mapstructure:"expected_status_codes" required:"false"`
 }
 
 type Datasource struct {
 	config Config
 }
 
+// DatasourceOutput holds the outputs of the datasource. The response body is
+// also decoded from JSON when the response Content-Type is JSON, as
+// `response_json`, which is null otherwise.
 type DatasourceOutput struct {
 	// The URL the data was requested from.
 	Url string `mapstructure:"url"`
 	// The raw body of the HTTP response.
 	ResponseBody string `mapstructure:"body"`
+	// The HTTP response code.
+	StatusCode int `mapstructure:"status_code"`
 	// A map of strings representing the response HTTP headers.
 	// Duplicate headers are concatenated with, according to [RFC2616](https://www.w3.org/Protocols/rfc2616/rfc2616-sec4.html#sec4.2).
-	ResponseHeaders map[string]string `mapstructure:"request_headers"`
+	ResponseHeaders map[string]string `mapstructure:"response_headers"`
+	// Deprecated: use `response_headers`, this holds the same response
+	// headers and was wrongly named.
+	DeprecatedResponseHeaders map[string]string `mapstructure:"request_headers"`
 }
 
 func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
@@ -86,6 +130,26 @@ func (d *Datasource) Configure(raws ...interface{}) error {
 			fmt.Errorf("the `method` must be one of %v", allowedMethods))
 	}
 
+	if d.config.Retry < 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("the `retry` must not be negative"))
+	}
+	if d.config.RetryDelay == 0 {
+		d.config.RetryDelay = time.Second
+	}
+	if d.config.RetryDelay < 0 || d.config.Timeout < 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("the `retry_delay` and `timeout` must not be negative"))
+	}
+
+	if (d.config.ClientCert == "") != (d.config.ClientKey == "") {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("the `client_cert` and `client_key` must be set together"))
+	}
+
+	for _, code := range d.config.ExpectedStatusCodes {
+		if code < 100 || code > 599 {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid expected status code %d", code))
+		}
+	}
+
 	if errs != nil && len(errs.Errors) > 0 {
 		return errs
 	}
@@ -96,6 +160,16 @@ func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
 	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
 }
 
+// isContentTypeJSON tells whether the body of a response of contentType can
+// be decoded as JSON.
+func isContentTypeJSON(contentType string) bool {
+	parsedType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return parsedType == "application/json" || strings.HasSuffix(parsedType, "+json")
+}
+
 // This is to prevent potential issues w/ binary files
 // and generally unprintable characters
 // See https://github.com/hashicorp/terraform/pull/3858#issuecomment-156856738
@@ -126,64 +200,181 @@ func isContentTypeText(contentType string) bool {
 // https://github.com/hashicorp/terraform-provider-http/blob/main/internal/provider/data_source.go
 func (d *Datasource) Execute() (cty.Value, error) {
 	ctx := context.TODO()
-	url, method, headers := d.config.Url, d.config.Method, d.config.RequestHeaders
-	client := &http.Client{}
 
+	client, err := d.client()
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	resp, bytes, err := d.doWithRetry(ctx, client)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" || isContentTypeText(contentType) == false {
+		fmt.Printf("Content-Type is not recognized as a text type, got %q\n",
+			contentType)
+		fmt.Println("If the content is binary data, Packer may not properly handle the contents of the response.")
+	}
+
+	responseHeaders := make(map[string]string)
+	for k, v := range resp.Header {
+		// Concatenate according to RFC2616
+		// cf. https://www.w3.org/Protocols/rfc2616/rfc2616-sec4.html#sec4.2
+		responseHeaders[k] = strings.Join(v, ", ")
+	}
+
+	responseJSON := cty.NullVal(cty.DynamicPseudoType)
+	if isContentTypeJSON(contentType) {
+		responseJSON, err = decodeJSON(bytes)
+		if err != nil {
+			return cty.NullVal(cty.EmptyObject), fmt.Errorf("failed to decode the JSON response body: %s", err)
+		}
+	}
+
+	output := DatasourceOutput{
+		Url:                       d.config.Url,
+		ResponseBody:              string(bytes),
+		StatusCode:                resp.StatusCode,
+		ResponseHeaders:           responseHeaders,
+		DeprecatedResponseHeaders: responseHeaders,
+	}
+	values := hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()).AsValueMap()
+	values[ResponseJSONKey] = responseJSON
+	return cty.ObjectVal(values), nil
+}
+
+// doWithRetry sends the request until it succeeds, fails with an error that
+// is not worth a retry, or the retries are exhausted.
+func (d *Datasource) doWithRetry(ctx context.Context, client *http.Client) (*http.Response, []byte, error) {
+	delay := d.config.RetryDelay
+	for attempt := 0; ; attempt++ {
+		resp, bytes, err := d.do(ctx, client)
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return nil, nil, permanent.err
+		}
+		if err == nil || attempt == d.config.Retry {
+			return resp, bytes, err
+		}
+
+		log.Printf("[WARN] %s, retrying in %s (%d/%d)", err, delay, attempt+1, d.config.Retry)
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+const maxRetryDelay = 30 * time.Second
+
+// permanentError is returned by do when retrying the request is pointless.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+// do sends the request, and reads the response body. It returns a
+// permanentError when the response code is not expected, and not worth a
+// retry.
+func (d *Datasource) do(ctx context.Context, client *http.Client) (*http.Response, []byte, error) {
 	// Create request body if it is provided
 	var requestBody io.Reader
 	if d.config.RequestBody != "" {
 		requestBody = strings.NewReader(d.config.RequestBody)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, url, requestBody)
-	// TODO: How to make a test case for this?
+	req, err := http.NewRequestWithContext(ctx, d.config.Method, d.config.Url, requestBody)
 	if err != nil {
-		fmt.Println("Error creating http request")
-		return cty.NullVal(cty.EmptyObject), err
+		return nil, nil, &permanentError{fmt.Errorf("failed to create HTTP request: %s", err)}
 	}
 
-	for name, value := range headers {
+	for name, value := range d.config.RequestHeaders {
 		req.Header.Set(name, value)
 	}
 
 	resp, err := client.Do(req)
-	// TODO: How to make test case for this
 	if err != nil {
-		fmt.Println("Error making performing http request")
-		return cty.NullVal(cty.EmptyObject), err
+		return nil, nil, fmt.Errorf("failed to perform HTTP request: %s", err)
 	}
-
 	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return cty.NullVal(cty.EmptyObject), fmt.Errorf("HTTP request error. Response code: %d", resp.StatusCode)
-	}
-
-	contentType := resp.Header.Get("Content-Type")
-	if contentType == "" || isContentTypeText(contentType) == false {
-		fmt.Printf("Content-Type is not recognized as a text type, got %q\n",
-			contentType)
-		fmt.Println("If the content is binary data, Packer may not properly handle the contents of the response.")
+	if !d.expectedStatusCode(resp.StatusCode) {
+		err := fmt.Errorf("HTTP request error. Response code: %d", resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return nil, nil, err
+		}
+		return nil, nil, &permanentError{err}
 	}
 
 	bytes, err := io.ReadAll(resp.Body)
-	// TODO: How to make test case for this?
 	if err != nil {
-		fmt.Println("Error processing response body of call")
-		return cty.NullVal(cty.EmptyObject), err
+		return nil, nil, fmt.Errorf("failed to read HTTP response body: %s", err)
 	}
+	return resp, bytes, nil
+}
 
-	responseHeaders := make(map[string]string)
-	for k, v := range resp.Header {
-		// Concatenate according to RFC2616
-		// cf. https://www.w3.org/Protocols/rfc2616/rfc2616-sec4.html#sec4.2
-		responseHeaders[k] = strings.Join(v, ", ")
+func (d *Datasource) expectedStatusCode(code int) bool {
+	if len(d.config.ExpectedStatusCodes) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, expected := range d.config.ExpectedStatusCodes {
+		if code == expected {
+			return true
+		}
 	}
+	return false
+}
 
-	output := DatasourceOutput{
-		Url:             d.config.Url,
-		ResponseHeaders: responseHeaders,
-		ResponseBody:    string(bytes),
+// client returns the HTTP client configured with the timeout and TLS
+// settings.
+func (d *Datasource) client() (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: d.config.InsecureSkipVerify,
+	}
+
+	if d.config.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			log.Printf("[WARN] failed to load the system certificate pool: %s", err)
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(d.config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_file: %s", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificate found in ca_file %s", d.config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if d.config.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(d.config.ClientCert, d.config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client_cert and client_key: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{
+		Transport: transport,
+		Timeout:   d.config.Timeout,
+	}, nil
+}
+
+// decodeJSON decodes body into a cty value whose type is implied by the
+// JSON document.
+func decodeJSON(body []byte) (cty.Value, error) {
+	ty, err := ctyjson.ImpliedType(body)
+	if err != nil {
+		return cty.NilVal, err
 	}
-	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+	return ctyjson.Unmarshal(body, ty)
 }
@@ -22,6 +22,14 @@ type FlatConfig struct {
 	Method              *string           `mapstructure:"method" required:"false" cty:"method" hcl:"method"`
 	RequestHeaders      map[string]string `mapstructure:"request_headers" required:"false" cty:"request_headers" hcl:"request_headers"`
 	RequestBody         *string           `mapstructure:"request_body" required:"false" cty:"request_body" hcl:"request_body"`
+	Retry               *int              `mapstructure:"retry" required:"false" cty:"retry" hcl:"retry"`
+	RetryDelay          *string           `mapstructure:"retry_delay" required:"false" cty:"retry_delay" hcl:"retry_delay"`
+	Timeout             *string           `mapstructure:"timeout" required:"false" cty:"timeout" hcl:"timeout"`
+	CAFile              *string           `mapstructure:"ca_file" required:"false" cty:"ca_file" hcl:"ca_file"`
+	ClientCert          *string           `mapstructure:"client_cert" required:"false" cty:"client_cert" hcl:"client_cert"`
+	ClientKey           *string           `mapstructure:"client_key" required:"false" cty:"client_key" hcl:"client_key"`
+	InsecureSkipVerify  *bool             `mapstructure:"insecure_skip_verify" required:"false" cty:"insecure_skip_verify" hcl:"insecure_skip_verify"`
+	ExpectedStatusCodes []int             `mapstructure:"expected_status_codes" required:"false" cty:"expected_status_codes" hcl:"expected_status_codes"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -48,6 +56,14 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"method":                     &hcldec.AttrSpec{Name: "method", Type: cty.String, Required: false},
 		"request_headers":            &hcldec.AttrSpec{Name: "request_headers", Type: cty.Map(cty.String), Required: false},
 		"request_body":               &hcldec.AttrSpec{Name: "request_body", Type: cty.String, Required: false},
+		"retry":                      &hcldec.AttrSpec{Name: "retry", Type: cty.Number, Required: false},
+		"retry_delay":                &hcldec.AttrSpec{Name: "retry_delay", Type: cty.String, Required: false},
+		"timeout":                    &hcldec.AttrSpec{Name: "timeout", Type: cty.String, Required: false},
+		"ca_file":                    &hcldec.AttrSpec{Name: "ca_file", Type: cty.String, Required: false},
+		"client_cert":                &hcldec.AttrSpec{Name: "client_cert", Type: cty.String, Required: false},
+		"client_key":                 &hcldec.AttrSpec{Name: "client_key", Type: cty.String, Required: false},
+		"insecure_skip_verify":       &hcldec.AttrSpec{Name: "insecure_skip_verify", Type: cty.Bool, Required: false},
+		"expected_status_codes":      &hcldec.AttrSpec{Name: "expected_status_codes", Type: cty.List(cty.Number), Required: false},
 	}
 	return s
 }
@@ -55,9 +71,11 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 // FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatDatasourceOutput struct {
-	Url             *string           `mapstructure:"url" cty:"url" hcl:"url"`
-	ResponseBody    *string           `mapstructure:"body" cty:"body" hcl:"body"`
-	ResponseHeaders map[string]string `mapstructure:"request_headers" cty:"request_headers" hcl:"request_headers"`
+	Url                       *string           `mapstructure:"url" cty:"url" hcl:"url"`
+	ResponseBody              *string           `mapstructure:"body" cty:"body" hcl:"body"`
+	StatusCode                *int              `mapstructure:"status_code" cty:"status_code" hcl:"status_code"`
+	ResponseHeaders           map[string]string `mapstructure:"response_headers" cty:"response_headers" hcl:"response_headers"`
+	DeprecatedResponseHeaders map[string]string `mapstructure:"request_headers" cty:"request_headers" hcl:"request_headers"`
 }
 
 // FlatMapstructure returns a new FlatDatasourceOutput.
@@ -72,9 +90,11 @@ func (*DatasourceOutput) FlatMapstructure() interface{ HCL2Spec() map[string]hcl
 // The decoded values from this spec will then be applied to a FlatDatasourceOutput.
 func (*FlatDatasourceOutput) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"url":             &hcldec.AttrSpec{Name: "url", Type: cty.String, Required: false},
-		"body":            &hcldec.AttrSpec{Name: "body", Type: cty.String, Required: false},
-		"request_headers": &hcldec.AttrSpec{Name: "request_headers", Type: cty.Map(cty.String), Required: false},
+		"url":              &hcldec.AttrSpec{Name: "url", Type: cty.String, Required: false},
+		"body":             &hcldec.AttrSpec{Name: "body", Type: cty.String, Required: false},
+		"status_code":      &hcldec.AttrSpec{Name: "status_code", Type: cty.Number, Required: false},
+		"response_headers": &hcldec.AttrSpec{Name: "response_headers", Type: cty.Map(cty.String), Required: false},
+		"request_headers":  &hcldec.AttrSpec{Name: "request_headers", Type: cty.Map(cty.String), Required: false},
 	}
 	return s
 }
@@ -0,0 +1,184 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package http
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func testDatasource(t *testing.T, config map[string]interface{}) *Datasource {
+	d := new(Datasource)
+	if err := d.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return d
+}
+
+func TestDatasourceConfigure(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"valid", map[string]interface{}{"url": "https://example.com", "retry": 3, "timeout": "10s"}, false},
+		{"negative retry", map[string]interface{}{"url": "https://example.com", "retry": -1}, true},
+		{"client cert without key", map[string]interface{}{"url": "https://example.com", "client_cert": "cert.pem"}, true},
+		{"invalid status code", map[string]interface{}{"url": "https://example.com", "expected_status_codes": []int{200, 1000}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d Datasource
+			err := d.Configure(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Configure() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDatasourceExecute(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Version", "1.2.3")
+		_, _ = w.Write([]byte(`{"name": "base", "tags": ["a", "b"], "size": 42}`))
+	}))
+	defer server.Close()
+
+	d := testDatasource(t, map[string]interface{}{"url": server.URL})
+	value, err := d.Execute()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if code := value.GetAttr("status_code"); !code.RawEquals(cty.NumberIntVal(200)) {
+		t.Errorf("unexpected status_code %#v", code)
+	}
+	for _, key := range []string{"response_headers", "request_headers"} {
+		if header := value.GetAttr(key).Index(cty.StringVal("X-Version")); !header.RawEquals(cty.StringVal("1.2.3")) {
+			t.Errorf("unexpected %s X-Version %#v", key, header)
+		}
+	}
+	decoded := value.GetAttr(ResponseJSONKey)
+	if name := decoded.GetAttr("name"); !name.RawEquals(cty.StringVal("base")) {
+		t.Errorf("unexpected response_json.name %#v", name)
+	}
+	if tag := decoded.GetAttr("tags").Index(cty.NumberIntVal(1)); !tag.RawEquals(cty.StringVal("b")) {
+		t.Errorf("unexpected response_json.tags[1] %#v", tag)
+	}
+}
+
+func TestDatasourceExecute_notJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(`{"name": "base"}`))
+	}))
+	defer server.Close()
+
+	value, err := testDatasource(t, map[string]interface{}{"url": server.URL}).Execute()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if decoded := value.GetAttr(ResponseJSONKey); !decoded.IsNull() {
+		t.Fatalf("response_json should be null, got %#v", decoded)
+	}
+}
+
+func TestDatasourceExecute_retry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	d := testDatasource(t, map[string]interface{}{"url": server.URL, "retry": 2, "retry_delay": "1ms"})
+	if _, err := d.Execute(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if requests != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests)
+	}
+
+	requests = 0
+	d = testDatasource(t, map[string]interface{}{"url": server.URL, "retry": 1, "retry_delay": "1ms"})
+	_, err := d.Execute()
+	if err == nil || !strings.Contains(err.Error(), "Response code: 503") {
+		t.Fatalf("expected a 503 error, got %v", err)
+	}
+}
+
+func TestDatasourceExecute_expectedStatusCodes(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	d := testDatasource(t, map[string]interface{}{"url": server.URL, "retry": 3, "retry_delay": "1ms"})
+	if _, err := d.Execute(); err == nil {
+		t.Fatal("a 404 response should fail")
+	}
+	if requests != 1 {
+		t.Fatalf("a 404 response should not be retried, got %d requests", requests)
+	}
+
+	d = testDatasource(t, map[string]interface{}{"url": server.URL, "expected_status_codes": []int{200, 404}})
+	value, err := d.Execute()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if code := value.GetAttr("status_code"); !code.RawEquals(cty.NumberIntVal(404)) {
+		t.Fatalf("unexpected status_code %#v", code)
+	}
+}
+
+func TestDatasourceExecute_timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	d := testDatasource(t, map[string]interface{}{"url": server.URL, "timeout": "10ms"})
+	if _, err := d.Execute(); err == nil {
+		t.Fatal("the request should have timed out")
+	}
+}
+
+func TestDatasourceExecute_tls(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	if _, err := testDatasource(t, map[string]interface{}{"url": server.URL}).Execute(); err == nil {
+		t.Fatal("the server certificate should not be trusted")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := testDatasource(t, map[string]interface{}{"url": server.URL, "ca_file": caFile}).Execute(); err != nil {
+		t.Fatalf("the server certificate should be trusted with ca_file: %s", err)
+	}
+
+	if _, err := testDatasource(t, map[string]interface{}{"url": server.URL, "insecure_skip_verify": true}).Execute(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package hcl2template
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// PluginBinaries returns the path of the plugin binaries built for goos and
+// goarch that would be picked for each required plugin, along with their
+// checksum files.
+//
+// When listing binaries for another platform than the one Packer runs on,
+// the binaries cannot be started to check their version, so the version from
+// their name is trusted.
+func (cfg *PackerConfig) PluginBinaries(goos, goarch string) ([]string, hcl.Diagnostics) {
+	opts := cfg.listInstallationsOptions(goos, goarch)
+	opts.SkipDescribe = goos != runtime.GOOS || goarch != runtime.GOARCH
+
+	pluginReqs, _, diags := cfg.pluginRequirements()
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	var paths []string
+	for _, pluginRequirement := range pluginReqs {
+		if override, ok := cfg.parser.PluginConfig.DevOverride(pluginRequirement.Identifier.String()); ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Plugin %s is overridden by a dev override", pluginRequirement.Identifier),
+				Detail: fmt.Sprintf("Plugin %s is loaded from %q. Development builds cannot be bundled, "+
+					"remove the dev_overrides entry to use an installed release.", pluginRequirement.Identifier, override),
+			})
+			continue
+		}
+
+		sortedInstalls, err := pluginRequirement.ListInstallations(opts)
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Failed to list installation for %s", pluginRequirement.Identifier),
+				Detail:   err.Error(),
+			})
+			continue
+		}
+		if len(sortedInstalls) == 0 {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Missing plugin %s for %s_%s", pluginRequirement.Identifier, goos, goarch),
+				Detail: fmt.Sprintf("No installation of %s %s was found for %s_%s in %q.",
+					pluginRequirement.Identifier, pluginRequirement.VersionConstraints, goos, goarch, opts.PluginDirectory),
+			})
+			continue
+		}
+		binaryPath := sortedInstalls[len(sortedInstalls)-1].BinaryPath
+		paths = append(paths, binaryPath)
+		for _, checksummer := range opts.Checksummers {
+			paths = append(paths, binaryPath+checksummer.FileExt())
+		}
+	}
+
+	return paths, diags
+}
+
+// bundledFunctions maps the functions reading local files to the index of
+// their path argument, and whether that path is relative to the config
+// directory instead of the current directory.
+var bundledFunctions = map[string]struct {
+	arg      int
+	fromRoot bool
+}{
+	"file":         {0, true},
+	"filebase64":   {0, false},
+	"templatefile": {0, true},
+	"fileset":      {0, true},
+}
+
+// bundledProvisionerAttributes lists, per provisioner type, the attributes
+// that reference local files or directories.
+var bundledProvisionerAttributes = map[string][]string{
+	"file":          {"source", "sources"},
+	"shell":         {"script", "scripts"},
+	"shell-local":   {"script", "scripts"},
+	"powershell":    {"script", "scripts"},
+	"windows-shell": {"script", "scripts"},
+}
+
+// BundleFiles returns the local files a config needs to run: the config and
+// variable files that were parsed, the files read through file functions and
+// the files uploaded or executed by provisioners. Paths are absolute and
+// sorted, directories are returned as is.
+//
+// Initialize must have been called first so that variables and locals used
+// in paths can be evaluated. Paths that cannot be evaluated before the build
+// starts are reported as warnings.
+func (cfg *PackerConfig) BundleFiles() ([]string, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	uniq := map[string]struct{}{}
+	add := func(base, path string) {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(base, path)
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		uniq[path] = struct{}{}
+	}
+
+	for filename := range cfg.parser.Files() {
+		add(cfg.Cwd, filename)
+	}
+
+	ectx := cfg.EvalContext(BuildContext, nil)
+
+	for _, file := range cfg.files {
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			// JSON config files are bundled, but the expressions in them
+			// are not inspected.
+			continue
+		}
+		diags = append(diags, hclsyntax.VisitAll(body, func(n hclsyntax.Node) hcl.Diagnostics {
+			call, ok := n.(*hclsyntax.FunctionCallExpr)
+			if !ok {
+				return nil
+			}
+			fn, ok := bundledFunctions[call.Name]
+			if !ok || len(call.Args) <= fn.arg {
+				return nil
+			}
+			base := cfg.Cwd
+			if fn.fromRoot {
+				base = cfg.Basedir
+			}
+			if call.Name == "fileset" {
+				paths, ok, moreDiags := evaluateFileset(call, ectx)
+				if !ok {
+					return bundleWarnings(moreDiags, call.Range())
+				}
+				for _, path := range paths {
+					add(base, path)
+				}
+				return nil
+			}
+			value, moreDiags := call.Args[fn.arg].Value(ectx)
+			if moreDiags.HasErrors() || !value.IsWhollyKnown() || value.Type() != cty.String {
+				return bundleWarnings(moreDiags, call.Range())
+			}
+			add(base, value.AsString())
+			return nil
+		})...)
+	}
+
+	for _, build := range cfg.Builds {
+		provBlocks := append([]*ProvisionerBlock{}, build.ProvisionerBlocks...)
+		if build.ErrorCleanupProvisionerBlock != nil {
+			provBlocks = append(provBlocks, build.ErrorCleanupProvisionerBlock)
+		}
+		for _, pb := range provBlocks {
+			attrNames := bundledProvisionerAttributes[pb.PType]
+			if len(attrNames) == 0 {
+				continue
+			}
+			schema := &hcl.BodySchema{
+				Attributes: []hcl.AttributeSchema{{Name: "direction"}},
+			}
+			for _, name := range attrNames {
+				schema.Attributes = append(schema.Attributes, hcl.AttributeSchema{Name: name})
+			}
+			content, _, _ := pb.Rest.PartialContent(schema)
+			if attr, ok := content.Attributes["direction"]; ok {
+				direction, _ := attr.Expr.Value(ectx)
+				if direction.Type() == cty.String && direction.IsKnown() && direction.AsString() == "download" {
+					continue
+				}
+			}
+			for _, name := range attrNames {
+				attr, ok := content.Attributes[name]
+				if !ok {
+					continue
+				}
+				value, moreDiags := attr.Expr.Value(ectx)
+				if moreDiags.HasErrors() || !value.IsWhollyKnown() || value.IsNull() {
+					diags = append(diags, bundleWarnings(moreDiags, attr.Expr.Range())...)
+					continue
+				}
+				if value.Type() == cty.String {
+					add(cfg.Cwd, value.AsString())
+					continue
+				}
+				if !value.CanIterateElements() {
+					continue
+				}
+				for it := value.ElementIterator(); it.Next(); {
+					_, v := it.Element()
+					if v.Type() == cty.String && !v.IsNull() {
+						add(cfg.Cwd, v.AsString())
+					}
+				}
+			}
+		}
+	}
+
+	files := make([]string, 0, len(uniq))
+	for path := range uniq {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	return files, diags
+}
+
+// evaluateFileset runs a fileset call and returns the matched files,
+// prefixed with the directory they were looked up in. ok is false when the
+// call cannot be evaluated yet.
+func evaluateFileset(call *hclsyntax.FunctionCallExpr, ectx *hcl.EvalContext) (paths []string, ok bool, diags hcl.Diagnostics) {
+	dir, diags := call.Args[0].Value(ectx)
+	if diags.HasErrors() || !dir.IsWhollyKnown() || dir.Type() != cty.String {
+		return nil, false, diags
+	}
+	result, moreDiags := call.Value(ectx)
+	diags = append(diags, moreDiags...)
+	if diags.HasErrors() || !result.IsWhollyKnown() {
+		return nil, false, diags
+	}
+	for it := result.ElementIterator(); it.Next(); {
+		_, v := it.Element()
+		paths = append(paths, filepath.Join(dir.AsString(), v.AsString()))
+	}
+	return paths, true, diags
+}
+
+// bundleWarnings turns the errors from evaluating a path into a single
+// warning: the path may only be known once the build runs.
+func bundleWarnings(diags hcl.Diagnostics, rng hcl.Range) hcl.Diagnostics {
+	detail := "The path may depend on values only known during the build. " +
+		"Make sure the file is present where the bundle is run."
+	var errs []string
+	for _, diag := range diags {
+		if diag.Severity == hcl.DiagError && diag.Summary != "" {
+			errs = append(errs, diag.Summary)
+		}
+	}
+	if len(errs) > 0 {
+		detail = strings.Join(errs, "\n")
+	}
+	return hcl.Diagnostics{&hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  "Cannot resolve a local file to bundle",
+		Detail:   detail,
+		Subject:  rng.Ptr(),
+	}}
+}
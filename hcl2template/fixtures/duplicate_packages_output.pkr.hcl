@@ -0,0 +1,8 @@
+provisioner "packages" {
+  packages = {
+    nginx = "1.24.0"
+  }
+  outputs = {
+    nginx_version = "/tmp/nginx_version"
+  }
+}
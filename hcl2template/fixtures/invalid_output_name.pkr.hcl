@@ -0,0 +1,6 @@
+provisioner "shell" {
+  inline = ["uname -r > /tmp/kernel_version"]
+  outputs = {
+    "kernel version" = "/tmp/kernel_version"
+  }
+}
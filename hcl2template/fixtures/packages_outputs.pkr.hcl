@@ -0,0 +1,9 @@
+provisioner "packages" {
+  packages = {
+    nginx             = "1.24.0"
+    "python3.12-venv" = ""
+  }
+  outputs = {
+    kernel_version = "/tmp/kernel_version"
+  }
+}
@@ -0,0 +1,6 @@
+provisioner "shell" {
+  inline = ["hostname > /tmp/host"]
+  outputs = {
+    Host = "/tmp/host"
+  }
+}
@@ -302,16 +302,20 @@ func filterVarsFromLogs(inputOrLocal Variables) {
 		if !variable.Sensitive {
 			continue
 		}
-		value := variable.Value()
-		_ = cty.Walk(value, func(_ cty.Path, nested cty.Value) (bool, error) {
-			if nested.IsWhollyKnown() && !nested.IsNull() && nested.Type().Equals(cty.String) {
-				packer.RegisterSecret(nested.AsString())
-			}
-			return true, nil
-		})
+		registerSecretStrings(variable.Value())
 	}
 }
 
+// registerSecretStrings hides the strings nested in value from the logs.
+func registerSecretStrings(value cty.Value) {
+	_ = cty.Walk(value, func(_ cty.Path, nested cty.Value) (bool, error) {
+		if nested.IsWhollyKnown() && !nested.IsNull() && nested.Type().Equals(cty.String) {
+			packer.RegisterSecret(nested.AsString())
+		}
+		return true, nil
+	})
+}
+
 func (cfg *PackerConfig) detectBuildPrereqDependencies() hcl.Diagnostics {
 	var diags hcl.Diagnostics
 
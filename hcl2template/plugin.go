@@ -18,6 +18,13 @@ import (
 
 // PluginRequirements returns a sorted list of plugin requirements.
 func (cfg *PackerConfig) PluginRequirements() (plugingetter.Requirements, hcl.Diagnostics) {
+	reqs, _, diags := cfg.pluginRequirements()
+	return reqs, diags
+}
+
+// pluginRequirements returns a sorted list of plugin requirements, along
+// with the required_plugins entry of each, keyed by accessor.
+func (cfg *PackerConfig) pluginRequirements() (plugingetter.Requirements, map[string]*RequiredPlugin, hcl.Diagnostics) {
 
 	var diags hcl.Diagnostics
 	var reqs plugingetter.Requirements
@@ -51,16 +58,17 @@ func (cfg *PackerConfig) PluginRequirements() (plugingetter.Requirements, hcl.Di
 
 	}
 
-	return reqs, diags
+	return reqs, uniq, diags
 }
 
-func (cfg *PackerConfig) DetectPluginBinaries() hcl.Diagnostics {
-	// Then we can apply any constraint from the template, if any
+// listInstallationsOptions returns the options used to look for installed
+// plugins built for goos and goarch.
+func (cfg *PackerConfig) listInstallationsOptions(goos, goarch string) plugingetter.ListInstallationsOptions {
 	opts := plugingetter.ListInstallationsOptions{
 		PluginDirectory: cfg.parser.PluginConfig.PluginDirectory,
 		BinaryInstallationOptions: plugingetter.BinaryInstallationOptions{
-			OS:              runtime.GOOS,
-			ARCH:            runtime.GOARCH,
+			OS:              goos,
+			ARCH:            goarch,
 			APIVersionMajor: pluginsdk.APIVersionMajor,
 			APIVersionMinor: pluginsdk.APIVersionMinor,
 			Checksummers: []plugingetter.Checksummer{
@@ -70,34 +78,63 @@ func (cfg *PackerConfig) DetectPluginBinaries() hcl.Diagnostics {
 		},
 	}
 
-	if runtime.GOOS == "windows" && opts.Ext == "" {
+	if goos == "windows" && opts.Ext == "" {
 		opts.BinaryInstallationOptions.Ext = ".exe"
 	}
 
-	pluginReqs, diags := cfg.PluginRequirements()
+	return opts
+}
+
+func (cfg *PackerConfig) DetectPluginBinaries() hcl.Diagnostics {
+	// Then we can apply any constraint from the template, if any
+	opts := cfg.listInstallationsOptions(runtime.GOOS, runtime.GOARCH)
+
+	pluginReqs, requiredPlugins, diags := cfg.pluginRequirements()
 	if diags.HasErrors() {
 		return diags
 	}
 
+	if warning := cfg.parser.PluginConfig.DevOverridesWarning(); warning != nil {
+		diags = append(diags, warning)
+	}
+
 	uninstalledPlugins := map[string]string{}
 
 	for _, pluginRequirement := range pluginReqs {
-		sortedInstalls, err := pluginRequirement.ListInstallations(opts)
-		if err != nil {
-			diags = append(diags, &hcl.Diagnostic{
-				Severity: hcl.DiagError,
-				Summary:  fmt.Sprintf("Failed to list installation for %s", pluginRequirement.Identifier),
-				Detail:   err.Error(),
-			})
-			continue
+		binaryPath, overridden := cfg.parser.PluginConfig.DevOverride(pluginRequirement.Identifier.String())
+		if overridden {
+			log.Printf("[WARN] Using dev override %q for plugin %s, ignoring version constraints", binaryPath, pluginRequirement.Identifier)
+		} else {
+			sortedInstalls, err := pluginRequirement.ListInstallations(opts)
+			if err != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  fmt.Sprintf("Failed to list installation for %s", pluginRequirement.Identifier),
+					Detail:   err.Error(),
+				})
+				continue
+			}
+			if len(sortedInstalls) == 0 {
+				uninstalledPlugins[pluginRequirement.Identifier.String()] = pluginRequirement.VersionConstraints.String()
+				continue
+			}
+			log.Printf("[TRACE] Found the following %q installations: %v", pluginRequirement.Identifier, sortedInstalls)
+			binaryPath = sortedInstalls[len(sortedInstalls)-1].BinaryPath
 		}
-		if len(sortedInstalls) == 0 {
-			uninstalledPlugins[pluginRequirement.Identifier.String()] = pluginRequirement.VersionConstraints.String()
-			continue
+		if sandbox := requiredPlugins[pluginRequirement.Accessor].Sandbox; sandbox != nil {
+			err := cfg.parser.PluginConfig.SetPluginSandbox(binaryPath, sandbox)
+			if err != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  fmt.Sprintf("Cannot sandbox plugin %s", pluginRequirement.Identifier),
+					Detail:   err.Error(),
+					Subject:  &requiredPlugins[pluginRequirement.Accessor].DeclRange,
+				})
+				continue
+			}
+			log.Printf("[INFO] Plugin %s will run in a sandbox: %+v", pluginRequirement.Identifier, *sandbox)
 		}
-		log.Printf("[TRACE] Found the following %q installations: %v", pluginRequirement.Identifier, sortedInstalls)
-		install := sortedInstalls[len(sortedInstalls)-1]
-		err = cfg.parser.PluginConfig.DiscoverMultiPlugin(pluginRequirement.Accessor, install.BinaryPath)
+		err := cfg.parser.PluginConfig.DiscoverMultiPlugin(pluginRequirement.Accessor, binaryPath)
 		if err != nil {
 			diags = append(diags, &hcl.Diagnostic{
 				Severity: hcl.DiagError,
@@ -5,13 +5,17 @@ package hcl2template
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	hcl2shim "github.com/hashicorp/packer/hcl2template/shim"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/provisioner/packages"
 	"github.com/zclconf/go-cty/cty"
 )
 
@@ -71,6 +75,18 @@ type ProvisionerBlock struct {
 	ContinueOnError bool
 	Override        map[string]interface{}
 	OnlyExcept      OnlyExcept
+	// OnlyIf is evaluated right before the provisioner runs, the
+	// provisioner is skipped when it is false. Its references and type are
+	// checked when the builds are created, see checkOnlyIf. It is nil when
+	// not set.
+	OnlyIf hcl.Expression
+	// Outputs maps build variable names to remote files whose content is
+	// read once the provisioner succeeded.
+	Outputs map[string]string
+	// LogFile is the path of the local file the output of the provisioner
+	// is written to, evaluated right before the provisioner runs. It is only
+	// accepted on logFileProvisioners, and nil when not set.
+	LogFile hcl.Expression
 	HCL2Ref
 }
 
@@ -78,17 +94,24 @@ func (p *ProvisionerBlock) String() string {
 	return fmt.Sprintf(buildProvisionerLabel+"-block %q %q", p.PType, p.PName)
 }
 
+// logFileProvisioners are the provisioners that run commands whose output
+// log_file can write to a local file.
+var logFileProvisioners = []string{"shell", "powershell", "windows-shell", "shell-local"}
+
 func (p *Parser) decodeProvisioner(block *hcl.Block, ectx *hcl.EvalContext) (*ProvisionerBlock, hcl.Diagnostics) {
 	var b struct {
-		Name            string    `hcl:"name,optional"`
-		PauseBefore     string    `hcl:"pause_before,optional"`
-		MaxRetries      int       `hcl:"max_retries,optional"`
-		Timeout         string    `hcl:"timeout,optional"`
-		ContinueOnError bool      `hcl:"continue_on_error,optional"`
-		Only            []string  `hcl:"only,optional"`
-		Except          []string  `hcl:"except,optional"`
-		Override        cty.Value `hcl:"override,optional"`
-		Rest            hcl.Body  `hcl:",remain"`
+		Name            string            `hcl:"name,optional"`
+		PauseBefore     string            `hcl:"pause_before,optional"`
+		MaxRetries      int               `hcl:"max_retries,optional"`
+		Timeout         string            `hcl:"timeout,optional"`
+		ContinueOnError bool              `hcl:"continue_on_error,optional"`
+		Only            []string          `hcl:"only,optional"`
+		Except          []string          `hcl:"except,optional"`
+		Override        cty.Value         `hcl:"override,optional"`
+		OnlyIf          *hcl.Attribute    `hcl:"only_if,optional"`
+		Outputs         map[string]string `hcl:"outputs,optional"`
+		LogFile         *hcl.Attribute    `hcl:"log_file,optional"`
+		Rest            hcl.Body          `hcl:",remain"`
 	}
 	diags := gohcl.DecodeBody(block.Body, ectx, &b)
 	if diags.HasErrors() {
@@ -109,6 +132,49 @@ func (p *Parser) decodeProvisioner(block *hcl.Block, ectx *hcl.EvalContext) (*Pr
 		return nil, diags
 	}
 
+	if b.OnlyIf != nil {
+		provisioner.OnlyIf = b.OnlyIf.Expr
+	}
+	if b.LogFile != nil {
+		if !slices.Contains(logFileProvisioners, provisioner.PType) {
+			return nil, append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported log_file",
+				Detail:   "log_file is only supported by the shell, powershell, windows-shell and shell-local provisioners.",
+				Subject:  b.LogFile.Range.Ptr(),
+			})
+		}
+		provisioner.LogFile = b.LogFile.Expr
+	}
+
+	for name := range b.Outputs {
+		if !hclsyntax.ValidIdentifier(name) {
+			return nil, append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Invalid output name %q", name),
+				Detail:   "Output names must be valid identifiers, they are accessed as build.<name>.",
+				Subject:  block.DefRange.Ptr(),
+			})
+		}
+		if name == "name" || slices.Contains(packer.BuilderDataCommonKeys, name) {
+			return nil, append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Reserved output name %q", name),
+				Detail:   fmt.Sprintf("build.%s is already set by Packer, pick another output name.", name),
+				Subject:  block.DefRange.Ptr(),
+			})
+		}
+	}
+	provisioner.Outputs = b.Outputs
+
+	if provisioner.PType == "packages" {
+		moreDiags := provisioner.addPackagesOutputs(b.Rest, ectx)
+		diags = append(diags, moreDiags...)
+		if moreDiags.HasErrors() {
+			return nil, diags
+		}
+	}
+
 	if !b.Override.IsNull() {
 		if !b.Override.Type().IsObjectType() {
 			return nil, append(diags, &hcl.Diagnostic{
@@ -169,6 +235,55 @@ func (p *Parser) decodeProvisioner(block *hcl.Block, ectx *hcl.EvalContext) (*Pr
 	return provisioner, diags
 }
 
+// addPackagesOutputs registers the installed version of each package of a
+// packages provisioner as an output, named by packages.OutputName. Packages
+// that can't be evaluated yet are left to the provisioner to report.
+func (p *ProvisionerBlock) addPackagesOutputs(body hcl.Body, ectx *hcl.EvalContext) hcl.Diagnostics {
+	content, _, _ := body.PartialContent(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "packages"}},
+	})
+	attr, ok := content.Attributes["packages"]
+	if !ok {
+		return nil
+	}
+	value, valDiags := attr.Expr.Value(ectx)
+	if valDiags.HasErrors() || value.IsNull() || !(value.Type().IsMapType() || value.Type().IsObjectType()) {
+		return nil
+	}
+	if !value.IsWhollyKnown() {
+		return hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagWarning,
+			Summary:  "Package versions not published",
+			Detail: "The packages of this provisioner are not known yet, " +
+				"the installed versions can't be referenced as build variables.",
+			Subject: attr.Range.Ptr(),
+		}}
+	}
+
+	outputs := make(map[string]string, len(p.Outputs))
+	for name, path := range p.Outputs {
+		outputs[name] = path
+	}
+	var diags hcl.Diagnostics
+	for it := value.ElementIterator(); it.Next(); {
+		key, _ := it.Element()
+		name := packages.OutputName(key.AsString())
+		if _, exists := outputs[name]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Duplicate output name %q", name),
+				Detail: fmt.Sprintf("The installed version of package %q is published as build.%s, "+
+					"which is already used by another output of this provisioner.", key.AsString(), name),
+				Subject: attr.Range.Ptr(),
+			})
+			continue
+		}
+		outputs[name] = packages.VersionFile(key.AsString())
+	}
+	p.Outputs = outputs
+	return diags
+}
+
 func (cfg *PackerConfig) startProvisioner(source SourceUseBlock, pb *ProvisionerBlock, ectx *hcl.EvalContext) (packersdk.Provisioner, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 
@@ -6,6 +6,7 @@ package hcl2template
 import (
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/hashicorp/hcl/v2"
 )
 
@@ -34,6 +35,24 @@ func TestPackerConfig_ParseProvisionerBlock(t *testing.T) {
 			true,
 			"provisioner's override.'test' block must be an HCL object",
 		},
+		{
+			"failure - output name is not an identifier",
+			"fixtures/invalid_output_name.pkr.hcl",
+			true,
+			`Invalid output name "kernel version"`,
+		},
+		{
+			"failure - output name is reserved",
+			"fixtures/reserved_output_name.pkr.hcl",
+			true,
+			`Reserved output name "Host"`,
+		},
+		{
+			"failure - package version output is already declared",
+			"fixtures/duplicate_packages_output.pkr.hcl",
+			true,
+			`Duplicate output name "nginx_version"`,
+		},
 	}
 
 	for _, test := range tests {
@@ -83,3 +102,25 @@ func TestPackerConfig_ParseProvisionerBlock(t *testing.T) {
 		})
 	}
 }
+
+func TestPackerConfig_ParseProvisionerBlock_packagesOutputs(t *testing.T) {
+	cfg := PackerConfig{parser: getBasicParser()}
+	f, diags := cfg.parser.ParseHCLFile("fixtures/packages_outputs.pkr.hcl")
+	if diags.HasErrors() {
+		t.Fatalf("failed to parse input file: %s", diags)
+	}
+	provBlock := f.OutermostBlockAtPos(hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	pb, diags := cfg.parser.decodeProvisioner(provBlock, nil)
+	if diags.HasErrors() {
+		t.Fatalf("unexpected error: %s", diags)
+	}
+
+	expected := map[string]string{
+		"kernel_version":          "/tmp/kernel_version",
+		"nginx_version":           "/tmp/packer-packages/nginx",
+		"python3_12-venv_version": "/tmp/packer-packages/python3_12-venv",
+	}
+	if diff := cmp.Diff(expected, pb.Outputs); diff != "" {
+		t.Fatalf("unexpected outputs: %s", diff)
+	}
+}
@@ -7,8 +7,11 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer/datasource/external"
+	httpdatasource "github.com/hashicorp/packer/datasource/http"
 	hcl2shim "github.com/hashicorp/packer/hcl2template/shim"
 	"github.com/zclconf/go-cty/cty"
 )
@@ -41,6 +44,52 @@ func (data *DatasourceBlock) Ref() DatasourceRef {
 	}
 }
 
+// dynamicDatasourceOutputs lists the outputs of the built-in datasources
+// whose type depends on the data, like decoded JSON documents. The plugin
+// protocol cannot describe them in the output spec, so they are added to
+// the placeholder values used when datasources are not executed.
+var dynamicDatasourceOutputs = map[string][]string{
+	"external": {external.ResultKey},
+	"http":     {httpdatasource.ResponseJSONKey},
+}
+
+// filterDatasourceFromLogs hides the result of an external datasource from
+// the logs when it is configured with `sensitive = true`. Datasources run in
+// plugin processes, so the values must be registered as secrets by core.
+// Only the strings of the result are hidden, numbers and booleans are too
+// common to be filtered out of the logs.
+func filterDatasourceFromLogs(dsType string, config, value cty.Value) {
+	if dsType != "external" || !config.Type().IsObjectType() || !config.Type().HasAttribute("sensitive") {
+		return
+	}
+	sensitive := config.GetAttr("sensitive")
+	if !sensitive.IsKnown() || sensitive.IsNull() || !sensitive.Type().Equals(cty.Bool) || sensitive.False() {
+		return
+	}
+	if !value.Type().IsObjectType() || !value.Type().HasAttribute(external.ResultKey) {
+		return
+	}
+	registerSecretStrings(value.GetAttr(external.ResultKey))
+}
+
+// datasourcePlaceholder returns the unknown value standing for the outputs of
+// a datasource of type dsType that is not executed.
+func datasourcePlaceholder(dsType string, spec hcldec.ObjectSpec) cty.Value {
+	ty := hcldec.ImpliedType(spec)
+	dynamic := dynamicDatasourceOutputs[dsType]
+	if len(dynamic) == 0 || !ty.IsObjectType() {
+		return cty.UnknownVal(ty)
+	}
+	attrs := map[string]cty.Type{}
+	for name, attrType := range ty.AttributeTypes() {
+		attrs[name] = attrType
+	}
+	for _, name := range dynamic {
+		attrs[name] = cty.DynamicPseudoType
+	}
+	return cty.UnknownVal(cty.Object(attrs))
+}
+
 func (ds *Datasources) Values() (map[string]cty.Value, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	res := map[string]cty.Value{}
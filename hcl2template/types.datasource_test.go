@@ -7,8 +7,11 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/hashicorp/hcl/v2/hcldec"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer/builder/null"
 	"github.com/hashicorp/packer/packer"
+	"github.com/zclconf/go-cty/cty"
 )
 
 func TestParse_datasource(t *testing.T) {
@@ -261,3 +264,45 @@ func TestParse_datasource(t *testing.T) {
 
 	testParse(t, tests)
 }
+
+func TestDatasourcePlaceholder(t *testing.T) {
+	spec := hcldec.ObjectSpec{
+		"body": &hcldec.AttrSpec{Name: "body", Type: cty.String},
+	}
+
+	placeholder := datasourcePlaceholder("http", spec)
+	if placeholder.IsKnown() {
+		t.Fatalf("placeholder should be unknown, got %#v", placeholder)
+	}
+	attrs := placeholder.Type().AttributeTypes()
+	if attrs["body"] != cty.String || attrs["response_json"] != cty.DynamicPseudoType {
+		t.Fatalf("unexpected placeholder type %#v", placeholder.Type())
+	}
+
+	if placeholder := datasourcePlaceholder("null", spec); placeholder.Type().HasAttribute("response_json") {
+		t.Fatalf("only http should have a response_json placeholder, got %#v", placeholder.Type())
+	}
+}
+
+func TestFilterDatasourceFromLogs(t *testing.T) {
+	value := cty.ObjectVal(map[string]cty.Value{
+		"result": cty.ObjectVal(map[string]cty.Value{
+			"password": cty.StringVal("datasource-secret-password"),
+		}),
+	})
+
+	filterDatasourceFromLogs("external", cty.ObjectVal(map[string]cty.Value{"sensitive": cty.False}), value)
+	if got := packersdk.LogSecretFilter.FilterString("datasource-secret-password"); got != "datasource-secret-password" {
+		t.Fatalf("the value should not be hidden, got %q", got)
+	}
+
+	filterDatasourceFromLogs("mock", cty.ObjectVal(map[string]cty.Value{"sensitive": cty.True}), value)
+	if got := packersdk.LogSecretFilter.FilterString("datasource-secret-password"); got != "datasource-secret-password" {
+		t.Fatalf("only the external datasource should be hidden, got %q", got)
+	}
+
+	filterDatasourceFromLogs("external", cty.ObjectVal(map[string]cty.Value{"sensitive": cty.True}), value)
+	if got := packersdk.LogSecretFilter.FilterString("datasource-secret-password"); got != "<sensitive>" {
+		t.Fatalf("the value should be hidden, got %q", got)
+	}
+}
@@ -5,12 +5,14 @@ package hcl2template
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hcldec"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	hcl2shim "github.com/hashicorp/packer/hcl2template/shim"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 )
 
 // HCL2Provisioner has a reference to the part of the HCL2 body where it is
@@ -29,28 +31,85 @@ func (p *HCL2Provisioner) ConfigSpec() hcldec.ObjectSpec {
 	return p.Provisioner.ConfigSpec()
 }
 
-func (p *HCL2Provisioner) HCL2Prepare(buildVars map[string]interface{}) error {
-	var diags hcl.Diagnostics
-	ectx := p.evalContext
-	if len(buildVars) > 0 {
-		ectx = p.evalContext.NewChild()
-		buildValues := map[string]cty.Value{}
-		if !p.evalContext.Variables[buildAccessor].IsNull() {
-			for k, v := range p.evalContext.Variables[buildAccessor].AsValueMap() {
-				buildValues[k] = v
-			}
-		}
-		for k, v := range buildVars {
-			val, err := ConvertPluginConfigValueToHCLValue(v)
-			if err != nil {
-				return err
-			}
-
-			buildValues[k] = val
+// buildEvalContext returns the evaluation context of the provisioner, with
+// the build variables generated by the builder set.
+func (p *HCL2Provisioner) buildEvalContext(buildVars map[string]interface{}) (*hcl.EvalContext, error) {
+	if len(buildVars) == 0 {
+		return p.evalContext, nil
+	}
+	ectx := p.evalContext.NewChild()
+	buildValues := map[string]cty.Value{}
+	if !p.evalContext.Variables[buildAccessor].IsNull() {
+		for k, v := range p.evalContext.Variables[buildAccessor].AsValueMap() {
+			buildValues[k] = v
 		}
-		ectx.Variables = map[string]cty.Value{
-			buildAccessor: cty.ObjectVal(buildValues),
+	}
+	for k, v := range buildVars {
+		val, err := ConvertPluginConfigValueToHCLValue(v)
+		if err != nil {
+			return nil, err
 		}
+
+		buildValues[k] = val
+	}
+	ectx.Variables = map[string]cty.Value{
+		buildAccessor: cty.ObjectVal(buildValues),
+	}
+	return ectx, nil
+}
+
+// ShouldRun evaluates the only_if expression of the provisioner with the
+// build variables generated by the builder.
+func (p *HCL2Provisioner) ShouldRun(buildVars map[string]interface{}) (bool, error) {
+	expr := p.provisionerBlock.OnlyIf
+	if expr == nil {
+		return true, nil
+	}
+	ectx, err := p.buildEvalContext(buildVars)
+	if err != nil {
+		return false, err
+	}
+	val, diags := expr.Value(ectx)
+	if diags.HasErrors() {
+		return false, diags
+	}
+	val, err = convert.Convert(val, cty.Bool)
+	if err != nil {
+		return false, fmt.Errorf("%s: only_if must be a bool: %s", expr.Range(), err)
+	}
+	if val.IsNull() || !val.IsKnown() {
+		return false, fmt.Errorf("%s: only_if must be either true or false, not null or unknown", expr.Range())
+	}
+	return val.True(), nil
+}
+
+// LogFilePath evaluates the log_file expression of the provisioner with the
+// build variables generated by the builder.
+func (p *HCL2Provisioner) LogFilePath(buildVars map[string]interface{}) (string, error) {
+	expr := p.provisionerBlock.LogFile
+	ectx, err := p.buildEvalContext(buildVars)
+	if err != nil {
+		return "", err
+	}
+	val, diags := expr.Value(ectx)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	val, err = convert.Convert(val, cty.String)
+	if err != nil {
+		return "", fmt.Errorf("%s: log_file must be a string: %s", expr.Range(), err)
+	}
+	if val.IsNull() || !val.IsKnown() || val.AsString() == "" {
+		return "", fmt.Errorf("%s: log_file must be a path, not empty, null or unknown", expr.Range())
+	}
+	return val.AsString(), nil
+}
+
+func (p *HCL2Provisioner) HCL2Prepare(buildVars map[string]interface{}) error {
+	var diags hcl.Diagnostics
+	ectx, err := p.buildEvalContext(buildVars)
+	if err != nil {
+		return err
 	}
 
 	flatProvisionerCfg, moreDiags := decodeHCL2Spec(p.provisionerBlock.HCL2Ref.Rest, ectx, p.Provisioner)
@@ -10,11 +10,11 @@ import (
 
 	"github.com/gobwas/glob"
 	"github.com/hashicorp/hcl/v2"
-	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	pkrfunction "github.com/hashicorp/packer/hcl2template/function"
 	"github.com/hashicorp/packer/packer"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 	"github.com/zclconf/go-cty/cty/function"
 )
 
@@ -463,7 +463,7 @@ func (cfg *PackerConfig) recursivelyEvaluateDatasources(ref DatasourceRef, depen
 	}
 
 	if skipExecution {
-		placeholderValue := cty.UnknownVal(hcldec.ImpliedType(datasource.OutputSpec()))
+		placeholderValue := datasourcePlaceholder(ds.Type, datasource.OutputSpec())
 		ds.value = placeholderValue
 		cfg.Datasources[ref] = ds
 		return dependencies, diags
@@ -482,6 +482,7 @@ func (cfg *PackerConfig) recursivelyEvaluateDatasources(ref DatasourceRef, depen
 		return dependencies, diags
 	}
 
+	filterDatasourceFromLogs(ds.Type, opts, realValue)
 	ds.value = realValue
 	cfg.Datasources[ref] = ds
 	// remove ref from the dependencies map.
@@ -502,7 +503,7 @@ func (cfg *PackerConfig) evaluateDatasource(ds DatasourceBlock, skipExecution bo
 	}
 
 	if skipExecution {
-		placeholderValue := cty.UnknownVal(hcldec.ImpliedType(datasource.OutputSpec()))
+		placeholderValue := datasourcePlaceholder(ds.Type, datasource.OutputSpec())
 		ds.value = placeholderValue
 		cfg.Datasources[ds.Ref()] = ds
 		return diags
@@ -521,6 +522,7 @@ func (cfg *PackerConfig) evaluateDatasource(ds DatasourceBlock, skipExecution bo
 		return diags
 	}
 
+	filterDatasourceFromLogs(ds.Type, opts, realValue)
 	ds.value = realValue
 	cfg.Datasources[ds.Ref()] = ds
 
@@ -557,6 +559,17 @@ func (cfg *PackerConfig) getCoreBuildProvisioner(source SourceUseBlock, pb *Prov
 
 	flatProvisionerCfg, _ := decodeHCL2Spec(pb.HCL2Ref.Rest, ectx, provisioner)
 
+	var onlyIf func(map[string]interface{}) (bool, error)
+	var logFile func(map[string]interface{}) (string, error)
+	if hclProvisioner, ok := provisioner.(*HCL2Provisioner); ok {
+		if pb.OnlyIf != nil {
+			onlyIf = hclProvisioner.ShouldRun
+		}
+		if pb.LogFile != nil {
+			logFile = hclProvisioner.LogFilePath
+		}
+	}
+
 	// If we're pausing, we wrap the provisioner in a special pauser.
 	if pb.PauseBefore != 0 {
 		provisioner = &packer.PausedProvisioner{
@@ -582,6 +595,31 @@ func (cfg *PackerConfig) getCoreBuildProvisioner(source SourceUseBlock, pb *Prov
 		}
 	}
 
+	if onlyIf != nil {
+		if moreDiags := checkOnlyIf(pb.OnlyIf, ectx); moreDiags.HasErrors() {
+			return packer.CoreBuildProvisioner{}, append(diags, moreDiags...)
+		}
+	}
+
+	if logFile != nil {
+		// The build variables are only known when the provisioner runs,
+		// only check that the expression can be evaluated.
+		if _, moreDiags := pb.LogFile.Value(ectx); moreDiags.HasErrors() {
+			return packer.CoreBuildProvisioner{}, append(diags, moreDiags...)
+		}
+		provisioner = &packer.LogFileProvisioner{
+			Provisioner: provisioner,
+			Path:        logFile,
+		}
+	}
+
+	if len(pb.Outputs) > 0 {
+		provisioner = &packer.OutputsProvisioner{
+			Provisioner: provisioner,
+			Outputs:     pb.Outputs,
+		}
+	}
+
 	// Wrap last (outside retries) so retries are exhausted before the error
 	// is ignored and the build is allowed to continue.
 	if pb.ContinueOnError {
@@ -595,9 +633,46 @@ func (cfg *PackerConfig) getCoreBuildProvisioner(source SourceUseBlock, pb *Prov
 		PName:       pb.PName,
 		Provisioner: provisioner,
 		HCLConfig:   flatProvisionerCfg,
+		OnlyIf:      onlyIf,
 	}, diags
 }
 
+// checkOnlyIf checks the references of an only_if expression, and that it
+// can be a bool. The build variables are only known when the provisioner
+// runs, they are replaced with unknown strings.
+func checkOnlyIf(expr hcl.Expression, ectx *hcl.EvalContext) hcl.Diagnostics {
+	ectx = ectx.NewChild()
+	ectx.Variables = map[string]cty.Value{}
+	if build := ectx.Parent().Variables[buildAccessor]; !build.IsNull() && build.IsKnown() && build.Type().IsObjectType() {
+		buildValues := map[string]cty.Value{}
+		for k, v := range build.AsValueMap() {
+			buildValues[k] = cty.UnknownVal(cty.String)
+			if k == "name" {
+				buildValues[k] = v
+			}
+		}
+		ectx.Variables[buildAccessor] = cty.ObjectVal(buildValues)
+	}
+
+	val, diags := expr.Value(ectx)
+	if diags.HasErrors() {
+		return diags
+	}
+	if _, err := convert.Convert(val, cty.Bool); err != nil || val.IsNull() {
+		detail := "only_if must be either true or false, not null"
+		if err != nil {
+			detail = fmt.Sprintf("only_if must be a bool: %s", err)
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid only_if",
+			Detail:   detail,
+			Subject:  expr.Range().Ptr(),
+		})
+	}
+	return diags
+}
+
 // getCoreBuildProvisioners takes a list of post processor block, starts
 // according provisioners and sends parsed HCL2 over to it.
 func (cfg *PackerConfig) getCoreBuildPostProcessors(source SourceUseBlock, blocksList [][]*PostProcessorBlock, ectx *hcl.EvalContext, exceptMatches *int) ([][]packer.CoreBuildPostProcessor, hcl.Diagnostics) {
@@ -803,6 +878,11 @@ func (cfg *PackerConfig) GetBuilds(opts packer.GetBuildsOptions) ([]*packer.Core
 			for _, k := range append(packer.BuilderDataCommonKeys, generatedVars...) {
 				unknownBuildValues[k] = cty.StringVal("<unknown>")
 			}
+			for _, pb := range build.ProvisionerBlocks {
+				for k := range pb.Outputs {
+					unknownBuildValues[k] = cty.StringVal("<unknown>")
+				}
+			}
 			unknownBuildValues["name"] = cty.StringVal(build.Name)
 
 			variables := map[string]cty.Value{
@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/packer/hcl2template/addrs"
+	"github.com/hashicorp/packer/packer"
 	"github.com/zclconf/go-cty/cty"
 )
 
@@ -53,7 +54,10 @@ type RequiredPlugin struct {
 	Source      string
 	Type        *addrs.Plugin
 	Requirement VersionConstraint
-	DeclRange   hcl.Range
+	// Sandbox restricts what the plugin processes can do, it is nil when
+	// the plugin is not sandboxed.
+	Sandbox   *packer.PluginSandbox
+	DeclRange hcl.Range
 }
 
 type RequiredPlugins struct {
@@ -197,18 +201,24 @@ func decodeRequiredPluginsBlock(block *hcl.Block) (*RequiredPlugins, hcl.Diagnos
 
 			attrTypes := expr.Type().AttributeTypes()
 			for name := range attrTypes {
-				if name == "version" || name == "source" {
+				if name == "version" || name == "source" || name == "sandbox" {
 					continue
 				}
 				diags = append(diags, &hcl.Diagnostic{
 					Severity: hcl.DiagError,
 					Summary:  "Invalid required_plugins object",
-					Detail:   `required_plugins objects can only contain "version" and "source" attributes.`,
+					Detail:   `required_plugins objects can only contain "version", "source" and "sandbox" attributes.`,
 					Subject:  attr.Expr.Range().Ptr(),
 				})
 				break
 			}
 
+			if expr.Type().HasAttribute("sandbox") {
+				sandbox, sandboxDiags := decodePluginSandbox(expr.GetAttr("sandbox"), attr.Expr.Range())
+				diags = append(diags, sandboxDiags...)
+				rp.Sandbox = sandbox
+			}
+
 		default:
 			// should not happen
 			diags = append(diags, &hcl.Diagnostic{
@@ -228,6 +238,52 @@ func decodeRequiredPluginsBlock(block *hcl.Block) (*RequiredPlugins, hcl.Diagnos
 	return ret, diags
 }
 
+// decodePluginSandbox decodes the sandbox attribute of a required_plugins
+// entry. Both of its attributes are optional, declaring an empty sandbox
+// denies network access and writes outside of the temporary directory.
+func decodePluginSandbox(val cty.Value, declRange hcl.Range) (*packer.PluginSandbox, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	invalid := func(detail string) hcl.Diagnostics {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid plugin sandbox",
+			Detail:   detail,
+			Subject:  declRange.Ptr(),
+		})
+	}
+
+	if val.IsNull() || !val.Type().IsObjectType() {
+		return nil, invalid(`The sandbox must be an object. For example: sandbox = { network = true, writable_paths = ["./output"] }`)
+	}
+
+	sandbox := &packer.PluginSandbox{}
+	for name := range val.Type().AttributeTypes() {
+		v := val.GetAttr(name)
+		switch name {
+		case "network":
+			if v.IsNull() || !v.Type().Equals(cty.Bool) {
+				return nil, invalid("The network sandbox setting must be a boolean.")
+			}
+			sandbox.Network = v.True()
+		case "writable_paths":
+			if v.IsNull() || !(v.Type().IsTupleType() || v.Type().IsListType()) {
+				return nil, invalid("The writable_paths sandbox setting must be a list of strings.")
+			}
+			for it := v.ElementIterator(); it.Next(); {
+				_, path := it.Element()
+				if path.IsNull() || !path.Type().Equals(cty.String) {
+					return nil, invalid("The writable_paths sandbox setting must be a list of strings.")
+				}
+				sandbox.WritablePaths = append(sandbox.WritablePaths, path.AsString())
+			}
+		default:
+			return nil, invalid(fmt.Sprintf(`Unknown sandbox setting %q, plugin sandboxes can only contain "network" and "writable_paths" attributes.`, name))
+		}
+	}
+
+	return sandbox, diags
+}
+
 // checkPluginNameNormalized verifies that the given string is already
 // normalized and returns an error if not.
 func checkPluginNameNormalized(name string, declrange hcl.Range) hcl.Diagnostics {
@@ -9,6 +9,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/packer/hcl2template/addrs"
+	"github.com/hashicorp/packer/packer"
 )
 
 func TestPackerConfig_required_plugin_parse(t *testing.T) {
@@ -112,6 +113,44 @@ func TestPackerConfig_required_plugin_parse(t *testing.T) {
 				},
 			},
 		}},
+		{"required_plugin_sandboxed", PackerConfig{parser: getBasicParser()}, `
+		packer {
+			required_plugins {
+				amazon = {
+					source  = "github.com/hashicorp/amazon"
+					version = "~> v1.2.3"
+					sandbox = {
+						network        = true
+						writable_paths = ["./output", "/var/cache/packer"]
+					}
+				}
+			}
+		} `, `
+		source "amazon-ebs" "example" {
+		}
+		`, false, PackerConfig{
+			Packer: struct {
+				VersionConstraints []VersionConstraint
+				RequiredPlugins    []*RequiredPlugins
+			}{
+				RequiredPlugins: []*RequiredPlugins{
+					{RequiredPlugins: map[string]*RequiredPlugin{
+						"amazon": {
+							Name:   "amazon",
+							Source: "github.com/hashicorp/amazon",
+							Type:   &addrs.Plugin{Source: "github.com/hashicorp/amazon"},
+							Requirement: VersionConstraint{
+								Required: mustVersionConstraints(version.NewConstraint("~> v1.2.3")),
+							},
+							Sandbox: &packer.PluginSandbox{
+								Network:       true,
+								WritablePaths: []string{"./output", "/var/cache/packer"},
+							},
+						},
+					}},
+				},
+			},
+		}},
 		{"missing-required-plugin-for-pre-defined-builder", PackerConfig{
 			parser: getBasicParser(func(p *Parser) {})},
 			`
@@ -152,3 +191,36 @@ func TestPackerConfig_required_plugin_parse(t *testing.T) {
 		})
 	}
 }
+
+func TestPackerConfig_required_plugin_invalid_sandbox(t *testing.T) {
+	tests := []struct {
+		name    string
+		sandbox string
+	}{
+		{"not an object", `sandbox = true`},
+		{"unknown setting", `sandbox = { syscalls = ["ptrace"] }`},
+		{"network not a bool", `sandbox = { network = "yes" }`},
+		{"writable_paths not a list", `sandbox = { writable_paths = "/tmp" }`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := PackerConfig{parser: getBasicParser()}
+			file, diags := cfg.parser.ParseHCL([]byte(`
+			packer {
+				required_plugins {
+					amazon = {
+						source  = "github.com/hashicorp/amazon"
+						version = "~> v1.2.3"
+						`+tt.sandbox+`
+					}
+				}
+			}`), "required_plugins.pkr.hcl")
+			if len(diags) > 0 {
+				t.Fatal(diags)
+			}
+			if diags := cfg.decodeRequiredPluginsBlock(file); !diags.HasErrors() {
+				t.Fatalf("expected an invalid sandbox to be reported")
+			}
+		})
+	}
+}
@@ -45,16 +45,18 @@ func newPEMSigner(_ context.Context, cfg BackendConfig) (Signer, error) {
 	return &pemSigner{signer: signer, verifier: verifier}, nil
 }
 
-func (s *pemSigner) Sign(_ context.Context, payloadType string, payload []byte) (Signature, error) {
-	pae := PreAuthEncode(payloadType, payload)
+func (s *pemSigner) Sign(ctx context.Context, payloadType string, payload []byte) (Signature, error) {
+	return s.SignBlob(ctx, PreAuthEncode(payloadType, payload))
+}
 
+func (s *pemSigner) SignBlob(_ context.Context, blob []byte) (Signature, error) {
 	var message []byte
 	var opts crypto.SignerOpts
 	if _, ok := s.signer.Public().(ed25519.PublicKey); ok {
-		message = pae
+		message = blob
 		opts = crypto.Hash(0)
 	} else {
-		digest := sha256.Sum256(pae)
+		digest := sha256.Sum256(blob)
 		message = digest[:]
 		opts = crypto.SHA256
 	}
@@ -74,21 +76,23 @@ func (s *pemSigner) Verifier(context.Context, BackendConfig) (Verifier, error) {
 	return s.verifier, nil
 }
 
-func (v *pemVerifier) Verify(_ context.Context, payloadType string, payload, signature []byte) error {
-	pae := PreAuthEncode(payloadType, payload)
+func (v *pemVerifier) Verify(ctx context.Context, payloadType string, payload, signature []byte) error {
+	return v.VerifyBlob(ctx, PreAuthEncode(payloadType, payload), signature)
+}
 
+func (v *pemVerifier) VerifyBlob(_ context.Context, blob, signature []byte) error {
 	switch publicKey := v.publicKey.(type) {
 	case *rsa.PublicKey:
-		digest := sha256.Sum256(pae)
+		digest := sha256.Sum256(blob)
 		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature)
 	case *ecdsa.PublicKey:
-		digest := sha256.Sum256(pae)
+		digest := sha256.Sum256(blob)
 		if !ecdsa.VerifyASN1(publicKey, digest[:], signature) {
 			return fmt.Errorf("ECDSA verification failed")
 		}
 		return nil
 	case ed25519.PublicKey:
-		if !ed25519.Verify(publicKey, pae, signature) {
+		if !ed25519.Verify(publicKey, blob, signature) {
 			return fmt.Errorf("Ed25519 verification failed")
 		}
 		return nil
@@ -46,6 +46,34 @@ func TestPEMSignerAndVerifier(t *testing.T) {
 	}
 }
 
+func TestPEMSignerSignBlob(t *testing.T) {
+	privateKeyPath, publicKeyPath := writeECDSAKeypair(t)
+
+	signer, err := NewSigner(context.Background(), BackendConfig{
+		Mode:      SigningModeKey,
+		SignerRef: privateKeyPath,
+	})
+	if err != nil {
+		t.Fatalf("create signer: %v", err)
+	}
+	verifier, err := LoadPEMVerifier(publicKeyPath)
+	if err != nil {
+		t.Fatalf("create verifier: %v", err)
+	}
+
+	blob := []byte("e3b0c442  SHA256SUMS\n")
+	signature, err := signer.(BlobSigner).SignBlob(context.Background(), blob)
+	if err != nil {
+		t.Fatalf("sign blob: %v", err)
+	}
+	if err := verifier.(BlobVerifier).VerifyBlob(context.Background(), blob, signature.Sig); err != nil {
+		t.Fatalf("verify blob: %v", err)
+	}
+	if err := verifier.Verify(context.Background(), InTotoPayloadType, blob, signature.Sig); err == nil {
+		t.Fatal("a blob signature should not verify as a DSSE signature")
+	}
+}
+
 func TestVerifierOverrideMismatchFails(t *testing.T) {
 	privateKeyPath, _ := writeECDSAKeypair(t)
 	_, mismatchedPublicKeyPath := writeECDSAKeypair(t)
@@ -60,9 +60,12 @@ func newKMSSigner(ctx context.Context, cfg BackendConfig) (Signer, error) {
 	}, nil
 }
 
-func (s *kmsSigner) Sign(_ context.Context, payloadType string, payload []byte) (Signature, error) {
-	encoded := PreAuthEncode(payloadType, payload)
-	signature, err := s.signerVerifier.SignMessage(bytes.NewReader(encoded))
+func (s *kmsSigner) Sign(ctx context.Context, payloadType string, payload []byte) (Signature, error) {
+	return s.SignBlob(ctx, PreAuthEncode(payloadType, payload))
+}
+
+func (s *kmsSigner) SignBlob(_ context.Context, blob []byte) (Signature, error) {
+	signature, err := s.signerVerifier.SignMessage(bytes.NewReader(blob))
 	if err != nil {
 		return Signature{}, fmt.Errorf("sign payload with KMS: %w", err)
 	}
@@ -126,13 +129,12 @@ type sigstoreVerifier struct {
 	keyID    string
 }
 
-func (v *sigstoreVerifier) Verify(_ context.Context, payloadType string, payload, signature []byte) error {
-	encoded := PreAuthEncode(payloadType, payload)
-	if err := v.verifier.VerifySignature(bytes.NewReader(signature), bytes.NewReader(encoded)); err != nil {
-		return err
-	}
+func (v *sigstoreVerifier) Verify(ctx context.Context, payloadType string, payload, signature []byte) error {
+	return v.VerifyBlob(ctx, PreAuthEncode(payloadType, payload), signature)
+}
 
-	return nil
+func (v *sigstoreVerifier) VerifyBlob(_ context.Context, blob, signature []byte) error {
+	return v.verifier.VerifySignature(bytes.NewReader(signature), bytes.NewReader(blob))
 }
 
 func (v *sigstoreVerifier) KeyID() string {
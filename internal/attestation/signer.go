@@ -31,6 +31,19 @@ type Verifier interface {
 	KeyID() string
 }
 
+// BlobSigner is implemented by the signers that can sign raw bytes, for
+// detached signatures checked with `cosign verify-blob` or `openssl dgst`.
+// RSA and ECDSA keys sign the SHA-256 digest of the blob, Ed25519 keys the
+// blob itself.
+type BlobSigner interface {
+	SignBlob(ctx context.Context, blob []byte) (Signature, error)
+}
+
+// BlobVerifier is implemented by the verifiers of BlobSigner signatures.
+type BlobVerifier interface {
+	VerifyBlob(ctx context.Context, blob, signature []byte) error
+}
+
 type BackendConfig struct {
 	Mode              string
 	SignerRef         string
@@ -118,7 +118,7 @@ func detectLocalGitDependency(workingDir string, runner gitRunner) (ResolvedDepe
 		return ResolvedDependency{}, false
 	}
 
-	repositoryURL = sanitizeGitRemoteURL(repositoryURL)
+	repositoryURL = SanitizeGitRemoteURL(repositoryURL)
 
 	uri := "git+" + repositoryURL
 	if branch, branchErr := runner(workingDir, "rev-parse", "--abbrev-ref", "HEAD"); branchErr == nil && branch != "" && branch != "HEAD" {
@@ -133,7 +133,23 @@ func detectLocalGitDependency(workingDir string, runner gitRunner) (ResolvedDepe
 	}, true
 }
 
-func sanitizeGitRemoteURL(raw string) string {
+// GitWorktreeDirty reports whether the git worktree holding workingDir has
+// uncommitted changes, including untracked files. It runs `git status
+// --porcelain`, so all the ignore rules of git are honored.
+func GitWorktreeDirty(workingDir string) (bool, error) {
+	return gitWorktreeDirty(workingDir, runGitCommand)
+}
+
+func gitWorktreeDirty(workingDir string, runner gitRunner) (bool, error) {
+	status, err := runner(workingDir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return status != "", nil
+}
+
+// SanitizeGitRemoteURL strips the credentials embedded in a git remote URL.
+func SanitizeGitRemoteURL(raw string) string {
 	// SCP-style remotes (git@host:repo) carry no userinfo — leave them unchanged
 	if !strings.Contains(raw, "://") {
 		return raw
@@ -83,3 +83,21 @@ func TestDetectGitDependencyGracefullySkipsWhenUnavailable(t *testing.T) {
 		t.Fatalf("expected dependency detection to skip unavailable git context")
 	}
 }
+
+func TestGitWorktreeDirty(t *testing.T) {
+	for status, want := range map[string]bool{"": false, "?? build.log": true} {
+		runner := func(_ string, args ...string) (string, error) {
+			if got := strings.Join(args, " "); got != "status --porcelain" {
+				return "", fmt.Errorf("unexpected git args %q", got)
+			}
+			return status, nil
+		}
+		dirty, err := gitWorktreeDirty("/workspace/packer", runner)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if dirty != want {
+			t.Errorf("status %q: got dirty %t, want %t", status, dirty, want)
+		}
+	}
+}
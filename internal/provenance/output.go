@@ -0,0 +1,86 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package provenance
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// The provenance post-processor writes its outputs next to the artifact, or
+// to its output_dir, under a name derived from the artifact. These helpers
+// compute these paths, so that other post-processors can find the outputs.
+
+// OutputDir returns the directory the outputs for source are written to:
+// outputDir when set, or the directory of the first file of source.
+func OutputDir(source packersdk.Artifact, outputDir string) string {
+	if outputDir == "" && len(source.Files()) > 0 {
+		outputDir = filepath.Dir(source.Files()[0])
+	}
+	if outputDir == "" {
+		outputDir = "."
+	}
+	return outputDir
+}
+
+// OutputStem returns the base filename of the outputs for source. When a
+// build name is available it is prefixed so that parallel builds writing to
+// a shared output directory cannot collide on the same output paths.
+func OutputStem(source packersdk.Artifact, buildName string) string {
+	base := artifactStem(source)
+
+	buildName = SanitizeFilename(strings.TrimSpace(buildName))
+	if buildName == "" || base == buildName || strings.HasPrefix(base, buildName+".") {
+		return base
+	}
+
+	return buildName + "." + base
+}
+
+func artifactStem(source packersdk.Artifact) string {
+	if files := source.Files(); len(files) > 0 {
+		return filepath.Base(files[0])
+	}
+
+	return SanitizeFilename(fmt.Sprintf("%s-%s", source.BuilderId(), source.Id()))
+}
+
+// StatementPath returns the path of the provenance attestation.
+func StatementPath(dir, stem string) string {
+	return filepath.Join(dir, stem+".provenance.json")
+}
+
+// SBOMAttestationPath returns the path of the SBOM attestation.
+func SBOMAttestationPath(dir, stem string) string {
+	return filepath.Join(dir, stem+".sbom.att.json")
+}
+
+// SigstoreBundlePath returns the path of the Sigstore bundle written next to
+// the attestation at attestationPath, when signing keyless.
+func SigstoreBundlePath(attestationPath string) string {
+	if strings.HasSuffix(attestationPath, ".json") {
+		return strings.TrimSuffix(attestationPath, ".json") + ".sigstore.json"
+	}
+
+	return attestationPath + ".sigstore.json"
+}
+
+// SanitizeFilename replaces the characters that are not letters, digits,
+// `.`, `-` or `_` with `_`.
+func SanitizeFilename(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			return r
+		case r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, value)
+}
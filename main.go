@@ -12,6 +12,7 @@ import (
 	"log"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sync"
 	"syscall"
@@ -39,6 +40,12 @@ func main() {
 
 // realMain is executed from main and returns the exit status to exit with.
 func realMain() int {
+	if packer.InPluginSandboxLauncher() {
+		// We were started to run a plugin in a sandbox, this only returns
+		// if that fails.
+		return packer.RunPluginSandbox()
+	}
+
 	var wrapConfig panicwrap.WrapConfig
 	// When following env variable is set, packer
 	// won't panic wrap itself as it's already wrapped.
@@ -345,6 +352,15 @@ func loadConfig() (*config, error) {
 		DataSources:     packer.MapOfDatasource{},
 	}
 
+	if interval := os.Getenv("PACKER_PLUGIN_SAMPLE_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			log.Printf("[WARN] Invalid PACKER_PLUGIN_SAMPLE_INTERVAL %q, plugin resource sampling disabled: %s", interval, err)
+		} else {
+			config.Plugins.PluginResourceSampleInterval = d
+		}
+	}
+
 	// Finally, try to use an internal plugin. Note that this will not override
 	// any previously-loaded plugins.
 	if err := config.discoverInternalComponents(); err != nil {
@@ -385,6 +401,10 @@ func loadConfig() (*config, error) {
 		return nil, fmt.Errorf("%s: %s", configFilePath, err)
 	}
 
+	if err := config.Plugins.SetDevOverrides(config.DevOverrides, filepath.Dir(configFilePath)); err != nil {
+		return nil, fmt.Errorf("%s: %s", configFilePath, err)
+	}
+
 	return &config, nil
 }
 
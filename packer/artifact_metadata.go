@@ -0,0 +1,170 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package packer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/klauspost/compress/zstd"
+)
+
+// BuildMetadataState is the artifact state holding the metadata of the build
+// for post-processors, as a map with:
+//
+//   - started_at: when the build started, in RFC3339 format.
+//   - packer_version: the version of Packer running the build.
+//   - plugins: the version of the plugins used by the build, by name.
+//   - plugin_resources: the resources used by the plugins of the build, by
+//     name, as maps with peak_rss_bytes, cpu_seconds and samples. Only set
+//     when resource sampling is enabled.
+//   - status: the outcome of the build, one of BuildStatusSuccess,
+//     BuildStatusFailed and BuildStatusCancelled.
+//   - error: why the build failed, only set when it did.
+const BuildMetadataState = "build_metadata"
+
+const (
+	BuildStatusSuccess   = "success"
+	BuildStatusFailed    = "failed"
+	BuildStatusCancelled = "cancelled"
+)
+
+// recordOutcomePostProcessors are the post-processors that also run for the
+// builds that failed or were cancelled, with a failedBuildArtifact, so that
+// they record the outcome of every build.
+var recordOutcomePostProcessors = []string{"manifest"}
+
+// BuildSBOMsState is the artifact state holding the SBOMs downloaded by the
+// `hcp-sbom` provisioners of the build, as a list of maps with their name,
+// format (CYCLONEDX or SPDX) and content. They are only decompressed when a
+// post-processor reads them.
+const BuildSBOMsState = "build_sboms"
+
+// metadataArtifact wraps the artifacts passed to post-processors to add the
+// metadata and SBOMs of the build to their state.
+type metadataArtifact struct {
+	packersdk.Artifact
+	metadata map[string]interface{}
+	sboms    []SBOM
+}
+
+func (a *metadataArtifact) State(name string) interface{} {
+	switch name {
+	case BuildMetadataState:
+		return a.metadata
+	case BuildSBOMsState:
+		return sbomsState(a.sboms)
+	}
+	return a.Artifact.State(name)
+}
+
+// failedBuildArtifact stands for the artifact of a build that failed or was
+// cancelled, for the post-processors recording its outcome.
+type failedBuildArtifact struct {
+	builderID string
+}
+
+func (a *failedBuildArtifact) BuilderId() string        { return a.builderID }
+func (a *failedBuildArtifact) Files() []string          { return nil }
+func (a *failedBuildArtifact) Id() string               { return "" }
+func (a *failedBuildArtifact) String() string           { return "no artifact, the build did not succeed" }
+func (a *failedBuildArtifact) State(string) interface{} { return nil }
+func (a *failedBuildArtifact) Destroy() error           { return nil }
+
+// recordOutcome runs the recordOutcomePostProcessors of the build, after it
+// failed with buildErr, so that they record its outcome. They run even when
+// ctx is cancelled, their errors are only logged.
+func (b *CoreBuild) recordOutcome(ctx context.Context, originalUi packersdk.Ui, startedAt time.Time, buildErr error) {
+	status := BuildStatusFailed
+	if ctx.Err() != nil {
+		status = BuildStatusCancelled
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	var metadata map[string]interface{}
+	for _, ppSeq := range b.PostProcessors {
+		for _, corePP := range ppSeq {
+			if !slices.Contains(recordOutcomePostProcessors, corePP.PType) {
+				continue
+			}
+			if metadata == nil {
+				metadata = b.postProcessorMetadata(startedAt)
+				metadata["status"] = status
+				metadata["error"] = buildErr.Error()
+			}
+			ppUi := &TargetedUI{
+				Target: fmt.Sprintf("%s (%s)", b.Name(), corePP.PType),
+				Ui:     originalUi,
+			}
+			_, _, _, err := corePP.PostProcessor.PostProcess(ctx, ppUi, &metadataArtifact{
+				Artifact: &failedBuildArtifact{builderID: b.BuilderType},
+				metadata: metadata,
+			})
+			if err != nil {
+				log.Printf("[WARN] Unable to record the outcome of build %s with the %s post-processor: %s", b.Name(), corePP.PType, err)
+			}
+		}
+	}
+}
+
+// postProcessorMetadata returns the BuildMetadataState of the build. Only
+// basic types are used, so that it goes through RPC to plugins.
+func (b *CoreBuild) postProcessorMetadata(startedAt time.Time) map[string]interface{} {
+	metadata := b.GetMetadata()
+	plugins := map[string]interface{}{}
+	for name, plugin := range metadata.Plugins {
+		plugins[name] = plugin.Description.Version
+	}
+	result := map[string]interface{}{
+		"started_at":     startedAt.UTC().Format(time.RFC3339Nano),
+		"packer_version": metadata.PackerVersion,
+		"plugins":        plugins,
+		"status":         BuildStatusSuccess,
+	}
+	if len(metadata.PluginResources) > 0 {
+		resources := map[string]interface{}{}
+		for name, usage := range metadata.PluginResources {
+			resources[name] = map[string]interface{}{
+				"peak_rss_bytes": int64(usage.PeakRSS),
+				"cpu_seconds":    usage.CPUTime.Seconds(),
+				"samples":        int64(usage.Samples),
+			}
+		}
+		result["plugin_resources"] = resources
+	}
+	return result
+}
+
+// sbomsState returns the BuildSBOMsState of the build, the decompressed
+// sboms.
+func sbomsState(sboms []SBOM) []interface{} {
+	result := []interface{}{}
+	if len(sboms) == 0 {
+		return result
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		log.Printf("[WARN] Unable to create a zstd decoder for the SBOMs: %s", err)
+		return result
+	}
+	defer decoder.Close()
+
+	for _, sbom := range sboms {
+		content, err := decoder.DecodeAll(sbom.CompressedData, nil)
+		if err != nil {
+			log.Printf("[WARN] Unable to decompress SBOM %q: %s", sbom.Name, err)
+			continue
+		}
+		result = append(result, map[string]interface{}{
+			"name":    sbom.Name,
+			"format":  string(sbom.Format),
+			"content": string(content),
+		})
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package packer
+
+import (
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// outputsArtifact wraps the artifact of a builder to add the outputs read by
+// provisioners to its generated data, so that post-processors can reference
+// them like the data generated by the builder.
+type outputsArtifact struct {
+	packersdk.Artifact
+	outputs map[string]string
+}
+
+func (a *outputsArtifact) State(name string) interface{} {
+	state := a.Artifact.State(name)
+	if name != "generated_data" {
+		return state
+	}
+
+	generatedData := map[interface{}]interface{}{}
+	switch data := state.(type) {
+	case map[interface{}]interface{}:
+		for k, v := range data {
+			generatedData[k] = v
+		}
+	case map[string]interface{}:
+		for k, v := range data {
+			generatedData[k] = v
+		}
+	}
+	for k, v := range a.outputs {
+		generatedData[k] = v
+	}
+	return generatedData
+}
@@ -7,7 +7,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"maps"
 	"sync"
+	"time"
 
 	hcpPackerModels "github.com/hashicorp/hcp-sdk-go/clients/cloud-packer-service/stable/2023-01-01/models"
 	"github.com/hashicorp/packer-plugin-sdk/common"
@@ -67,6 +69,10 @@ type BuildMetadata struct {
 	PackerVersion string
 	Plugins       map[string]PluginDetails
 	SBOMs         []SBOM
+	// PluginResources is the resource usage sampled for the plugin
+	// processes of the build, keyed by plugin name. It is only populated
+	// when resource sampling is enabled.
+	PluginResources map[string]PluginResourceUsage
 }
 
 func (b *CoreBuild) getPluginsMetadata() map[string]PluginDetails {
@@ -102,9 +108,47 @@ func (b *CoreBuild) GetMetadata() BuildMetadata {
 		Plugins:       b.getPluginsMetadata(),
 		SBOMs:         b.SBOMs,
 	}
+	metadata.PluginResources = b.pluginResources()
 	return metadata
 }
 
+// pluginResources sums the resource usage sampled for the plugin processes
+// started for the components of the build, by plugin name.
+func (b *CoreBuild) pluginResources() map[string]PluginResourceUsage {
+	components := []interface{}{b.Builder}
+	for _, p := range b.Provisioners {
+		components = append(components, p.Provisioner)
+	}
+	if b.CleanupProvisioner.Provisioner != nil {
+		components = append(components, b.CleanupProvisioner.Provisioner)
+	}
+	for _, pps := range b.PostProcessors {
+		for _, pp := range pps {
+			components = append(components, pp.PostProcessor)
+		}
+	}
+
+	var resources map[string]PluginResourceUsage
+	seen := map[*PluginClient]bool{}
+	for _, component := range components {
+		client := pluginClientOf(component)
+		if client == nil || seen[client] {
+			continue
+		}
+		seen[client] = true
+		usage, ok := client.ResourceUsage()
+		if !ok {
+			continue
+		}
+		if resources == nil {
+			resources = map[string]PluginResourceUsage{}
+		}
+		name := client.resourceName()
+		resources[name] = resources[name].add(usage)
+	}
+	return resources
+}
+
 // CoreBuildPostProcessor Keeps track of the post-processor and the
 // configuration of the post-processor used within a build.
 type CoreBuildPostProcessor struct {
@@ -133,6 +177,9 @@ type CoreBuildProvisioner struct {
 	// Its only use is for telemetry, since we use it to extract the
 	// field names from it.
 	HCLConfig cty.Value
+	// OnlyIf, when set, decides at run time whether the provisioner runs.
+	// See OnlyIfProvisioner.
+	OnlyIf func(generatedData map[string]interface{}) (bool, error)
 	// config is JSON-specific, and is the configuration of the
 	// provisioner, with overrides
 	config []interface{}
@@ -270,6 +317,7 @@ func (b *CoreBuild) Run(ctx context.Context, originalUi packersdk.Ui) ([]packers
 	if !b.prepareCalled {
 		panic("Prepare must be called first")
 	}
+	startedAt := time.Now()
 
 	// Copy the hooks
 	hooks := make(map[string][]packersdk.Hook)
@@ -288,19 +336,24 @@ func (b *CoreBuild) Run(ctx context.Context, originalUi packersdk.Ui) ([]packers
 			} else {
 				pConfig = p.HCLConfig
 			}
+			provisioner := p.Provisioner
 			if b.debug {
-				hookedProvisioners[i] = &HookedProvisioner{
-					&DebuggedProvisioner{Provisioner: p.Provisioner},
-					pConfig,
-					p.PType,
-				}
-			} else {
-				hookedProvisioners[i] = &HookedProvisioner{
-					p.Provisioner,
-					pConfig,
-					p.PType,
+				provisioner = &DebuggedProvisioner{Provisioner: provisioner}
+			}
+			// Evaluated first so that skipped provisioners don't pause
+			// in debug mode.
+			if p.OnlyIf != nil {
+				provisioner = &OnlyIfProvisioner{
+					Provisioner: provisioner,
+					TypeName:    p.PType,
+					OnlyIf:      p.OnlyIf,
 				}
 			}
+			hookedProvisioners[i] = &HookedProvisioner{
+				Provisioner: provisioner,
+				Config:      pConfig,
+				TypeName:    p.PType,
+			}
 		}
 
 		if _, ok := hooks[packersdk.HookProvision]; !ok {
@@ -313,10 +366,18 @@ func (b *CoreBuild) Run(ctx context.Context, originalUi packersdk.Ui) ([]packers
 	}
 
 	if b.CleanupProvisioner.PType != "" {
+		provisioner := b.CleanupProvisioner.Provisioner
+		if b.CleanupProvisioner.OnlyIf != nil {
+			provisioner = &OnlyIfProvisioner{
+				Provisioner: provisioner,
+				TypeName:    b.CleanupProvisioner.PType,
+				OnlyIf:      b.CleanupProvisioner.OnlyIf,
+			}
+		}
 		hookedCleanupProvisioner := &HookedProvisioner{
-			b.CleanupProvisioner.Provisioner,
-			b.CleanupProvisioner.config,
-			b.CleanupProvisioner.PType,
+			Provisioner: provisioner,
+			Config:      b.CleanupProvisioner.config,
+			TypeName:    b.CleanupProvisioner.PType,
 		}
 		hooks[packersdk.HookCleanupProvision] = []packersdk.Hook{&ProvisionHook{
 			Provisioners: []*HookedProvisioner{hookedCleanupProvisioner},
@@ -342,6 +403,7 @@ func (b *CoreBuild) Run(ctx context.Context, originalUi packersdk.Ui) ([]packers
 	builderArtifact, err := b.Builder.Run(ctx, builderUi, hook)
 	ts.End(err)
 	if err != nil {
+		b.recordOutcome(ctx, originalUi, startedAt, err)
 		return nil, err
 	}
 
@@ -357,22 +419,49 @@ func (b *CoreBuild) Run(ctx context.Context, originalUi packersdk.Ui) ([]packers
 		}
 	}
 
+	outputs := map[string]string{}
+	for _, p := range b.Provisioners {
+		for provisioner := p.Provisioner; provisioner != nil; {
+			switch wrapper := provisioner.(type) {
+			case *ContinueOnErrorProvisioner:
+				provisioner = wrapper.Provisioner
+			case *OutputsProvisioner:
+				maps.Copy(outputs, wrapper.Values)
+				provisioner = wrapper.Provisioner
+			case *LogFileProvisioner:
+				provisioner = wrapper.Provisioner
+			default:
+				provisioner = nil
+			}
+		}
+	}
+
 	// If there was no result, don't worry about running post-processors
 	// because there is nothing they can do, just return.
 	if builderArtifact == nil {
 		return nil, nil
 	}
 
+	if len(outputs) > 0 {
+		builderArtifact = &outputsArtifact{
+			Artifact: builderArtifact,
+			outputs:  outputs,
+		}
+	}
+
 	errors := make([]error, 0)
 	keepOriginalArtifact := len(b.PostProcessors) == 0
 
 	select {
 	case <-ctx.Done():
 		log.Println("Build was cancelled. Skipping post-processors.")
+		b.recordOutcome(ctx, originalUi, startedAt, ctx.Err())
 		return nil, ctx.Err()
 	default:
 	}
 
+	buildMetadata := b.postProcessorMetadata(startedAt)
+
 	// Run the post-processors
 PostProcessorRunSeqLoop:
 	for _, ppSeq := range b.PostProcessors {
@@ -394,7 +483,11 @@ PostProcessorRunSeqLoop:
 			} else {
 				ts = CheckpointReporter.AddSpan(corePP.PType, "post-processor", corePP.HCLConfig)
 			}
-			artifact, defaultKeep, forceOverride, err := corePP.PostProcessor.PostProcess(ctx, ppUi, priorArtifact)
+			artifact, defaultKeep, forceOverride, err := corePP.PostProcessor.PostProcess(ctx, ppUi, &metadataArtifact{
+				Artifact: priorArtifact,
+				metadata: buildMetadata,
+				sboms:    b.SBOMs,
+			})
 			ts.End(err)
 			if err != nil {
 				errors = append(errors, fmt.Errorf("Post-processor failed: %s", err))
@@ -7,11 +7,14 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
+	hcpPackerModels "github.com/hashicorp/hcp-sdk-go/clients/cloud-packer-service/stable/2023-01-01/models"
 	"github.com/hashicorp/packer-plugin-sdk/common"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 	"github.com/hashicorp/packer/version"
+	"github.com/klauspost/compress/zstd"
 	"github.com/zclconf/go-cty/cty"
 )
 
@@ -363,6 +366,64 @@ func TestBuild_Run(t *testing.T) {
 	if !pp.PostProcessCalled {
 		t.Fatal("should be called")
 	}
+
+	// Verify the post-processor can read the metadata of the build
+	metadata, ok := pp.PostProcessArtifact.State(BuildMetadataState).(map[string]interface{})
+	if !ok {
+		t.Fatalf("bad: %#v", pp.PostProcessArtifact.State(BuildMetadataState))
+	}
+	if metadata["packer_version"] != version.FormattedVersion() || metadata["started_at"] == "" {
+		t.Fatalf("bad: %#v", metadata)
+	}
+}
+
+func TestMetadataArtifact_SBOMs(t *testing.T) {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	artifact := &metadataArtifact{
+		Artifact: new(packersdk.MockArtifact),
+		sboms: []SBOM{{
+			Name:           "vm",
+			Format:         hcpPackerModels.HashicorpCloudPacker20230101SbomFormatCYCLONEDX,
+			CompressedData: encoder.EncodeAll([]byte(`{"bomFormat": "CycloneDX"}`), nil),
+		}},
+	}
+
+	sboms := artifact.State(BuildSBOMsState).([]interface{})
+	expected := []interface{}{map[string]interface{}{
+		"name":    "vm",
+		"format":  "CYCLONEDX",
+		"content": `{"bomFormat": "CycloneDX"}`,
+	}}
+	if !reflect.DeepEqual(sboms, expected) {
+		t.Fatalf("bad: %#v", sboms)
+	}
+}
+
+func TestBuild_GetMetadata_PluginResources(t *testing.T) {
+	newClient := func(name string, usage PluginResourceUsage) *PluginClient {
+		return &PluginClient{config: &PluginClientConfig{Name: name}, resources: usage}
+	}
+	build := testBuild()
+	build.Builder = &cmdBuilder{client: newClient("test", PluginResourceUsage{PeakRSS: 4096, CPUTime: time.Second, Samples: 2})}
+	build.Provisioners[0].Provisioner = &PausedProvisioner{
+		Provisioner: &cmdProvisioner{client: newClient("test", PluginResourceUsage{PeakRSS: 2048, CPUTime: time.Second, Samples: 1})},
+	}
+
+	resources := build.GetMetadata().PluginResources
+	expected := map[string]PluginResourceUsage{
+		"test": {PeakRSS: 4096, CPUTime: 2 * time.Second, Samples: 3},
+	}
+	if !reflect.DeepEqual(resources, expected) {
+		t.Fatalf("bad: %#v", resources)
+	}
+
+	// The usage of the processes of other builds is not included.
+	if resources := testBuild().GetMetadata().PluginResources; resources != nil {
+		t.Fatalf("bad: %#v", resources)
+	}
 }
 
 func TestBuild_Run_Artifacts(t *testing.T) {
@@ -555,6 +616,83 @@ func TestBuild_Run_Artifacts(t *testing.T) {
 	}
 }
 
+func TestBuild_Run_Outputs(t *testing.T) {
+	ui := testUi()
+
+	build := testBuild()
+	build.Provisioners[0].Provisioner = &ContinueOnErrorProvisioner{
+		Provisioner: &OutputsProvisioner{
+			Provisioner: new(packersdk.MockProvisioner),
+			Outputs:     map[string]string{"kernel": "/tmp/kernel_version"},
+		},
+	}
+	build.Prepare()
+	if _, err := build.Run(context.Background(), ui); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	pp := build.PostProcessors[0][0].PostProcessor.(*MockPostProcessor)
+	generatedData, ok := pp.PostProcessArtifact.State("generated_data").(map[interface{}]interface{})
+	if !ok {
+		t.Fatalf("unexpected generated data %#v", pp.PostProcessArtifact.State("generated_data"))
+	}
+	if _, ok := generatedData["kernel"]; !ok {
+		t.Fatalf("expected the output in the generated data, got %#v", generatedData)
+	}
+}
+
+func TestBuild_Run_recordsFailure(t *testing.T) {
+	ui := testUi()
+
+	build := testBuild()
+	build.Builder = &packersdk.MockBuilder{RunErrResult: true}
+	manifest := &MockPostProcessor{ArtifactId: "manifest"}
+	build.PostProcessors = append(build.PostProcessors, []CoreBuildPostProcessor{
+		{manifest, "manifest", "manifest", cty.Value{}, make(map[string]interface{}), nil},
+	})
+	build.Prepare()
+	if _, err := build.Run(context.Background(), ui); err == nil {
+		t.Fatal("the build should fail")
+	}
+
+	if pp := build.PostProcessors[0][0].PostProcessor.(*MockPostProcessor); pp.PostProcessCalled {
+		t.Fatal("only the manifest post-processor should run for failed builds")
+	}
+	if !manifest.PostProcessCalled {
+		t.Fatal("the manifest post-processor should record the failed build")
+	}
+	metadata := manifest.PostProcessArtifact.State(BuildMetadataState).(map[string]interface{})
+	if metadata["status"] != BuildStatusFailed || metadata["error"] == "" {
+		t.Fatalf("unexpected build metadata %#v", metadata)
+	}
+	if files := manifest.PostProcessArtifact.Files(); len(files) != 0 {
+		t.Fatalf("a failed build has no files, got %q", files)
+	}
+}
+
+func TestBuild_Run_recordsCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	build := testBuild()
+	manifest := &MockPostProcessor{ArtifactId: "manifest"}
+	build.PostProcessors = [][]CoreBuildPostProcessor{{
+		{manifest, "manifest", "manifest", cty.Value{}, make(map[string]interface{}), nil},
+	}}
+	build.Prepare()
+	if _, err := build.Run(ctx, testUi()); err == nil {
+		t.Fatal("the build should be cancelled")
+	}
+
+	if !manifest.PostProcessCalled {
+		t.Fatal("the manifest post-processor should record the cancelled build")
+	}
+	metadata := manifest.PostProcessArtifact.State(BuildMetadataState).(map[string]interface{})
+	if metadata["status"] != BuildStatusCancelled {
+		t.Fatalf("unexpected build metadata %#v", metadata)
+	}
+}
+
 func TestBuild_RunBeforePrepare(t *testing.T) {
 	defer func() {
 		p := recover()
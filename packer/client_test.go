@@ -5,7 +5,10 @@ package packer
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -145,3 +148,49 @@ func TestClient_Stdin(t *testing.T) {
 		t.Fatal("process didn't exit cleanly")
 	}
 }
+
+func TestClient_exitError(t *testing.T) {
+	c := NewClient(&PluginClientConfig{
+		Cmd:             helperProcess("crash"),
+		StderrTailLines: 5,
+	})
+	defer c.Kill()
+
+	if _, err := c.Start(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err := c.exitError(io.ErrUnexpectedEOF)
+	var exitErr *PluginExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected a PluginExitError, got %#v", err)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected the RPC error to be wrapped, got %s", err)
+	}
+	if exitErr.ExitCode != 3 {
+		t.Fatalf("expected exit code 3, got %d", exitErr.ExitCode)
+	}
+
+	expectedTail := []string{"line 25", "line 26", "line 27", "line 28", "line 29"}
+	if !reflect.DeepEqual(expectedTail, exitErr.StderrTail) {
+		t.Fatalf("unexpected stderr tail: %#v", exitErr.StderrTail)
+	}
+	if !strings.Contains(err.Error(), "exit status 3") {
+		t.Fatalf("expected the exit state in the error, got %q", err)
+	}
+}
+
+func TestClient_exitError_killed(t *testing.T) {
+	c := NewClient(&PluginClientConfig{Cmd: helperProcess("mock")})
+
+	if _, err := c.Start(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	c.Kill()
+
+	err := c.exitError(io.ErrUnexpectedEOF)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("errors of killed plugins should not be decorated, got %#v", err)
+	}
+}
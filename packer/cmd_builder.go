@@ -31,7 +31,8 @@ func (b *cmdBuilder) Prepare(config ...interface{}) ([]string, []string, error)
 		b.checkExit(r, nil)
 	}()
 
-	return b.builder.Prepare(config...)
+	generatedVars, warnings, err := b.builder.Prepare(config...)
+	return generatedVars, warnings, b.client.exitError(err)
 }
 
 func (b *cmdBuilder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
@@ -40,7 +41,8 @@ func (b *cmdBuilder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Ho
 		b.checkExit(r, nil)
 	}()
 
-	return b.builder.Run(ctx, ui, hook)
+	artifact, err := b.builder.Run(ctx, ui, hook)
+	return artifact, b.client.exitError(err)
 }
 
 func (c *cmdBuilder) checkExit(p interface{}, cb func()) {
@@ -31,7 +31,7 @@ func (d *cmdDatasource) Configure(configs ...interface{}) error {
 		d.checkExit(r, nil)
 	}()
 
-	return d.d.Configure(configs...)
+	return d.client.exitError(d.d.Configure(configs...))
 }
 
 func (d *cmdDatasource) OutputSpec() hcldec.ObjectSpec {
@@ -49,7 +49,8 @@ func (d *cmdDatasource) Execute() (cty.Value, error) {
 		d.checkExit(r, nil)
 	}()
 
-	return d.d.Execute()
+	value, err := d.d.Execute()
+	return value, d.client.exitError(err)
 }
 
 func (d *cmdDatasource) checkExit(p interface{}, cb func()) {
@@ -21,7 +21,7 @@ func (c *cmdHook) Run(ctx context.Context, name string, ui packersdk.Ui, comm pa
 		c.checkExit(r, nil)
 	}()
 
-	return c.hook.Run(ctx, name, ui, comm, data)
+	return c.client.exitError(c.hook.Run(ctx, name, ui, comm, data))
 }
 
 func (c *cmdHook) checkExit(p interface{}, cb func()) {
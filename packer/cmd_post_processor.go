@@ -31,7 +31,7 @@ func (c *cmdPostProcessor) Configure(config ...interface{}) error {
 		c.checkExit(r, nil)
 	}()
 
-	return c.p.Configure(config...)
+	return c.client.exitError(c.p.Configure(config...))
 }
 
 func (c *cmdPostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, a packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
@@ -40,7 +40,8 @@ func (c *cmdPostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, a p
 		c.checkExit(r, nil)
 	}()
 
-	return c.p.PostProcess(ctx, ui, a)
+	artifact, keep, forceOverride, err := c.p.PostProcess(ctx, ui, a)
+	return artifact, keep, forceOverride, c.client.exitError(err)
 }
 
 func (c *cmdPostProcessor) checkExit(p interface{}, cb func()) {
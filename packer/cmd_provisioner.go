@@ -31,7 +31,7 @@ func (c *cmdProvisioner) Prepare(configs ...interface{}) error {
 		c.checkExit(r, nil)
 	}()
 
-	return c.p.Prepare(configs...)
+	return c.client.exitError(c.p.Prepare(configs...))
 }
 
 func (c *cmdProvisioner) Provision(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator, generatedData map[string]interface{}) error {
@@ -40,7 +40,7 @@ func (c *cmdProvisioner) Provision(ctx context.Context, ui packersdk.Ui, comm pa
 		c.checkExit(r, nil)
 	}()
 
-	return c.p.Provision(ctx, ui, comm, generatedData)
+	return c.client.exitError(c.p.Provision(ctx, ui, comm, generatedData))
 }
 
 func (c *cmdProvisioner) checkExit(p interface{}, cb func()) {
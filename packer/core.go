@@ -142,6 +142,10 @@ func NewCore(c *CoreConfig) *Core {
 func (c *Core) DetectPluginBinaries() hcl.Diagnostics {
 	var diags hcl.Diagnostics
 
+	if warning := c.components.PluginConfig.DevOverridesWarning(); warning != nil {
+		diags = diags.Append(warning)
+	}
+
 	err := c.components.PluginConfig.Discover()
 	if err != nil {
 		diags = diags.Append(&hcl.Diagnostic{
@@ -87,6 +87,11 @@ type ListInstallationsOptions struct {
 	// The directory in which to look for when installing plugins
 	PluginDirectory string
 
+	// SkipDescribe trusts the version and API version found in the name
+	// of the binaries instead of running them to check it. This is
+	// required to list binaries built for another platform.
+	SkipDescribe bool
+
 	BinaryInstallationOptions
 }
 
@@ -265,12 +270,6 @@ func (pr Requirement) ListInstallations(opts ListInstallationsOptions) (InstallL
 			}
 		}
 
-		describeInfo, err := GetPluginDescription(path)
-		if err != nil {
-			log.Printf("failed to call describe on %q: %s", path, err)
-			continue
-		}
-
 		// versionsStr now looks like v1.2.3_x5.1 or amazon_v1.2.3_x5.1
 		parts := strings.SplitN(versionsStr, "_", 2)
 		pluginVersionStr, protocolVersionStr := parts[0], parts[1]
@@ -296,26 +295,34 @@ func (pr Requirement) ListInstallations(opts ListInstallationsOptions) (InstallL
 			continue
 		}
 
-		descVersion, err := goversion.NewVersion(describeInfo.Version)
-		if err != nil {
-			log.Printf("malformed reported version string %q: %s, ignoring", describeInfo.Version, err)
-			continue
-		}
+		if !opts.SkipDescribe {
+			describeInfo, err := GetPluginDescription(path)
+			if err != nil {
+				log.Printf("failed to call describe on %q: %s", path, err)
+				continue
+			}
 
-		if ver.Compare(descVersion) != 0 {
-			log.Printf("plugin %q reported version %q while its name implies version %q, ignoring", path, describeInfo.Version, pluginVersionStr)
-			continue
-		}
+			descVersion, err := goversion.NewVersion(describeInfo.Version)
+			if err != nil {
+				log.Printf("malformed reported version string %q: %s, ignoring", describeInfo.Version, err)
+				continue
+			}
 
-		preRel := descVersion.Prerelease()
-		if preRel != "" && preRel != "dev" {
-			log.Printf("invalid plugin pre-release version %q, only development or release binaries are accepted", pluginVersionStr)
-		}
+			if ver.Compare(descVersion) != 0 {
+				log.Printf("plugin %q reported version %q while its name implies version %q, ignoring", path, describeInfo.Version, pluginVersionStr)
+				continue
+			}
 
-		// Check the API version matches between path and describe
-		if describeInfo.APIVersion != protocolVersionStr {
-			log.Printf("plugin %q reported API version %q while its name implies version %q, ignoring", path, describeInfo.APIVersion, protocolVersionStr)
-			continue
+			preRel := descVersion.Prerelease()
+			if preRel != "" && preRel != "dev" {
+				log.Printf("invalid plugin pre-release version %q, only development or release binaries are accepted", pluginVersionStr)
+			}
+
+			// Check the API version matches between path and describe
+			if describeInfo.APIVersion != protocolVersionStr {
+				log.Printf("plugin %q reported API version %q while its name implies version %q, ignoring", path, describeInfo.APIVersion, protocolVersionStr)
+				continue
+			}
 		}
 
 		// no constraint means always pass, this will happen for implicit
@@ -338,7 +345,7 @@ func (pr Requirement) ListInstallations(opts ListInstallationsOptions) (InstallL
 		res = append(res, &Installation{
 			BinaryPath: path,
 			Version:    pluginVersionStr,
-			APIVersion: describeInfo.APIVersion,
+			APIVersion: protocolVersionStr,
 		})
 	}
 
@@ -14,6 +14,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	pluginsdk "github.com/hashicorp/packer-plugin-sdk/plugin"
@@ -33,6 +34,17 @@ type PluginConfig struct {
 	// UseProtobuf is set if all the plugin candidates support protobuf, and
 	// the user has not forced usage of gob for serialisation.
 	UseProtobuf bool
+	// PluginResourceSampleInterval enables sampling the memory and CPU
+	// usage of plugin processes at this interval when non-zero.
+	PluginResourceSampleInterval time.Duration
+	// DevOverrides maps plugin sources to locally built binaries that are
+	// used in place of the installed plugins. See SetDevOverrides.
+	DevOverrides map[string]string
+	// PluginSandboxes are the sandboxes to start plugins in, keyed by
+	// plugin binary path. See SetPluginSandbox.
+	PluginSandboxes map[string]*PluginSandbox
+
+	sandboxLauncher string
 }
 
 // PACKERSPACE is used to represent the spaces that separate args for a command
@@ -111,6 +123,13 @@ func (c *PluginConfig) Discover() error {
 		pluginMap[pluginName] = install.BinaryPath
 	}
 
+	for source := range c.DevOverrides {
+		path, _ := c.DevOverride(source)
+		pluginName := strings.TrimPrefix(filepath.Base(source), "packer-plugin-")
+		log.Printf("[WARN] Using dev override %q for plugin %s", path, source)
+		pluginMap[pluginName] = path
+	}
+
 	for name, path := range pluginMap {
 		err := c.DiscoverMultiPlugin(name, path)
 		if err != nil {
@@ -133,7 +152,7 @@ var PackerUseProto = true
 // if the "packer-plugin-amazon" binary had an "ebs" builder one could use
 // the "amazon-ebs" builder.
 func (c *PluginConfig) DiscoverMultiPlugin(pluginName, pluginPath string) error {
-	desc, err := plugingetter.GetPluginDescription(pluginPath)
+	desc, err := c.describePlugin(pluginPath)
 	if err != nil {
 		return fmt.Errorf("failed to get plugin description from executable %q: %s", pluginPath, err)
 	}
@@ -176,7 +195,7 @@ func (c *PluginConfig) DiscoverMultiPlugin(pluginName, pluginPath string) error
 			}
 			args = append(args, builderName)
 
-			return c.Client(pluginPath, args...).Builder()
+			return c.pluginClient(pluginName, pluginPath, args...).Builder()
 		})
 		GlobalPluginsDetailsStore.SetBuilder(key, pluginDetails)
 	}
@@ -204,7 +223,7 @@ func (c *PluginConfig) DiscoverMultiPlugin(pluginName, pluginPath string) error
 			}
 			args = append(args, postProcessorName)
 
-			return c.Client(pluginPath, args...).PostProcessor()
+			return c.pluginClient(pluginName, pluginPath, args...).PostProcessor()
 		})
 		GlobalPluginsDetailsStore.SetPostProcessor(key, pluginDetails)
 	}
@@ -232,7 +251,7 @@ func (c *PluginConfig) DiscoverMultiPlugin(pluginName, pluginPath string) error
 			}
 			args = append(args, provisionerName)
 
-			return c.Client(pluginPath, args...).Provisioner()
+			return c.pluginClient(pluginName, pluginPath, args...).Provisioner()
 		})
 		GlobalPluginsDetailsStore.SetProvisioner(key, pluginDetails)
 
@@ -260,7 +279,7 @@ func (c *PluginConfig) DiscoverMultiPlugin(pluginName, pluginPath string) error
 			}
 			args = append(args, datasourceName)
 
-			return c.Client(pluginPath, args...).Datasource()
+			return c.pluginClient(pluginName, pluginPath, args...).Datasource()
 		})
 		GlobalPluginsDetailsStore.SetDataSource(key, pluginDetails)
 	}
@@ -280,6 +299,12 @@ func (c *PluginConfig) DiscoverMultiPlugin(pluginName, pluginPath string) error
 }
 
 func (c *PluginConfig) Client(path string, args ...string) *PluginClient {
+	return c.pluginClient("", path, args...)
+}
+
+// pluginClient returns a client for the plugin known as name, like Client.
+// Resource usage is recorded under that name, see PluginClientConfig.Name.
+func (c *PluginConfig) pluginClient(name, path string, args ...string) *PluginClient {
 	originalPath := path
 
 	// Check for special case using `packer plugin PLUGIN`
@@ -316,10 +341,12 @@ func (c *PluginConfig) Client(path string, args ...string) *PluginClient {
 		log.Printf("[INFO] Starting external plugin %s %s", path, strings.Join(args, " "))
 	}
 	var config PluginClientConfig
-	config.Cmd = exec.Command(path, args...)
+	config.Name = name
+	config.Cmd = c.command(path, args...)
 	config.Managed = true
 	config.MinPort = c.PluginMinPort
 	config.MaxPort = c.PluginMaxPort
+	config.ResourceSampleInterval = c.PluginResourceSampleInterval
 	return NewClient(&config)
 }
 
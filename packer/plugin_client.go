@@ -15,6 +15,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 
@@ -37,7 +38,11 @@ var managedClients = make([]*PluginClient, 0, 5)
 type PluginClient struct {
 	config      *PluginClientConfig
 	exited      bool
+	killed      atomic.Bool
+	exitState   *os.ProcessState
+	stderrTail  *lineRing
 	doneLogging chan struct{}
+	resources   PluginResourceUsage
 	l           sync.Mutex
 	address     net.Addr
 }
@@ -46,6 +51,11 @@ type PluginClient struct {
 // plugin client. After being used to initialize a plugin client,
 // that configuration must not be modified again.
 type PluginClientConfig struct {
+	// Name is the name the plugin was discovered as, like `amazon`. It is
+	// the name its resource usage is reported under in the metadata of the
+	// builds, and defaults to the name it logs under, see logPrefix.
+	Name string
+
 	// The unstarted subprocess for starting the plugin.
 	Cmd *exec.Cmd
 
@@ -68,6 +78,15 @@ type PluginClientConfig struct {
 	// If non-nil, then the stderr of the client will be written to here
 	// (as well as the log).
 	Stderr io.Writer
+
+	// StderrTailLines is the number of stderr lines kept in memory to be
+	// reported if the plugin exits unexpectedly. Defaults to 20.
+	StderrTailLines int
+
+	// ResourceSampleInterval is the interval at which the memory and CPU
+	// usage of the plugin process is sampled. Sampling is disabled if
+	// zero.
+	ResourceSampleInterval time.Duration
 }
 
 // This makes sure all the managed subprocesses are killed and properly
@@ -116,7 +135,14 @@ func NewClient(config *PluginClientConfig) (c *PluginClient) {
 		config.Stderr = io.Discard
 	}
 
-	c = &PluginClient{config: config}
+	if config.StderrTailLines == 0 {
+		config.StderrTailLines = 20
+	}
+
+	c = &PluginClient{
+		config:     config,
+		stderrTail: newLineRing(config.StderrTailLines),
+	}
 	if config.Managed {
 		managedClients = append(managedClients, c)
 	}
@@ -198,6 +224,7 @@ func (c *PluginClient) Kill() {
 		return
 	}
 
+	c.killed.Store(true)
 	_ = cmd.Process.Kill()
 
 	// Wait for the client to finish logging so we have a complete log
@@ -277,8 +304,13 @@ func (c *PluginClient) Start() (net.Addr, error) {
 		c.l.Lock()
 		defer c.l.Unlock()
 		c.exited = true
+		c.exitState = cmd.ProcessState
 	}()
 
+	if c.config.ResourceSampleInterval > 0 {
+		go c.sampleResources(cmd.Process, exitCh)
+	}
+
 	// Start goroutine that logs the stderr
 	go c.logStderr(stderr_r)
 
@@ -365,13 +397,19 @@ func (c *PluginClient) Start() (net.Addr, error) {
 	return c.address, err
 }
 
-func (c *PluginClient) logStderr(r io.Reader) {
+// logPrefix returns the name the plugin is known as in the logs.
+func (c *PluginClient) logPrefix() string {
 	logPrefix := filepath.Base(c.config.Cmd.Path)
 	if logPrefix == "packer" {
 		// we just called the normal packer binary with the plugin arg.
 		// grab the last arg from the list which will match the plugin name.
 		logPrefix = c.config.Cmd.Args[len(c.config.Cmd.Args)-1]
 	}
+	return logPrefix
+}
+
+func (c *PluginClient) logStderr(r io.Reader) {
+	logPrefix := c.logPrefix()
 
 	bufR := bufio.NewReader(r)
 	for {
@@ -380,6 +418,7 @@ func (c *PluginClient) logStderr(r io.Reader) {
 			_, _ = c.config.Stderr.Write([]byte(line))
 
 			line = strings.TrimRightFunc(line, unicode.IsSpace)
+			c.stderrTail.Add(line)
 
 			log.Printf("%s plugin: %s", logPrefix, line)
 		}
@@ -393,6 +432,40 @@ func (c *PluginClient) logStderr(r io.Reader) {
 	close(c.doneLogging)
 }
 
+// exitError decorates err with the exit state and the last lines of output
+// of the plugin if its process exited without being killed by us. Errors
+// that are not caused by the plugin going away are returned as is.
+func (c *PluginClient) exitError(err error) error {
+	if err == nil || c.doneLogging == nil {
+		return err
+	}
+
+	if isTransportError(err) {
+		// The connection may break before the process is reaped, give it
+		// a moment to be.
+		select {
+		case <-c.doneLogging:
+		case <-time.After(pluginExitGracePeriod):
+		}
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+	if !c.exited || c.killed.Load() || Killed || c.exitState == nil {
+		return err
+	}
+
+	exitErr := &PluginExitError{
+		Plugin:     c.logPrefix(),
+		ExitCode:   c.exitState.ExitCode(),
+		State:      c.exitState.String(),
+		StderrTail: c.stderrTail.Lines(),
+		Err:        err,
+	}
+	log.Printf("[ERR] %s", exitErr)
+	return exitErr
+}
+
 func (c *PluginClient) Client() (*packerrpc.Client, error) {
 	addr, err := c.Start()
 	if err != nil {
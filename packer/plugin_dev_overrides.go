@@ -0,0 +1,92 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package packer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+)
+
+// SetDevOverrides validates and sets the plugin development overrides read
+// from the Packer config file. Each source is mapped to a locally built
+// plugin binary, or to the directory it was built in. Relative paths are
+// resolved from baseDir.
+func (c *PluginConfig) SetDevOverrides(overrides map[string]string, baseDir string) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	c.DevOverrides = make(map[string]string, len(overrides))
+	for source, path := range overrides {
+		plugin, err := addrs.ParsePluginSourceString(source)
+		if err != nil {
+			return fmt.Errorf("dev_overrides: invalid plugin source %q: %s", source, err)
+		}
+		if path == "" {
+			return fmt.Errorf("dev_overrides: empty path for plugin %q", source)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		c.DevOverrides[plugin.String()] = path
+	}
+	return nil
+}
+
+// DevOverride returns the binary to run for the plugin identified by source,
+// if it was overridden for development. Version constraints don't apply to
+// overridden plugins.
+func (c *PluginConfig) DevOverride(source string) (string, bool) {
+	path, ok := c.DevOverrides[source]
+	if !ok {
+		return "", false
+	}
+
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		plugin, _ := addrs.ParsePluginSourceString(source)
+		binary := "packer-plugin-" + plugin.Name()
+		if runtime.GOOS == "windows" {
+			binary += ".exe"
+		}
+		path = filepath.Join(path, binary)
+	}
+	return path, true
+}
+
+// DevOverridesWarning returns a warning listing the plugin development
+// overrides in effect, or nil if there are none.
+func (c *PluginConfig) DevOverridesWarning() *hcl.Diagnostic {
+	if len(c.DevOverrides) == 0 {
+		return nil
+	}
+
+	sources := make([]string, 0, len(c.DevOverrides))
+	for source := range c.DevOverrides {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	detail := &strings.Builder{}
+	detail.WriteString("The following plugins are loaded from the dev_overrides of your Packer config file, " +
+		"regardless of the installed versions and of the version constraints of the template:\n\n")
+	for _, source := range sources {
+		path, _ := c.DevOverride(source)
+		fmt.Fprintf(detail, "* %s => %s\n", source, path)
+	}
+	detail.WriteString("\nThe behaviour of these plugins may differ from their released versions. " +
+		"Remove the overrides from your config file to use the installed plugins.")
+
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  "Plugin development overrides in effect",
+		Detail:   detail.String(),
+	}
+}
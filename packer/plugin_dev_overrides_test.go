@@ -0,0 +1,85 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package packer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPluginConfig_SetDevOverrides(t *testing.T) {
+	c := PluginConfig{}
+	err := c.SetDevOverrides(map[string]string{
+		"github.com/acme/foo": "bin/packer-plugin-foo",
+		"github.com/acme/bar": "/opt/packer-plugin-bar",
+	}, "/home/me")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[string]string{
+		"github.com/acme/foo": filepath.Join("/home/me", "bin/packer-plugin-foo"),
+		"github.com/acme/bar": "/opt/packer-plugin-bar",
+	}
+	for source, want := range expected {
+		got, ok := c.DevOverride(source)
+		if !ok {
+			t.Errorf("expected %s to be overridden", source)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: expected %q, got %q", source, want, got)
+		}
+	}
+
+	if _, ok := c.DevOverride("github.com/acme/baz"); ok {
+		t.Errorf("github.com/acme/baz should not be overridden")
+	}
+
+	if warning := c.DevOverridesWarning(); warning == nil || !strings.Contains(warning.Detail, "github.com/acme/foo") {
+		t.Errorf("expected a warning listing the overrides, got %#v", warning)
+	}
+}
+
+func TestPluginConfig_SetDevOverrides_invalid(t *testing.T) {
+	c := PluginConfig{}
+	if err := c.SetDevOverrides(map[string]string{"github.com/acme/foo": ""}, "/"); err == nil {
+		t.Errorf("expected an error for an empty path")
+	}
+	if err := c.SetDevOverrides(map[string]string{"not a source": "/bin/foo"}, "/"); err == nil {
+		t.Errorf("expected an error for an invalid source")
+	}
+}
+
+func TestPluginConfig_Discover_devOverride(t *testing.T) {
+	t.Setenv("PACKER_PLUGIN_PATH", t.TempDir())
+
+	// The override points to a build directory, in which the binary is
+	// found by name.
+	devDir := t.TempDir()
+	shPath := MustHaveCommand(t, "bash")
+	script := fmt.Sprintf("#!%s\n%s", shPath, strings.Join(
+		append([]string{"PKR_WANT_TEST_PLUGINS=1"}, helperCommand(t, "bird", "$@")...),
+		" "))
+	if err := os.WriteFile(filepath.Join(devDir, "packer-plugin-dev"), []byte(script), os.ModePerm); err != nil {
+		t.Fatalf("failed to create fake plugin binary: %v", err)
+	}
+
+	c := PluginConfig{}
+	if err := c.SetDevOverrides(map[string]string{"github.com/acme/dev": devDir}, ""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := c.Discover(); err != nil {
+		t.Fatalf("error discovering plugins; %s", err)
+	}
+
+	for _, builder := range []string{"dev-feather", "dev-guacamole"} {
+		if !c.Builders.Has(builder) {
+			t.Errorf("expected to find builder %q", builder)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package packer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/rpc"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pluginExitGracePeriod is how long we wait for a plugin process to be
+// reaped after an RPC call failed with a transport error, before giving up on
+// attaching crash diagnostics to that error.
+const pluginExitGracePeriod = 2 * time.Second
+
+// PluginExitError is returned in place of the RPC error when a plugin process
+// exited while Packer was still talking to it.
+type PluginExitError struct {
+	// Plugin is the name the plugin logs under.
+	Plugin string
+	// ExitCode is the exit code of the process, -1 if it was terminated by
+	// a signal.
+	ExitCode int
+	// State is the human readable exit state, e.g. "exit status 2" or
+	// "signal: killed".
+	State string
+	// StderrTail holds the last lines the plugin wrote to its stderr.
+	StderrTail []string
+	// Err is the error returned by the RPC call.
+	Err error
+}
+
+func (e *PluginExitError) Error() string {
+	msg := &strings.Builder{}
+	fmt.Fprintf(msg, "plugin %s exited unexpectedly (%s): %s", e.Plugin, e.State, e.Err)
+	if len(e.StderrTail) == 0 {
+		return msg.String()
+	}
+	fmt.Fprintf(msg, "\nLast %d lines of plugin output:", len(e.StderrTail))
+	for _, line := range e.StderrTail {
+		fmt.Fprintf(msg, "\n  %s", line)
+	}
+	return msg.String()
+}
+
+func (e *PluginExitError) Unwrap() error {
+	return e.Err
+}
+
+// isTransportError tells whether err was caused by the connection to the
+// plugin going away, as opposed to an error returned by the plugin itself.
+func isTransportError(err error) bool {
+	return errors.Is(err, rpc.ErrShutdown) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// lineRing keeps the last max lines written to it.
+type lineRing struct {
+	l     sync.Mutex
+	max   int
+	lines []string
+}
+
+func newLineRing(max int) *lineRing {
+	return &lineRing{max: max}
+}
+
+func (r *lineRing) Add(line string) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	if r.max <= 0 {
+		return
+	}
+	r.lines = append(r.lines, line)
+	if len(r.lines) > r.max {
+		r.lines = r.lines[len(r.lines)-r.max:]
+	}
+}
+
+func (r *lineRing) Lines() []string {
+	r.l.Lock()
+	defer r.l.Unlock()
+	return append([]string(nil), r.lines...)
+}
+
+// PluginResourceUsage is a summary of the resources sampled for a plugin
+// process while it was running, or for all the processes of a plugin used by
+// a build.
+type PluginResourceUsage struct {
+	// PeakRSS is the highest resident set size observed, in bytes.
+	PeakRSS uint64
+	// CPUTime is the user and system CPU time consumed by the process at
+	// the time of the last sample. For a build, it is the sum of the CPU
+	// time of the processes of the plugin.
+	CPUTime time.Duration
+	// Samples is the number of samples taken.
+	Samples int
+}
+
+func (u PluginResourceUsage) String() string {
+	return fmt.Sprintf("peak_rss=%dKiB cpu=%s samples=%d", u.PeakRSS/1024, u.CPUTime, u.Samples)
+}
+
+// add merges the usage of another process of the same plugin.
+func (u PluginResourceUsage) add(other PluginResourceUsage) PluginResourceUsage {
+	if other.PeakRSS > u.PeakRSS {
+		u.PeakRSS = other.PeakRSS
+	}
+	u.CPUTime += other.CPUTime
+	u.Samples += other.Samples
+	return u
+}
+
+// ResourceUsage returns the resources sampled for the plugin process, and
+// false if none were.
+func (c *PluginClient) ResourceUsage() (PluginResourceUsage, bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	return c.resources, c.resources.Samples > 0
+}
+
+// resourceName returns the name the plugin resource usage is reported
+// under, the name the plugin was discovered as when known.
+func (c *PluginClient) resourceName() string {
+	if c.config.Name != "" {
+		return c.config.Name
+	}
+	return c.logPrefix()
+}
+
+// sampleResources periodically records the memory and CPU usage of the
+// plugin process until exitCh is closed.
+func (c *PluginClient) sampleResources(process *os.Process, exitCh <-chan struct{}) {
+	ticker := time.NewTicker(c.config.ResourceSampleInterval)
+	defer ticker.Stop()
+
+	name := c.logPrefix()
+	for {
+		select {
+		case <-exitCh:
+			if usage, ok := c.ResourceUsage(); ok {
+				log.Printf("[INFO] %s plugin resource usage: %s", name, usage)
+			}
+			return
+		case <-ticker.C:
+		}
+
+		rss, cpu, err := processResources(process.Pid)
+		if err != nil {
+			log.Printf("[DEBUG] %s plugin: stopping resource sampling: %s", name, err)
+			return
+		}
+		c.l.Lock()
+		if rss > c.resources.PeakRSS {
+			c.resources.PeakRSS = rss
+		}
+		c.resources.CPUTime = cpu
+		c.resources.Samples++
+		c.l.Unlock()
+		log.Printf("[DEBUG] %s plugin: rss=%dKiB cpu=%s", name, rss/1024, cpu)
+	}
+}
+
+// pluginClientOf returns the client of the plugin process behind a
+// component of a build, unwrapping the provisioner wrappers of core.
+func pluginClientOf(component interface{}) *PluginClient {
+	for {
+		switch c := component.(type) {
+		case *cmdBuilder:
+			return c.client
+		case *cmdProvisioner:
+			return c.client
+		case *cmdPostProcessor:
+			return c.client
+		case *HookedProvisioner:
+			component = c.Provisioner
+		case *PausedProvisioner:
+			component = c.Provisioner
+		case *TimeoutProvisioner:
+			component = c.Provisioner
+		case *RetriedProvisioner:
+			component = c.Provisioner
+		case *ContinueOnErrorProvisioner:
+			component = c.Provisioner
+		case *DebuggedProvisioner:
+			component = c.Provisioner
+		case *SBOMInternalProvisioner:
+			component = c.Provisioner
+		case *OnlyIfProvisioner:
+			component = c.Provisioner
+		case *OutputsProvisioner:
+			component = c.Provisioner
+		case *LogFileProvisioner:
+			component = c.Provisioner
+		default:
+			return nil
+		}
+	}
+}
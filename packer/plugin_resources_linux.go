@@ -0,0 +1,77 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+//go:build linux
+// +build linux
+
+package packer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the USER_HZ value used by the kernel to report process
+// times in /proc, which is 100 on every supported architecture.
+const clockTicks = 100
+
+// processResources returns the resident set size in bytes and the total CPU
+// time of the process identified by pid.
+func processResources(pid int) (uint64, time.Duration, error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	// The command name is enclosed in parenthesis and may contain spaces,
+	// so the fields we want are counted from its end.
+	end := strings.LastIndexByte(string(stat), ')')
+	if end < 0 {
+		return 0, 0, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	// utime and stime are the 14th and 15th fields, 12th and 13th after
+	// the command name.
+	if len(fields) < 13 {
+		return 0, 0, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	cpu := time.Duration(utime+stime) * time.Second / clockTicks
+
+	status, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer status.Close()
+
+	var rss uint64
+	scanner := bufio.NewScanner(status)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "VmRSS:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return 0, 0, fmt.Errorf("malformed status for pid %d", pid)
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		rss = kb * 1024
+		break
+	}
+
+	return rss, cpu, scanner.Err()
+}
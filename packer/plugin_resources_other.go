@@ -0,0 +1,18 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+//go:build !linux
+// +build !linux
+
+package packer
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// processResources is only implemented on Linux.
+func processResources(pid int) (uint64, time.Duration, error) {
+	return 0, 0, fmt.Errorf("resource sampling is not supported on %s", runtime.GOOS)
+}
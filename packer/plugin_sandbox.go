@@ -0,0 +1,120 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package packer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	pluginsdk "github.com/hashicorp/packer-plugin-sdk/plugin"
+)
+
+// PluginSandboxEnvvar carries the sandbox policy and the plugin command line
+// to the Packer process acting as the sandbox launcher.
+const PluginSandboxEnvvar = "PACKER_PLUGIN_SANDBOX"
+
+// PluginSandbox describes what a plugin process is allowed to do when it is
+// started in a sandbox. Anything that is not declared is denied.
+type PluginSandbox struct {
+	// Network allows the plugin to reach the network. When false, the
+	// plugin runs in a network namespace of its own with no interfaces.
+	Network bool `json:"network"`
+	// WritablePaths are the files and directories, recursively, the
+	// plugin can write to. Reading is always allowed.
+	WritablePaths []string `json:"writable_paths"`
+}
+
+// sandboxedCommand is the message passed to the sandbox launcher.
+type sandboxedCommand struct {
+	PluginSandbox
+	Path string   `json:"path"`
+	Args []string `json:"args"`
+}
+
+// SetPluginSandbox makes every process started from the plugin binary at
+// pluginPath run in a sandbox restricted by sb. Relative writable paths are
+// resolved from the current working directory.
+func (c *PluginConfig) SetPluginSandbox(pluginPath string, sb *PluginSandbox) error {
+	if err := checkSandboxSupport(sb); err != nil {
+		return err
+	}
+
+	launcher, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the sandbox launcher: %s", err)
+	}
+
+	resolved := &PluginSandbox{Network: sb.Network}
+	for _, path := range sb.WritablePaths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("invalid writable path %q: %s", path, err)
+		}
+		resolved.WritablePaths = append(resolved.WritablePaths, abs)
+	}
+
+	if c.PluginSandboxes == nil {
+		c.PluginSandboxes = map[string]*PluginSandbox{}
+	}
+	c.PluginSandboxes[pluginPath] = resolved
+	c.sandboxLauncher = launcher
+	return nil
+}
+
+// command returns the command to run the plugin at path, through the sandbox
+// launcher if a sandbox was set for it.
+func (c *PluginConfig) command(path string, args ...string) *exec.Cmd {
+	sb, ok := c.PluginSandboxes[path]
+	if !ok {
+		return exec.Command(path, args...)
+	}
+
+	policy, _ := json.Marshal(sandboxedCommand{
+		PluginSandbox: *sb,
+		Path:          path,
+		Args:          args,
+	})
+	cmd := exec.Command(c.sandboxLauncher, args...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", PluginSandboxEnvvar, policy))
+	cmd.SysProcAttr = sandboxSysProcAttr(sb)
+	return cmd
+}
+
+// describePlugin returns the description of the plugin at path, obtained
+// from within its sandbox if it has one.
+func (c *PluginConfig) describePlugin(path string) (pluginsdk.SetDescription, error) {
+	desc := pluginsdk.SetDescription{}
+	out, err := c.command(path, "describe").Output()
+	if err != nil {
+		return desc, err
+	}
+	err = json.Unmarshal(out, &desc)
+	return desc, err
+}
+
+// InPluginSandboxLauncher tells whether this process was started to launch
+// a sandboxed plugin.
+func InPluginSandboxLauncher() bool {
+	return os.Getenv(PluginSandboxEnvvar) != ""
+}
+
+// RunPluginSandbox restricts the current process with the policy passed
+// through PluginSandboxEnvvar and replaces it with the plugin. It only
+// returns when the sandbox could not be set up, with the exit code to use.
+func RunPluginSandbox() int {
+	var sc sandboxedCommand
+	if err := json.Unmarshal([]byte(os.Getenv(PluginSandboxEnvvar)), &sc); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid plugin sandbox policy: %s\n", err)
+		return 1
+	}
+	os.Unsetenv(PluginSandboxEnvvar)
+
+	if err := execSandboxed(&sc); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start %s in a sandbox: %s\n", sc.Path, err)
+	}
+	return 1
+}
@@ -0,0 +1,180 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+//go:build linux
+// +build linux
+
+package packer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// landlockFileAccess are the rights that can be granted on a regular file,
+// as opposed to a directory hierarchy.
+const landlockFileAccess = unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE
+
+func landlockABI() (int, error) {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return 0, fmt.Errorf("landlock is not available: %s", errno)
+	}
+	return int(abi), nil
+}
+
+// landlockWriteAccess returns every write related right known to the given
+// Landlock ABI version, so that all of them are denied unless granted.
+func landlockWriteAccess(abi int) uint64 {
+	access := uint64(unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM)
+	if abi >= 2 {
+		access |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		access |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	return access
+}
+
+func checkSandboxSupport(sb *PluginSandbox) error {
+	if _, err := landlockABI(); err != nil {
+		return err
+	}
+	if sb.Network {
+		return nil
+	}
+	return checkNamespaceSupport()
+}
+
+// checkNamespaceSupport starts a process in the namespaces plugins without
+// network access run in, and kills it right away. Unprivileged user
+// namespaces can be disabled or restricted, which would otherwise only be
+// noticed when starting the plugin.
+func checkNamespaceSupport() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the sandbox launcher: %s", err)
+	}
+	cmd := exec.Command(exe)
+	cmd.SysProcAttr = sandboxSysProcAttr(&PluginSandbox{})
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("unprivileged user and network namespaces are required to deny plugins network access: %s", err)
+	}
+	_ = cmd.Process.Kill()
+	_ = cmd.Wait()
+	return nil
+}
+
+func sandboxSysProcAttr(sb *PluginSandbox) *syscall.SysProcAttr {
+	if sb.Network {
+		return nil
+	}
+	// The plugin talks to us through a unix socket, which keeps working
+	// from within an empty network namespace.
+	return &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET,
+		UidMappings: []syscall.SysProcIDMap{
+			{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1},
+		},
+		GidMappings: []syscall.SysProcIDMap{
+			{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1},
+		},
+	}
+}
+
+func execSandboxed(sc *sandboxedCommand) error {
+	// Landlock restricts the calling thread only, which is the one that
+	// is going to be replaced by the plugin.
+	runtime.LockOSThread()
+
+	writable := append([]string{
+		// Plugins create their RPC socket in the temporary directory.
+		pluginTempDir(),
+		os.DevNull,
+	}, sc.WritablePaths...)
+	if err := restrictWrites(writable); err != nil {
+		return err
+	}
+
+	return syscall.Exec(sc.Path, append([]string{sc.Path}, sc.Args...), os.Environ())
+}
+
+func pluginTempDir() string {
+	if dir := os.Getenv("PACKER_TMP_DIR"); dir != "" {
+		return dir
+	}
+	return os.TempDir()
+}
+
+// restrictWrites denies the current thread, and the processes it executes,
+// writing anywhere else than in paths.
+func restrictWrites(paths []string) error {
+	abi, err := landlockABI()
+	if err != nil {
+		return err
+	}
+	access := landlockWriteAccess(abi)
+
+	attr := unix.LandlockRulesetAttr{Access_fs: access}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to create landlock ruleset: %s", errno)
+	}
+	ruleset := int(fd)
+	defer unix.Close(ruleset)
+
+	for _, path := range paths {
+		if err := allowWrites(ruleset, path, access); err != nil {
+			return fmt.Errorf("writable path %q: %s", path, err)
+		}
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %s", err)
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, uintptr(ruleset), 0, 0); errno != 0 {
+		return fmt.Errorf("failed to enforce landlock ruleset: %s", errno)
+	}
+	return nil
+}
+
+func allowWrites(ruleset int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		return err
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= landlockFileAccess
+	}
+
+	rule := unix.LandlockPathBeneathAttr{
+		Allowed_access: access,
+		Parent_fd:      int32(fd),
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(ruleset), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+//go:build linux
+// +build linux
+
+package packer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRestrictWrites(t *testing.T) {
+	if err := checkSandboxSupport(&PluginSandbox{Network: true}); err != nil {
+		t.Skipf("sandboxing unavailable: %s", err)
+	}
+
+	allowed := t.TempDir()
+	denied := t.TempDir()
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestSandboxHelperProcess", "--", allowed, denied)
+	cmd.Env = append(os.Environ(), "GO_WANT_SANDBOX_HELPER_PROCESS=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("helper failed: %s: %s", err, out)
+	}
+
+	if _, err := os.Stat(filepath.Join(allowed, "file")); err != nil {
+		t.Errorf("expected write to allowed path to succeed: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(denied, "file")); err == nil {
+		t.Errorf("expected write to denied path to fail")
+	}
+	if !strings.Contains(string(out), "permission denied") {
+		t.Errorf("unexpected helper output: %s", out)
+	}
+}
+
+// This is not a real test. This is a helper process restricting itself
+// before trying to write to the paths it was given.
+func TestSandboxHelperProcess(*testing.T) {
+	if os.Getenv("GO_WANT_SANDBOX_HELPER_PROCESS") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	allowed, denied := args[1], args[2]
+
+	// Landlock only restricts the calling thread, so do everything from a
+	// goroutine locked to it. The thread is discarded when it returns.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runtime.LockOSThread()
+		if err := restrictWrites([]string{allowed}); err != nil {
+			fmt.Printf("restrict: %s\n", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(filepath.Join(allowed, "file"), nil, 0644); err != nil {
+			fmt.Printf("allowed: %s\n", err)
+			os.Exit(1)
+		}
+		err := os.WriteFile(filepath.Join(denied, "file"), nil, 0644)
+		fmt.Printf("denied: %v\n", err)
+	}()
+	<-done
+}
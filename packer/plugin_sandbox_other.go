@@ -0,0 +1,25 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+//go:build !linux
+// +build !linux
+
+package packer
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+func checkSandboxSupport(*PluginSandbox) error {
+	return fmt.Errorf("plugin sandboxing is not supported on %s", runtime.GOOS)
+}
+
+func sandboxSysProcAttr(*PluginSandbox) *syscall.SysProcAttr {
+	return nil
+}
+
+func execSandboxed(*sandboxedCommand) error {
+	return checkSandboxSupport(nil)
+}
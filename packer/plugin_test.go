@@ -111,6 +111,12 @@ func TestHelperProcess(*testing.T) {
 			os.Exit(1)
 		}
 		server.Serve()
+	case "crash":
+		fmt.Printf("%s|%s|tcp|:1234\n", pluginsdk.APIVersionMajor, pluginsdk.APIVersionMinor)
+		for i := 0; i < 30; i++ {
+			fmt.Fprintf(os.Stderr, "line %d\n", i)
+		}
+		os.Exit(3)
 	case "datasource":
 		server, err := pluginsdk.Server()
 		if err != nil {
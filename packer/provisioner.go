@@ -4,12 +4,16 @@
 package packer
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	hcpSbomProvisioner "github.com/hashicorp/packer/provisioner/hcp-sbom"
@@ -125,10 +129,12 @@ func (h *ProvisionHook) Run(ctx context.Context, name string, ui packersdk.Ui, c
 				"`communicator` config was set to \"none\". If you have any provisioners\n" +
 				"then a communicator is required. Please fix this to continue.")
 	}
+	// The data is cast once so that the values set by a provisioner, like
+	// its outputs, are seen by the next ones.
+	cast := CastDataToMap(data)
 	for _, p := range h.Provisioners {
 		ts := CheckpointReporter.AddSpan(p.TypeName, "provisioner", p.Config)
 
-		cast := CastDataToMap(data)
 		err := p.Provisioner.Provision(ctx, ui, comm, cast)
 
 		ts.End(err)
@@ -408,3 +414,141 @@ func (p *SBOMInternalProvisioner) Provision(
 
 	return nil
 }
+
+// OnlyIfProvisioner is a wrapper provisioner that only runs the wrapped
+// provisioner when OnlyIf, called with the generated data right before it
+// would run, returns true.
+type OnlyIfProvisioner struct {
+	Provisioner packersdk.Provisioner
+	// TypeName is the type of the wrapped provisioner, used in messages.
+	TypeName string
+	OnlyIf   func(generatedData map[string]interface{}) (bool, error)
+}
+
+func (p *OnlyIfProvisioner) ConfigSpec() hcldec.ObjectSpec { return p.Provisioner.ConfigSpec() }
+func (p *OnlyIfProvisioner) FlatConfig() interface{} {
+	if fc, ok := p.Provisioner.(interface{ FlatConfig() interface{} }); ok {
+		return fc.FlatConfig()
+	}
+	return nil
+}
+
+func (p *OnlyIfProvisioner) Prepare(raws ...interface{}) error {
+	return p.Provisioner.Prepare(raws...)
+}
+
+func (p *OnlyIfProvisioner) Provision(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator, generatedData map[string]interface{}) error {
+	run, err := p.OnlyIf(generatedData)
+	if err != nil {
+		return fmt.Errorf("Failed to evaluate only_if of the %s provisioner: %w", p.TypeName, err)
+	}
+	if !run {
+		ui.Say(fmt.Sprintf("Skipping the %s provisioner: its only_if condition is false", p.TypeName))
+		return nil
+	}
+	return p.Provisioner.Provision(ctx, ui, comm, generatedData)
+}
+
+// OutputsProvisioner is a wrapper provisioner that, after the successful
+// execution of the wrapped provisioner, downloads the remote files listed in
+// Outputs and sets their content in the generated data, so that later
+// provisioners and post-processors can reference them as build variables.
+type OutputsProvisioner struct {
+	Provisioner packersdk.Provisioner
+	// Outputs maps the name of the generated variables to the remote file
+	// they are read from.
+	Outputs map[string]string
+	// Values holds the outputs read during the last run.
+	Values map[string]string
+}
+
+func (p *OutputsProvisioner) ConfigSpec() hcldec.ObjectSpec { return p.Provisioner.ConfigSpec() }
+func (p *OutputsProvisioner) FlatConfig() interface{} {
+	if fc, ok := p.Provisioner.(interface{ FlatConfig() interface{} }); ok {
+		return fc.FlatConfig()
+	}
+	return nil
+}
+
+func (p *OutputsProvisioner) Prepare(raws ...interface{}) error {
+	return p.Provisioner.Prepare(raws...)
+}
+
+func (p *OutputsProvisioner) Provision(
+	ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator,
+	generatedData map[string]interface{},
+) error {
+	err := p.Provisioner.Provision(ctx, ui, comm, generatedData)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(p.Outputs))
+	for name := range p.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		var buf bytes.Buffer
+		if err := comm.Download(p.Outputs[name], &buf); err != nil {
+			return fmt.Errorf("failed to read output %q from %s: %s", name, p.Outputs[name], err)
+		}
+		values[name] = strings.TrimRight(buf.String(), "\r\n")
+		log.Printf("Provisioner output %s read from %s", name, p.Outputs[name])
+	}
+
+	for name, value := range values {
+		generatedData[name] = value
+	}
+	p.Values = values
+	return nil
+}
+
+// LogFileProvisioner is a wrapper provisioner that writes the output of the
+// wrapped provisioner to a local file, in addition to the build UI.
+type LogFileProvisioner struct {
+	Provisioner packersdk.Provisioner
+	// Path returns the path of the log file, from the generated data, right
+	// before the provisioner runs. Its parent directories are created if
+	// needed.
+	Path func(generatedData map[string]interface{}) (string, error)
+}
+
+func (p *LogFileProvisioner) ConfigSpec() hcldec.ObjectSpec { return p.Provisioner.ConfigSpec() }
+func (p *LogFileProvisioner) FlatConfig() interface{} {
+	if fc, ok := p.Provisioner.(interface{ FlatConfig() interface{} }); ok {
+		return fc.FlatConfig()
+	}
+	return nil
+}
+
+func (p *LogFileProvisioner) Prepare(raws ...interface{}) error {
+	return p.Provisioner.Prepare(raws...)
+}
+
+func (p *LogFileProvisioner) Provision(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator, generatedData map[string]interface{}) error {
+	path, err := p.Path(generatedData)
+	if err != nil {
+		return fmt.Errorf("Failed to evaluate log_file: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create the directory of log file %s: %s", path, err)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create log file: %s", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("[WARN] Failed to close log file %s: %s", path, err)
+		}
+	}()
+
+	logUi := &LogFileUi{Ui: ui, Writer: f}
+	defer logUi.Flush()
+	return p.Provisioner.Provision(ctx, logUi, logUi.Communicator(comm), generatedData)
+}
@@ -7,6 +7,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -91,6 +95,65 @@ func TestProvisionHook_cancel(t *testing.T) {
 	}
 }
 
+func TestProvisionHook_onlyIf(t *testing.T) {
+	pA := &packersdk.MockProvisioner{}
+	pB := &packersdk.MockProvisioner{}
+
+	var gotData map[string]interface{}
+	hook := &ProvisionHook{
+		Provisioners: []*HookedProvisioner{
+			{
+				Provisioner: &OnlyIfProvisioner{
+					Provisioner: pA,
+					OnlyIf: func(data map[string]interface{}) (bool, error) {
+						gotData = data
+						return data["ID"] == "linux", nil
+					},
+				},
+			},
+			{pB, nil, ""},
+		},
+	}
+
+	data := map[string]interface{}{"ID": "windows"}
+	if err := hook.Run(context.Background(), "foo", testUi(), new(packersdk.MockCommunicator), data); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if gotData["ID"] != "windows" {
+		t.Errorf("only_if should get the generated data, got %#v", gotData)
+	}
+	if pA.ProvCalled {
+		t.Error("provision should not be called on pA")
+	}
+	if !pB.ProvCalled {
+		t.Error("provision should be called on pB")
+	}
+}
+
+func TestProvisionHook_onlyIfError(t *testing.T) {
+	p := &packersdk.MockProvisioner{}
+
+	hook := &ProvisionHook{
+		Provisioners: []*HookedProvisioner{
+			{
+				Provisioner: &OnlyIfProvisioner{
+					Provisioner: p,
+					OnlyIf: func(map[string]interface{}) (bool, error) {
+						return false, errors.New("unknown value")
+					},
+				},
+			},
+		},
+	}
+
+	if err := hook.Run(context.Background(), "foo", testUi(), new(packersdk.MockCommunicator), nil); err == nil {
+		t.Fatal("should error")
+	}
+	if p.ProvCalled {
+		t.Error("provision should not be called")
+	}
+}
+
 // TODO(mitchellh): Test that they're run in the proper order
 
 func TestPausedProvisioner_impl(t *testing.T) {
@@ -498,3 +561,126 @@ func TestProvisionHook_continueOnErrorRunsRemaining(t *testing.T) {
 		t.Fatal("subsequent provisioner should run after a swallowed failure")
 	}
 }
+
+func TestOutputsProvisionerProvision(t *testing.T) {
+	mock := new(packersdk.MockProvisioner)
+	comm := &packersdk.MockCommunicator{DownloadData: "5.15.0-generic\n"}
+
+	prov := &OutputsProvisioner{
+		Provisioner: mock,
+		Outputs:     map[string]string{"kernel": "/tmp/kernel_version"},
+	}
+
+	// The next provisioner of the hook sees the output in its data
+	var seen map[string]interface{}
+	hook := &ProvisionHook{
+		Provisioners: []*HookedProvisioner{
+			{Provisioner: prov},
+			{
+				Provisioner: &OnlyIfProvisioner{
+					Provisioner: new(packersdk.MockProvisioner),
+					OnlyIf: func(generatedData map[string]interface{}) (bool, error) {
+						seen = generatedData
+						return true, nil
+					},
+				},
+			},
+		},
+	}
+
+	err := hook.Run(context.Background(), "foo", testUi(), comm, map[interface{}]interface{}{"ID": "i-123"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !mock.ProvCalled {
+		t.Fatal("prov should be called")
+	}
+	if comm.DownloadPath != "/tmp/kernel_version" {
+		t.Fatalf("unexpected download path %q", comm.DownloadPath)
+	}
+	if seen["kernel"] != "5.15.0-generic" {
+		t.Fatalf("expected the output to be in the generated data, got %#v", seen)
+	}
+	if seen["ID"] != "i-123" {
+		t.Fatalf("expected the builder data to be kept, got %#v", seen)
+	}
+	if prov.Values["kernel"] != "5.15.0-generic" {
+		t.Fatalf("unexpected values %#v", prov.Values)
+	}
+}
+
+func TestOutputsProvisionerProvision_failure(t *testing.T) {
+	mock := &packersdk.MockProvisioner{
+		ProvFunc: func(ctx context.Context) error {
+			return errors.New("failed")
+		},
+	}
+	comm := new(packersdk.MockCommunicator)
+
+	prov := &OutputsProvisioner{
+		Provisioner: mock,
+		Outputs:     map[string]string{"kernel": "/tmp/kernel_version"},
+	}
+
+	generatedData := map[string]interface{}{}
+	if err := prov.Provision(context.Background(), testUi(), comm, generatedData); err == nil {
+		t.Fatal("should have errored")
+	}
+	if comm.DownloadCalled {
+		t.Fatal("outputs should not be read when the provisioner fails")
+	}
+	if _, ok := generatedData["kernel"]; ok {
+		t.Fatal("output should not be set when the provisioner fails")
+	}
+}
+
+// commandProvisioner runs a command and relays its output to the UI, like
+// the shell provisioners.
+type commandProvisioner struct {
+	packersdk.MockProvisioner
+}
+
+func (p *commandProvisioner) Provision(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator, _ map[string]interface{}) error {
+	ui.Say("Provisioning with a command")
+	cmd := &packersdk.RemoteCmd{Command: "run"}
+	return cmd.RunWithUi(ctx, comm, ui)
+}
+
+func TestLogFileProvisionerProvision(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "provisioner.log")
+	prov := &LogFileProvisioner{
+		Provisioner: new(commandProvisioner),
+		Path: func(map[string]interface{}) (string, error) {
+			return path, nil
+		},
+	}
+	comm := &packersdk.MockCommunicator{
+		StartStdout: "hello\nno newline",
+		StartStderr: "oops\n",
+	}
+	if err := prov.Provision(context.Background(), testUi(), comm, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		// Drop the timestamp.
+		_, line, _ = strings.Cut(line, " ")
+		lines = append(lines, line)
+	}
+	slices.Sort(lines)
+	// The command output relayed to the UI is only logged once.
+	expected := []string{
+		"[stderr] oops",
+		"[stdout] hello",
+		"[stdout] no newline",
+		"[ui] Provisioning with a command",
+	}
+	if !slices.Equal(lines, expected) {
+		t.Fatalf("unexpected log file content:\n%s", content)
+	}
+}
@@ -5,6 +5,7 @@ package packer
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -12,6 +13,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 	"unicode"
@@ -308,6 +310,227 @@ func (u *TimestampedUi) timestampLine(string string) string {
 	return fmt.Sprintf("%v: %v", time.Now().Format(time.RFC3339), string)
 }
 
+// LogFileUi is a UI that wraps another UI implementation and also writes
+// every message to Writer, one line per output line, prefixed with a
+// timestamp and a stream marker. Say and Message output is marked as ui and
+// Error output as ui-error. The output of the commands run through the
+// communicator returned by Communicator is marked as stdout and stderr
+// instead, and is not logged a second time when the provisioner relays it
+// to the UI.
+type LogFileUi struct {
+	Ui     packersdk.Ui
+	Writer io.Writer
+
+	l sync.Mutex
+	// relayed counts the lines of command output that were logged and are
+	// expected to be relayed to the UI, by stream and line.
+	relayed map[string]int
+	// streams are the writers of command output created by Communicator.
+	streams []*logFileStream
+}
+
+var _ packersdk.Ui = new(LogFileUi)
+
+func (u *LogFileUi) Ask(query string) (string, error) {
+	return u.Ui.Ask(query)
+}
+
+func (u *LogFileUi) Askf(query string, args ...any) (string, error) {
+	return u.Ask(fmt.Sprintf(query, args...))
+}
+
+func (u *LogFileUi) Say(message string) {
+	if !u.takeRelayed("stdout", message) {
+		u.log("ui", message)
+	}
+	u.Ui.Say(message)
+}
+
+func (u *LogFileUi) Sayf(message string, args ...any) {
+	u.Say(fmt.Sprintf(message, args...))
+}
+
+func (u *LogFileUi) Message(message string) {
+	if !u.takeRelayed("stdout", message) {
+		u.log("ui", message)
+	}
+	u.Ui.Message(message)
+}
+
+func (u *LogFileUi) Error(message string) {
+	if !u.takeRelayed("stderr", message) {
+		u.log("ui-error", message)
+	}
+	u.Ui.Error(message)
+}
+
+func (u *LogFileUi) Errorf(message string, args ...any) {
+	u.Error(fmt.Sprintf(message, args...))
+}
+
+func (u *LogFileUi) Machine(t string, args ...string) {
+	u.Ui.Machine(t, args...)
+}
+
+func (u *LogFileUi) TrackProgress(src string, currentSize, totalSize int64, stream io.ReadCloser) io.ReadCloser {
+	return u.Ui.TrackProgress(src, currentSize, totalSize, stream)
+}
+
+// Communicator wraps comm so that the output of the commands it runs is
+// written to the log file. It returns nil when comm is nil.
+func (u *LogFileUi) Communicator(comm packersdk.Communicator) packersdk.Communicator {
+	if comm == nil {
+		return nil
+	}
+	return &logFileCommunicator{Communicator: comm, ui: u}
+}
+
+// Flush writes the incomplete last lines of command output, once the
+// commands exited.
+func (u *LogFileUi) Flush() {
+	u.l.Lock()
+	streams := u.streams
+	u.streams = nil
+	u.l.Unlock()
+	for _, s := range streams {
+		s.flush()
+	}
+}
+
+func (u *LogFileUi) log(stream, message string) {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	message = strings.TrimRight(scrubSecrets(message), "\r\n")
+
+	var buf bytes.Buffer
+	for _, line := range strings.Split(message, "\n") {
+		fmt.Fprintf(&buf, "%s [%s] %s\n", now, stream, strings.TrimSuffix(line, "\r"))
+	}
+
+	u.l.Lock()
+	defer u.l.Unlock()
+	if _, err := u.Writer.Write(buf.Bytes()); err != nil {
+		log.Printf("[WARN] Failed to write to the log file: %s", err)
+	}
+}
+
+// logCommandOutput logs a line of command output, and remembers it so that
+// it is not logged again when relayed to the UI.
+func (u *LogFileUi) logCommandOutput(stream, line string) {
+	u.log(stream, line)
+
+	u.l.Lock()
+	defer u.l.Unlock()
+	if u.relayed == nil {
+		u.relayed = map[string]int{}
+	}
+	u.relayed[stream+"\x00"+relayedLine(line)]++
+}
+
+// takeRelayed tells whether message is a line of command output, that was
+// already logged or that is logged now when it is the last line of output,
+// without a newline.
+func (u *LogFileUi) takeRelayed(stream, message string) bool {
+	u.l.Lock()
+	key := stream + "\x00" + message
+	if u.relayed[key] > 0 {
+		u.relayed[key]--
+		u.l.Unlock()
+		return true
+	}
+	streams := u.streams
+	u.l.Unlock()
+
+	for _, s := range streams {
+		if s.name == stream && s.takeLast(message) {
+			u.log(stream, message)
+			return true
+		}
+	}
+	return false
+}
+
+// relayedLine returns line as relayed to the UI by RemoteCmd.RunWithUi:
+// without trailing spaces, and only the text after the last carriage
+// return.
+func relayedLine(line string) string {
+	line = strings.TrimRightFunc(line, unicode.IsSpace)
+	if idx := strings.LastIndex(line, "\r"); idx > -1 {
+		line = line[idx+1:]
+	}
+	return line
+}
+
+// logFileCommunicator writes the output of the commands it starts to the
+// log file of ui.
+type logFileCommunicator struct {
+	packersdk.Communicator
+	ui *LogFileUi
+}
+
+func (c *logFileCommunicator) Start(ctx context.Context, cmd *packersdk.RemoteCmd) error {
+	// The output is logged before being passed on, so that it is known to
+	// be command output when the provisioner relays it to the UI.
+	cmd.Stdout = c.tee("stdout", cmd.Stdout)
+	cmd.Stderr = c.tee("stderr", cmd.Stderr)
+	return c.Communicator.Start(ctx, cmd)
+}
+
+func (c *logFileCommunicator) tee(name string, w io.Writer) io.Writer {
+	stream := &logFileStream{ui: c.ui, name: name}
+	c.ui.l.Lock()
+	c.ui.streams = append(c.ui.streams, stream)
+	c.ui.l.Unlock()
+	if w == nil {
+		return stream
+	}
+	return io.MultiWriter(stream, w)
+}
+
+// logFileStream logs the complete lines written to it.
+type logFileStream struct {
+	ui   *LogFileUi
+	name string
+
+	l   sync.Mutex
+	buf []byte
+}
+
+func (s *logFileStream) Write(p []byte) (int, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.buf = append(s.buf, p...)
+	for {
+		i := bytes.IndexByte(s.buf, '\n')
+		if i < 0 {
+			break
+		}
+		s.ui.logCommandOutput(s.name, string(s.buf[:i]))
+		s.buf = s.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// takeLast drops the incomplete last line written, when it is relayed as
+// line.
+func (s *logFileStream) takeLast(line string) bool {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if len(s.buf) == 0 || relayedLine(string(s.buf)) != line {
+		return false
+	}
+	s.buf = nil
+	return true
+}
+
+func (s *logFileStream) flush() {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if len(s.buf) > 0 {
+		s.ui.logCommandOutput(s.name, string(s.buf))
+		s.buf = nil
+	}
+}
+
 func scrubSecrets(message string) string {
 	return packersdk.LogSecretFilter.FilterString(message)
 }
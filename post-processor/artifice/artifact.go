@@ -6,7 +6,6 @@ package artifice
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 )
 
@@ -14,21 +13,20 @@ const BuilderId = "packer.post-processor.artifice"
 
 type Artifact struct {
 	files []string
+
+	// The ID and state kept from the input artifact.
+	id    string
+	state map[string]interface{}
 }
 
+// NewArtifact returns an artifact made of files, which must exist.
 func NewArtifact(files []string) (*Artifact, error) {
 	artifact := &Artifact{}
 	for _, f := range files {
-		globfiles, err := filepath.Glob(f)
-		if err != nil {
+		if _, err := os.Stat(f); err != nil {
 			return nil, err
 		}
-		for _, gf := range globfiles {
-			if _, err := os.Stat(gf); err != nil {
-				return nil, err
-			}
-			artifact.files = append(artifact.files, gf)
-		}
+		artifact.files = append(artifact.files, f)
 	}
 	return artifact, nil
 }
@@ -42,7 +40,7 @@ func (a *Artifact) Files() []string {
 }
 
 func (a *Artifact) Id() string {
-	return ""
+	return a.id
 }
 
 func (a *Artifact) String() string {
@@ -51,7 +49,7 @@ func (a *Artifact) String() string {
 }
 
 func (a *Artifact) State(name string) interface{} {
-	return nil
+	return a.state[name]
 }
 
 func (a *Artifact) Destroy() error {
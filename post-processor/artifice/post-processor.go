@@ -10,9 +10,11 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/common"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 )
@@ -26,8 +28,17 @@ import (
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
+	// The files of the artifact, or glob patterns resolved when the
+	// post-processor runs. `**` matches any number of directories.
 	Files []string `mapstructure:"files"`
 	Keep  bool     `mapstructure:"keep_input_artifact"`
+	// Fail when a pattern of `files` matches no file. By default these
+	// patterns are skipped.
+	RequireExists bool `mapstructure:"require_exists"`
+	// Keep the ID, `generated_data` and HCP Packer registry metadata of the
+	// input artifact, for the post-processors using them after this one,
+	// like `manifest`.
+	KeepState bool `mapstructure:"keep_state"`
 
 	ctx interpolate.Context
 }
@@ -55,16 +66,75 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return fmt.Errorf("No files specified in artifice configuration")
 	}
 
+	errs := new(packersdk.MultiError)
+	for _, pattern := range p.config.Files {
+		if !doublestar.ValidatePathPattern(pattern) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Invalid files pattern %q", pattern))
+		}
+	}
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
 	return nil
 }
 
+// keptState lists the state of the input artifact kept with keep_state. The
+// state of an artifact can't be listed, and the input artifact is closed
+// once the post-processor returns when running as a plugin, so only these
+// values are copied.
+var keptState = []string{"generated_data", registryimage.ArtifactStateURI}
+
 func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
 	if len(artifact.Files()) > 0 {
 		ui.Say(fmt.Sprintf("Discarding files from artifact: %s", strings.Join(artifact.Files(), ", ")))
 	}
 
-	artifact, err := NewArtifact(p.config.Files)
-	ui.Say(fmt.Sprintf("Using these artifact files: %s", strings.Join(artifact.Files(), ", ")))
+	files, err := p.resolveFiles(ui)
+	if err != nil {
+		return nil, false, false, err
+	}
+	newArtifact, err := NewArtifact(files)
+	if err != nil {
+		return nil, false, false, err
+	}
+	ui.Say(fmt.Sprintf("Using these artifact files: %s", strings.Join(newArtifact.Files(), ", ")))
+
+	if p.config.KeepState {
+		newArtifact.id = artifact.Id()
+		newArtifact.state = map[string]interface{}{}
+		for _, name := range keptState {
+			if value := artifact.State(name); value != nil {
+				newArtifact.state[name] = value
+			}
+		}
+	}
+
+	return newArtifact, true, false, nil
+}
 
-	return artifact, true, false, err
+// resolveFiles returns the files matching the patterns of the
+// configuration, in order.
+func (p *PostProcessor) resolveFiles(ui packersdk.Ui) ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+	for _, pattern := range p.config.Files {
+		matches, err := doublestar.FilepathGlob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to resolve %q: %s", pattern, err)
+		}
+		if len(matches) == 0 {
+			if p.config.RequireExists {
+				return nil, fmt.Errorf("No file matches %q", pattern)
+			}
+			ui.Message(fmt.Sprintf("No file matches %q, skipping it", pattern))
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
+	}
+	return files, nil
 }
@@ -20,6 +20,8 @@ type FlatConfig struct {
 	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
 	Files               []string          `mapstructure:"files" cty:"files" hcl:"files"`
 	Keep                *bool             `mapstructure:"keep_input_artifact" cty:"keep_input_artifact" hcl:"keep_input_artifact"`
+	RequireExists       *bool             `mapstructure:"require_exists" cty:"require_exists" hcl:"require_exists"`
+	KeepState           *bool             `mapstructure:"keep_state" cty:"keep_state" hcl:"keep_state"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -44,6 +46,8 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
 		"files":                      &hcldec.AttrSpec{Name: "files", Type: cty.List(cty.String), Required: false},
 		"keep_input_artifact":        &hcldec.AttrSpec{Name: "keep_input_artifact", Type: cty.Bool, Required: false},
+		"require_exists":             &hcldec.AttrSpec{Name: "require_exists", Type: cty.Bool, Required: false},
+		"keep_state":                 &hcldec.AttrSpec{Name: "keep_state", Type: cty.Bool, Required: false},
 	}
 	return s
 }
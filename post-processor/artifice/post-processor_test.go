@@ -0,0 +1,123 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package artifice
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func testArtifice(t *testing.T, config map[string]interface{}, source packersdk.Artifact) (packersdk.Artifact, error) {
+	var p PostProcessor
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	artifact, _, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source)
+	return artifact, err
+}
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestArtificeGlobs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "image.raw", "out/a.qcow2", "out/nested/b.qcow2", "out/nested/notes.txt")
+
+	artifact, err := testArtifice(t, map[string]interface{}{
+		"files": []string{
+			filepath.Join(dir, "image.raw"),
+			filepath.Join(dir, "out", "**", "*.qcow2"),
+			filepath.Join(dir, "*.raw"),
+			filepath.Join(dir, "*.missing"),
+		},
+	}, &packersdk.MockArtifact{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "image.raw"),
+		filepath.Join(dir, "out", "a.qcow2"),
+		filepath.Join(dir, "out", "nested", "b.qcow2"),
+	}
+	if !reflect.DeepEqual(artifact.Files(), expected) {
+		t.Fatalf("expected %v, got %v", expected, artifact.Files())
+	}
+	if artifact.Id() != "" || artifact.State("generated_data") != nil {
+		t.Errorf("expected the input artifact state to be dropped by default")
+	}
+}
+
+func TestArtificeRequireExists(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "image.raw")
+
+	_, err := testArtifice(t, map[string]interface{}{
+		"files":          []string{filepath.Join(dir, "image.raw"), filepath.Join(dir, "*.qcow2")},
+		"require_exists": true,
+	}, &packersdk.MockArtifact{})
+	if err == nil {
+		t.Fatal("expected a pattern matching no file to fail")
+	}
+}
+
+func TestArtificeKeepState(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "image.raw")
+	generatedData := map[interface{}]interface{}{"ImageID": "1234"}
+	source := &packersdk.MockArtifact{
+		IdValue: "ami-1234",
+		StateValues: map[string]interface{}{
+			"generated_data": generatedData,
+			"other":          "value",
+		},
+	}
+
+	artifact, err := testArtifice(t, map[string]interface{}{
+		"files":      []string{filepath.Join(dir, "image.raw")},
+		"keep_state": true,
+	}, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if artifact.Id() != "ami-1234" {
+		t.Errorf("expected the ID of the input artifact, got %q", artifact.Id())
+	}
+	if !reflect.DeepEqual(artifact.State("generated_data"), generatedData) {
+		t.Errorf("expected the generated data of the input artifact, got %v", artifact.State("generated_data"))
+	}
+	if artifact.State("other") != nil {
+		t.Errorf("expected only the kept state, got %v", artifact.State("other"))
+	}
+	if artifact.BuilderId() != BuilderId {
+		t.Errorf("unexpected builder ID %s", artifact.BuilderId())
+	}
+}
+
+func TestArtificeConfigure(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"no files":    {},
+		"bad pattern": {"files": []string{"out/[.raw"}},
+	}
+	for name, config := range cases {
+		var p PostProcessor
+		if err := p.Configure(config); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
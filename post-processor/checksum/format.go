@@ -0,0 +1,116 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package checksum
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// FormatGNU writes `<checksum>  <path>` lines, like `sha256sum`.
+	FormatGNU = "gnu"
+	// FormatBSD writes `<TYPE> (<path>) = <checksum>` lines, like
+	// `sha256sum --tag`.
+	FormatBSD = "bsd"
+	// FormatJSON writes a ChecksumFile.
+	FormatJSON = "json"
+)
+
+// ChecksumFile is the content of checksum files in the json format.
+type ChecksumFile struct {
+	Files []ChecksumEntry `json:"files"`
+}
+
+type ChecksumEntry struct {
+	// The path of the file, relative to the checksum file.
+	Path string `json:"path"`
+	// The hex encoded checksums of the file, by checksum type.
+	Checksums map[string]string `json:"checksums"`
+}
+
+// appendChecksums appends `<checksum>\t<name>` lines to checksumFile, the
+// format used when none is configured.
+func appendChecksums(checksumFile string, files []string, sums []map[string]string, checksumTypes []string) error {
+	fw, err := os.OpenFile(checksumFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, os.FileMode(0644))
+	if err != nil {
+		return fmt.Errorf("unable to create file %s: %s", checksumFile, err.Error())
+	}
+	defer fw.Close()
+
+	for _, ct := range checksumTypes {
+		for i, art := range files {
+			if _, err := fmt.Fprintf(fw, "%s\t%s\n", sums[i][ct], filepath.Base(art)); err != nil {
+				return fmt.Errorf("unable to write file %s: %s", checksumFile, err.Error())
+			}
+		}
+	}
+	return fw.Close()
+}
+
+// writeChecksums replaces checksumFile with the checksums of files in format.
+func writeChecksums(checksumFile string, format string, files []string, sums []map[string]string, checksumTypes []string) error {
+	var buf bytes.Buffer
+	switch format {
+	case FormatGNU:
+		if len(checksumTypes) > 1 {
+			return fmt.Errorf("the %s format can only hold one checksum type, %s would hold %s: "+
+				"use {{.ChecksumType}} in output", format, checksumFile, strings.Join(checksumTypes, ", "))
+		}
+		for i, art := range files {
+			fmt.Fprintf(&buf, "%s  %s\n", sums[i][checksumTypes[0]], relativePath(checksumFile, art))
+		}
+	case FormatBSD:
+		for i, art := range files {
+			for _, ct := range checksumTypes {
+				fmt.Fprintf(&buf, "%s (%s) = %s\n", strings.ToUpper(ct), relativePath(checksumFile, art), sums[i][ct])
+			}
+		}
+	case FormatJSON:
+		content := ChecksumFile{Files: []ChecksumEntry{}}
+		for i, art := range files {
+			entry := ChecksumEntry{
+				Path:      relativePath(checksumFile, art),
+				Checksums: map[string]string{},
+			}
+			for _, ct := range checksumTypes {
+				entry.Checksums[ct] = sums[i][ct]
+			}
+			content.Files = append(content.Files, entry)
+		}
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(content); err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(checksumFile, buf.Bytes(), os.FileMode(0644)); err != nil {
+		return fmt.Errorf("unable to write file %s: %s", checksumFile, err.Error())
+	}
+	return nil
+}
+
+// relativePath returns the path of file relative to the directory of
+// checksumFile, with forward slashes, so checksum tools can verify it from
+// there.
+func relativePath(checksumFile string, file string) string {
+	dir, err := filepath.Abs(filepath.Dir(checksumFile))
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -1,7 +1,7 @@
 // Copyright IBM Corp. 2024, 2025
 // SPDX-License-Identifier: BUSL-1.1
 
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,SignConfig
 
 package checksum
 
@@ -10,18 +10,25 @@ import (
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha3"
 	"crypto/sha512"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/common"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"lukechampine.com/blake3"
 )
 
 type Config struct {
@@ -29,7 +36,18 @@ type Config struct {
 
 	ChecksumTypes []string `mapstructure:"checksum_types"`
 	OutputPath    string   `mapstructure:"output"`
-	ctx           interpolate.Context
+	// The format of the checksum files: `gnu`, like `sha256sum`, `bsd`, like
+	// `sha256sum --tag`, or `json`. Files in these formats are rewritten on
+	// each run and list every artifact file relative to the checksum file.
+	// When `output` does not depend on `{{.ChecksumType}}`, a single `bsd`
+	// or `json` file holds all the checksum types. Defaults to appending
+	// `<checksum>\t<name>` lines to the checksum files.
+	Format string `mapstructure:"format"`
+	// Sign the checksum files, writing the detached signature of each of
+	// them to `<checksum file>.sig`.
+	Sign *SignConfig `mapstructure:"sign"`
+
+	ctx interpolate.Context
 }
 
 type PostProcessor struct {
@@ -51,6 +69,10 @@ func getHash(t string) hash.Hash {
 		h = sha512.New384()
 	case "sha512":
 		h = sha512.New()
+	case "sha3-256":
+		h = sha3.New256()
+	case "blake3":
+		h = blake3.New(32, nil)
 	}
 	return h
 }
@@ -82,6 +104,19 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		}
 	}
 
+	switch p.config.Format {
+	case "", FormatGNU, FormatBSD, FormatJSON:
+	default:
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("Unrecognized format %q, expected %q, %q or %q", p.config.Format, FormatGNU, FormatBSD, FormatJSON))
+	}
+
+	if p.config.Sign != nil {
+		if err := p.config.Sign.Prepare(); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+
 	if p.config.OutputPath == "" {
 		p.config.OutputPath = "packer_{{.BuildName}}_{{.BuilderType}}_{{.ChecksumType}}.checksum"
 	}
@@ -100,7 +135,6 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 
 func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
 	files := artifact.Files()
-	var h hash.Hash
 
 	var generatedData map[interface{}]interface{}
 	stateData := artifact.State("generated_data")
@@ -118,42 +152,55 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 
 	newartifact := NewArtifact(artifact.Files())
 
+	sums, err := hashFiles(files, p.config.ChecksumTypes)
+	if err != nil {
+		return nil, false, true, err
+	}
+
+	// The checksum files, in order, with the checksum types they hold.
+	var checksumFiles []string
+	checksumTypes := map[string][]string{}
 	for _, ct := range p.config.ChecksumTypes {
-		h = getHash(ct)
 		generatedData["ChecksumType"] = ct
 		p.config.ctx.Data = generatedData
 
-		for _, art := range files {
-			checksumFile, err := interpolate.Render(p.config.OutputPath, &p.config.ctx)
-			if err != nil {
-				return nil, false, true, err
-			}
+		checksumFile, err := interpolate.Render(p.config.OutputPath, &p.config.ctx)
+		if err != nil {
+			return nil, false, true, err
+		}
+		if _, ok := checksumTypes[checksumFile]; !ok {
+			checksumFiles = append(checksumFiles, checksumFile)
+		}
+		checksumTypes[checksumFile] = append(checksumTypes[checksumFile], ct)
+	}
+
+	for _, checksumFile := range checksumFiles {
+		if err := os.MkdirAll(filepath.Dir(checksumFile), os.FileMode(0755)); err != nil {
+			return nil, false, true, fmt.Errorf("unable to create dir: %s", err.Error())
+		}
 
+		if p.config.Format == "" {
 			if _, err := os.Stat(checksumFile); err != nil {
 				newartifact.files = append(newartifact.files, checksumFile)
 			}
-			if err := os.MkdirAll(filepath.Dir(checksumFile), os.FileMode(0755)); err != nil {
-				return nil, false, true, fmt.Errorf("unable to create dir: %s", err.Error())
-			}
-			fw, err := os.OpenFile(checksumFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, os.FileMode(0644))
-			if err != nil {
-				return nil, false, true, fmt.Errorf("unable to create file %s: %s", checksumFile, err.Error())
+			if err := appendChecksums(checksumFile, files, sums, checksumTypes[checksumFile]); err != nil {
+				return nil, false, true, err
 			}
-			fr, err := os.Open(art)
-			if err != nil {
-				fw.Close()
-				return nil, false, true, fmt.Errorf("unable to open file %s: %s", art, err.Error())
+		} else {
+			newartifact.files = append(newartifact.files, checksumFile)
+			if err := writeChecksums(checksumFile, p.config.Format, files, sums, checksumTypes[checksumFile]); err != nil {
+				return nil, false, true, err
 			}
+		}
+		ui.Say(fmt.Sprintf("Wrote checksums to %s", checksumFile))
 
-			if _, err = io.Copy(h, fr); err != nil {
-				fr.Close()
-				fw.Close()
-				return nil, false, true, fmt.Errorf("unable to compute %s hash for %s", ct, art)
+		if p.config.Sign != nil {
+			signatureFiles, err := p.config.Sign.signFile(ctx, checksumFile)
+			if err != nil {
+				return nil, false, true, err
 			}
-			fr.Close()
-			_, _ = fw.WriteString(fmt.Sprintf("%x\t%s\n", h.Sum(nil), filepath.Base(art)))
-			fw.Close()
-			h.Reset()
+			newartifact.files = append(newartifact.files, signatureFiles...)
+			ui.Say(fmt.Sprintf("Wrote the signature of %s to %s", checksumFile, strings.Join(signatureFiles, ", ")))
 		}
 	}
 
@@ -161,3 +208,50 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 	// delete the very artifact we're checksumming.
 	return newartifact, true, true, nil
 }
+
+// hashFiles reads each file once, computing all the checksum types at the
+// same time, and hashes files in parallel. It returns the hex encoded
+// checksums of each file, by type.
+func hashFiles(files []string, checksumTypes []string) ([]map[string]string, error) {
+	sums := make([]map[string]string, len(files))
+	errs := make([]error, len(files))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.GOMAXPROCS(-1))
+	for i, path := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			sums[i], errs[i] = hashFile(path, checksumTypes)
+		}()
+	}
+	wg.Wait()
+
+	return sums, errors.Join(errs...)
+}
+
+func hashFile(path string, checksumTypes []string) (map[string]string, error) {
+	fr, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %s: %s", path, err.Error())
+	}
+	defer fr.Close()
+
+	hashes := make([]hash.Hash, len(checksumTypes))
+	writers := make([]io.Writer, len(checksumTypes))
+	for i, ct := range checksumTypes {
+		hashes[i] = getHash(ct)
+		writers[i] = hashes[i]
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), fr); err != nil {
+		return nil, fmt.Errorf("unable to compute checksums for %s: %s", path, err.Error())
+	}
+
+	sums := make(map[string]string, len(checksumTypes))
+	for i, ct := range checksumTypes {
+		sums[ct] = hex.EncodeToString(hashes[i].Sum(nil))
+	}
+	return sums, nil
+}
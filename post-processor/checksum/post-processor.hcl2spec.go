@@ -20,6 +20,8 @@ type FlatConfig struct {
 	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
 	ChecksumTypes       []string          `mapstructure:"checksum_types" cty:"checksum_types" hcl:"checksum_types"`
 	OutputPath          *string           `mapstructure:"output" cty:"output" hcl:"output"`
+	Format              *string           `mapstructure:"format" cty:"format" hcl:"format"`
+	Sign                *FlatSignConfig   `mapstructure:"sign" cty:"sign" hcl:"sign"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -44,6 +46,37 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
 		"checksum_types":             &hcldec.AttrSpec{Name: "checksum_types", Type: cty.List(cty.String), Required: false},
 		"output":                     &hcldec.AttrSpec{Name: "output", Type: cty.String, Required: false},
+		"format":                     &hcldec.AttrSpec{Name: "format", Type: cty.String, Required: false},
+		"sign":                       &hcldec.BlockSpec{TypeName: "sign", Nested: hcldec.ObjectSpec((*FlatSignConfig)(nil).HCL2Spec())},
+	}
+	return s
+}
+
+// FlatSignConfig is an auto-generated flat version of SignConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatSignConfig struct {
+	Mode         *string `mapstructure:"mode" cty:"mode" hcl:"mode"`
+	Signer       *string `mapstructure:"signer" required:"true" cty:"signer" hcl:"signer"`
+	Verifier     *string `mapstructure:"verifier" cty:"verifier" hcl:"verifier"`
+	DSSEEnvelope *bool   `mapstructure:"dsse_envelope" cty:"dsse_envelope" hcl:"dsse_envelope"`
+}
+
+// FlatMapstructure returns a new FlatSignConfig.
+// FlatSignConfig is an auto-generated flat version of SignConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*SignConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatSignConfig)
+}
+
+// HCL2Spec returns the hcl spec of a SignConfig.
+// This spec is used by HCL to read the fields of SignConfig.
+// The decoded values from this spec will then be applied to a FlatSignConfig.
+func (*FlatSignConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"mode":          &hcldec.AttrSpec{Name: "mode", Type: cty.String, Required: false},
+		"signer":        &hcldec.AttrSpec{Name: "signer", Type: cty.String, Required: false},
+		"verifier":      &hcldec.AttrSpec{Name: "verifier", Type: cty.String, Required: false},
+		"dsse_envelope": &hcldec.AttrSpec{Name: "dsse_envelope", Type: cty.Bool, Required: false},
 	}
 	return s
 }
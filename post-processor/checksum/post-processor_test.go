@@ -6,15 +6,26 @@ package checksum
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template"
 	"github.com/hashicorp/packer/builder/file"
+	internalattestation "github.com/hashicorp/packer/internal/attestation"
 )
 
 func TestChecksumSHA1(t *testing.T) {
@@ -43,6 +54,224 @@ func TestChecksumSHA1(t *testing.T) {
 	defer f.Close()
 }
 
+func TestChecksumFormats(t *testing.T) {
+	cases := []struct {
+		name     string
+		format   string
+		types    string
+		output   string
+		expected string
+	}{
+		{
+			name:     "gnu",
+			format:   "gnu",
+			types:    `["sha256"]`,
+			output:   "sums/SHA256SUMS",
+			expected: "c0535e4be2b79ffd93291305436bf889314e4a3faec05ecffcbb7df31ad9e51a  ../package.txt\n",
+		},
+		{
+			name:   "bsd",
+			format: "bsd",
+			types:  `["sha3-256", "blake3"]`,
+			output: "CHECKSUMS",
+			expected: "SHA3-256 (package.txt) = d6ea8f9a1f22e1298e5a9506bd066f23cc56001f5d36582344a628649df53ae8\n" +
+				"BLAKE3 (package.txt) = 793c10bc0b28c378330d39edace7260af9da81d603b8ffede2706a21eda893f4\n",
+		},
+		{
+			name:   "json",
+			format: "json",
+			types:  `["md5", "sha256"]`,
+			output: "checksums.json",
+			expected: `{
+  "files": [
+    {
+      "path": "package.txt",
+      "checksums": {
+        "md5": "86fb269d190d2c85f6e0468ceca42a20",
+        "sha256": "c0535e4be2b79ffd93291305436bf889314e4a3faec05ecffcbb7df31ad9e51a"
+      }
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := fmt.Sprintf(`{"post-processors": [{"type": "checksum", "format": %q, "checksum_types": %s, "output": %q}]}`,
+				tc.format, tc.types, tc.output)
+			// Running twice checks the file is rewritten rather than appended to.
+			testChecksum(t, config)
+			artifact := testChecksum(t, config)
+			defer os.RemoveAll("sums")
+			defer artifact.Destroy()
+
+			buf, err := os.ReadFile(tc.output)
+			if err != nil {
+				t.Fatalf("Unable to read checksum file: %s", err)
+			}
+			if string(buf) != tc.expected {
+				t.Errorf("Expected:\n%s\nFound:\n%s", tc.expected, buf)
+			}
+		})
+	}
+}
+
+func TestChecksumGNUSeveralTypes(t *testing.T) {
+	_, artifact, err := setup(t)
+	if err != nil {
+		t.Fatalf("Error bootstrapping test: %s", err)
+	}
+	defer artifact.Destroy()
+
+	checksum := PostProcessor{}
+	err = checksum.Configure(map[string]interface{}{
+		"format":         "gnu",
+		"checksum_types": []string{"sha256", "sha512"},
+		"output":         "SUMS",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("SUMS")
+	if _, _, _, err := checksum.PostProcess(context.Background(), packersdk.TestUi(t), artifact); err == nil {
+		t.Fatal("expected the gnu format to refuse several checksum types in one file")
+	}
+}
+
+func TestChecksumSign(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := fmt.Sprintf(`{"post-processors": [{"type": "checksum", "format": "gnu", "checksum_types": ["sha256"], "output": "SHA256SUMS", "sign": {"signer": %q}}]}`, keyPath)
+	artifact := testChecksum(t, config)
+	defer artifact.Destroy()
+
+	if files := artifact.Files(); files[len(files)-1] != "SHA256SUMS.sig" {
+		t.Fatalf("expected the signature in the artifact files, got %q", files)
+	}
+	if _, err := os.Stat("SHA256SUMS.dsse.json"); !os.IsNotExist(err) {
+		t.Fatalf("no DSSE envelope should be written by default: %v", err)
+	}
+
+	// The signature is the one of the checksum file bytes, that any ECDSA
+	// implementation can check.
+	content, err := os.ReadFile("SHA256SUMS.sig")
+	if err != nil {
+		t.Fatalf("Unable to read signature file: %s", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(string(content))
+	if err != nil {
+		t.Fatalf("the signature should be base64 encoded: %s", err)
+	}
+	sums, err := os.ReadFile("SHA256SUMS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(sums)
+	if !ecdsa.VerifyASN1(&privateKey.PublicKey, digest[:], signature) {
+		t.Fatal("the signature does not cover the checksum file")
+	}
+}
+
+func TestChecksumSign_dsseEnvelope(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := fmt.Sprintf(`{"post-processors": [{"type": "checksum", "format": "gnu", "checksum_types": ["sha256"], "output": "SHA256SUMS", "sign": {"signer": %q, "dsse_envelope": true}}]}`, keyPath)
+	artifact := testChecksum(t, config)
+	defer artifact.Destroy()
+
+	files := artifact.Files()
+	if files[len(files)-2] != "SHA256SUMS.sig" || files[len(files)-1] != "SHA256SUMS.dsse.json" {
+		t.Fatalf("expected the signature and the envelope in the artifact files, got %q", files)
+	}
+
+	sums, err := os.ReadFile("SHA256SUMS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile("SHA256SUMS.sig")
+	if err != nil {
+		t.Fatalf("Unable to read signature file: %s", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(string(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(privateKey.Public().(ed25519.PublicKey), sums, signature) {
+		t.Fatal("the signature does not cover the checksum file")
+	}
+
+	content, err = os.ReadFile("SHA256SUMS.dsse.json")
+	if err != nil {
+		t.Fatalf("Unable to read envelope file: %s", err)
+	}
+	var envelope internalattestation.Envelope
+	if err := json.Unmarshal(content, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	backendConfig := internalattestation.BackendConfig{Mode: internalattestation.SigningModeKey, SignerRef: keyPath}
+	signer, err := internalattestation.NewSigner(context.Background(), backendConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := signer.Verifier(context.Background(), backendConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := internalattestation.VerifyEnvelope(context.Background(), envelope, verifier); err != nil {
+		t.Fatalf("invalid signature: %s", err)
+	}
+
+	payload, err := internalattestation.DecodeEnvelopePayload(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(payload, sums) || envelope.PayloadType != SignaturePayloadType {
+		t.Errorf("the envelope does not embed the checksum file: %+v", envelope)
+	}
+}
+
+func TestChecksumSignConfig(t *testing.T) {
+	checksum := PostProcessor{}
+	err := checksum.Configure(map[string]interface{}{
+		"sign": map[string]interface{}{"mode": "keyless", "signer": "key.pem"},
+	})
+	if err == nil {
+		t.Fatal("expected an unsupported signing mode to fail")
+	}
+
+	checksum = PostProcessor{}
+	err = checksum.Configure(map[string]interface{}{
+		"sign": map[string]interface{}{},
+	})
+	if err == nil {
+		t.Fatal("expected a missing signer to fail")
+	}
+}
+
 // Test Helpers
 
 func setup(t *testing.T) (packersdk.Ui, packersdk.Artifact, error) {
@@ -0,0 +1,134 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package checksum
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	internalattestation "github.com/hashicorp/packer/internal/attestation"
+)
+
+// SignaturePayloadType is the DSSE payload type of checksum file signatures.
+const SignaturePayloadType = "application/vnd.packer.checksums"
+
+// SignConfig signs checksum files with a local key or a KMS key. Each
+// checksum file gets a detached signature over its bytes, written base64
+// encoded to `<checksum file>.sig`, the format of `cosign sign-blob`. RSA
+// and ECDSA keys sign the SHA-256 digest of the file, so the signature can
+// be checked with `cosign verify-blob --key key.pub --signature SHA256SUMS.sig
+// SHA256SUMS`, or with `openssl dgst -sha256 -verify key.pub -signature
+// <(base64 -d SHA256SUMS.sig) SHA256SUMS`.
+type SignConfig struct {
+	// The signing mode: `key` (local PEM key, default) or `kms` (KMS or
+	// Vault URI).
+	Mode string `mapstructure:"mode"`
+	// A PEM private key path for `key` mode, or a KMS or Vault URI such as
+	// `awskms://...`, `gcpkms://...`, `azurekms://...`, or `hashivault://...`
+	// for `kms` mode.
+	Signer string `mapstructure:"signer" required:"true"`
+	// The PEM verifier path used to check the signature after signing.
+	// Defaults to the signer's public key.
+	Verifier string `mapstructure:"verifier"`
+	// Also write a DSSE envelope embedding the checksum file and its
+	// signature to `<checksum file>.dsse.json`. The envelope signature
+	// covers the DSSE pre-authentication encoding of the checksum file, so
+	// it must be verified as a DSSE envelope. Defaults to false.
+	DSSEEnvelope bool `mapstructure:"dsse_envelope"`
+}
+
+func (c *SignConfig) Prepare() error {
+	if c.Mode == "" {
+		c.Mode = internalattestation.SigningModeKey
+	}
+	if c.Mode != internalattestation.SigningModeKey && c.Mode != internalattestation.SigningModeKMS {
+		return fmt.Errorf("sign: mode must be %q or %q, got %q",
+			internalattestation.SigningModeKey, internalattestation.SigningModeKMS, c.Mode)
+	}
+	if c.Signer == "" {
+		return fmt.Errorf("sign: signer is required")
+	}
+	return nil
+}
+
+// signFile writes the detached signature of path to path.sig, and the DSSE
+// envelope signing it to path.dsse.json when enabled. It returns the paths
+// of the files written.
+func (c *SignConfig) signFile(ctx context.Context, path string) ([]string, error) {
+	backendConfig := internalattestation.BackendConfig{
+		Mode:        c.Mode,
+		SignerRef:   c.Signer,
+		VerifierRef: c.Verifier,
+	}
+	signer, err := internalattestation.NewSigner(ctx, backendConfig)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := internalattestation.NewVerifier(ctx, backendConfig, signer)
+	if err != nil {
+		return nil, err
+	}
+	blobSigner, ok := signer.(internalattestation.BlobSigner)
+	if !ok {
+		return nil, fmt.Errorf("sign: mode %q cannot write detached signatures", c.Mode)
+	}
+	blobVerifier, ok := verifier.(internalattestation.BlobVerifier)
+	if !ok {
+		return nil, fmt.Errorf("sign: the verifier cannot check detached signatures")
+	}
+
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file %s: %s", path, err.Error())
+	}
+	signature, err := blobSigner.SignBlob(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign %s: %s", path, err.Error())
+	}
+	if err := blobVerifier.VerifyBlob(ctx, payload, signature.Sig); err != nil {
+		return nil, fmt.Errorf("unable to verify the signature of %s: %s", path, err.Error())
+	}
+
+	signatureFile := path + ".sig"
+	content := base64.StdEncoding.EncodeToString(signature.Sig)
+	if err := os.WriteFile(signatureFile, []byte(content), os.FileMode(0644)); err != nil {
+		return nil, fmt.Errorf("unable to write file %s: %s", signatureFile, err.Error())
+	}
+	files := []string{signatureFile}
+
+	if c.DSSEEnvelope {
+		envelopeFile, err := writeEnvelope(ctx, path, payload, signer, verifier)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, envelopeFile)
+	}
+	return files, nil
+}
+
+// writeEnvelope writes the DSSE envelope signing payload, the content of
+// path, to path.dsse.json, and returns its path.
+func writeEnvelope(ctx context.Context, path string, payload []byte, signer internalattestation.Signer, verifier internalattestation.Verifier) (string, error) {
+	signature, err := signer.Sign(ctx, SignaturePayloadType, payload)
+	if err != nil {
+		return "", fmt.Errorf("unable to sign %s: %s", path, err.Error())
+	}
+	envelope := internalattestation.NewEnvelope(SignaturePayloadType, payload, signature)
+	if err := internalattestation.VerifyEnvelope(ctx, envelope, verifier); err != nil {
+		return "", fmt.Errorf("unable to verify the signature of %s: %s", path, err.Error())
+	}
+
+	content, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	envelopeFile := path + ".dsse.json"
+	if err := os.WriteFile(envelopeFile, content, os.FileMode(0644)); err != nil {
+		return "", fmt.Errorf("unable to write file %s: %s", envelopeFile, err.Error())
+	}
+	return envelopeFile, nil
+}
@@ -4,6 +4,7 @@
 package compress
 
 import (
+	"errors"
 	"fmt"
 	"os"
 )
@@ -12,6 +13,10 @@ const BuilderId = "packer.post-processor.compress"
 
 type Artifact struct {
 	Path string
+	// Parts holds the volumes of the archive, when it is split.
+	Parts []string
+	// Index is the JSON index of Parts.
+	Index string
 }
 
 func (a *Artifact) BuilderId() string {
@@ -23,10 +28,16 @@ func (*Artifact) Id() string {
 }
 
 func (a *Artifact) Files() []string {
+	if len(a.Parts) > 0 {
+		return append(append([]string{}, a.Parts...), a.Index)
+	}
 	return []string{a.Path}
 }
 
 func (a *Artifact) String() string {
+	if len(a.Parts) > 0 {
+		return fmt.Sprintf("compressed artifacts in: %s (%d parts)", a.Path, len(a.Parts))
+	}
 	return fmt.Sprintf("compressed artifacts in: %s", a.Path)
 }
 
@@ -35,5 +46,9 @@ func (*Artifact) State(name string) interface{} {
 }
 
 func (a *Artifact) Destroy() error {
-	return os.Remove(a.Path)
+	var errs []error
+	for _, path := range a.Files() {
+		errs = append(errs, os.Remove(path))
+	}
+	return errors.Join(errs...)
 }
@@ -16,6 +16,7 @@ import (
 	"testing"
 
 	"github.com/biogo/hts/bgzf"
+	"github.com/klauspost/compress/zstd"
 	"github.com/klauspost/pgzip"
 	"github.com/pierrec/lz4/v4"
 	"github.com/ulikunitz/xz"
@@ -119,6 +120,16 @@ func main() {
 	c.Close()
 	fmt.Printf("xz:\twriter %s\treader %s\tsize %d\n", resw.T.String(), resr.T.String(), c.sw)
 
+	c, err = NewCompressor("/tmp/image.r", "/tmp/image.w")
+	if err != nil {
+		panic(err)
+	}
+	resw = testing.Benchmark(c.BenchmarkZstdWriter)
+	c.w.Seek(0, 0)
+	resr = testing.Benchmark(c.BenchmarkZstdReader)
+	c.Close()
+	fmt.Printf("zstd:\twriter %s\treader %s\tsize %d\n", resw.T.String(), resr.T.String(), c.sw)
+
 }
 
 func (c *Compressor) BenchmarkGZIPWriter(b *testing.B) {
@@ -231,3 +242,25 @@ func (c *Compressor) BenchmarkXZReader(b *testing.B) {
 		b.Fatal(err)
 	}
 }
+
+func (c *Compressor) BenchmarkZstdWriter(b *testing.B) {
+	cw, _ := zstd.NewWriter(c.w, zstd.WithEncoderConcurrency(runtime.GOMAXPROCS(-1)))
+	b.ResetTimer()
+
+	_, err := io.Copy(cw, c.r)
+	if err != nil {
+		b.Fatal(err)
+	}
+	cw.Close()
+	c.w.Sync()
+}
+
+func (c *Compressor) BenchmarkZstdReader(b *testing.B) {
+	cr, _ := zstd.NewReader(c.w)
+	b.ResetTimer()
+
+	_, err := io.Copy(io.Discard, cr)
+	if err != nil {
+		b.Fatal(err)
+	}
+}
@@ -15,9 +15,13 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/biogo/hts/bgzf"
 	"github.com/dsnet/compress/bzip2"
+	"github.com/dustin/go-humanize"
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/common"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
@@ -48,10 +52,45 @@ type Config struct {
 	Format           string `mapstructure:"format"`
 	CompressionLevel int    `mapstructure:"compression_level"`
 
+	// Enlarge the zstd window to 128 MiB, the window `zstd --long=27` uses,
+	// so that matches far apart in large files like disk images are found.
+	// This does not enable the long distance matching mode of the zstd
+	// command line, only its window size. Decompressing needs as much
+	// memory. It cannot be combined with `zstd_seekable`, whose frames are
+	// compressed independently.
+	ZstdLongDistanceMatching bool `mapstructure:"zstd_long_distance_matching"`
+	// Write zstd files in the seekable format, made of independent frames
+	// and a seek table, so consumers can read any part of the file without
+	// decompressing it whole. Any zstd decoder can still decompress it.
+	ZstdSeekable bool `mapstructure:"zstd_seekable"`
+	// The size of the input compressed in each frame of the seekable
+	// format, like `8MiB`, the default. Smaller frames allow finer access
+	// but compress less.
+	ZstdSeekableFrameSize string `mapstructure:"zstd_seekable_frame_size"`
+
+	// Split the output in volumes of this size, like `4GiB`, named after
+	// `output` with a `.000`, `.001`, ... suffix. A JSON index listing the
+	// offset and SHA256 of each part is written to `<output>.index.json`.
+	// Concatenating the parts in order gives back the archive. Defaults to
+	// no splitting.
+	SplitSize string `mapstructure:"split_size"`
+
+	// Make tar and zip archives byte-identical for identical inputs: entries
+	// are sorted by path, their timestamp set to `source_date_epoch`, their
+	// owner cleared and their mode set to 0755 if executable, else 0644.
+	Reproducible bool `mapstructure:"reproducible"`
+	// The timestamp of the entries of reproducible archives, in seconds
+	// since the Unix epoch. Defaults to the `SOURCE_DATE_EPOCH` environment
+	// variable, or 0.
+	SourceDateEpoch int64 `mapstructure:"source_date_epoch"`
+
 	// Derived fields
 	Archive   string
 	Algorithm string
 
+	zstdFrameSize int
+	splitSize     int64
+
 	ctx interpolate.Context
 }
 
@@ -102,6 +141,59 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 
 	p.config.detectFromFilename()
 
+	if p.config.ZstdSeekableFrameSize == "" {
+		p.config.ZstdSeekableFrameSize = "8MiB"
+	}
+	frameSize, err := humanize.ParseBytes(p.config.ZstdSeekableFrameSize)
+	switch {
+	case err != nil:
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("Invalid zstd_seekable_frame_size: %s", err))
+	case frameSize == 0 || frameSize > zstdMaxSeekableFrameSize:
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("zstd_seekable_frame_size must be between 1B and 1GiB"))
+	default:
+		p.config.zstdFrameSize = int(frameSize)
+	}
+	if p.config.SplitSize != "" {
+		splitSize, err := humanize.ParseBytes(p.config.SplitSize)
+		switch {
+		case err != nil:
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("Invalid split_size: %s", err))
+		case splitSize == 0:
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("split_size must not be zero"))
+		default:
+			p.config.splitSize = int64(splitSize)
+		}
+	}
+
+	if p.config.Reproducible && p.config.SourceDateEpoch == 0 {
+		if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+			p.config.SourceDateEpoch, err = strconv.ParseInt(epoch, 10, 64)
+			if err != nil {
+				errs = packersdk.MultiErrorAppend(
+					errs, fmt.Errorf("Invalid SOURCE_DATE_EPOCH: %s", err))
+			}
+		}
+	}
+	if p.config.SourceDateEpoch < 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("source_date_epoch must not be negative"))
+	}
+
+	if (p.config.ZstdSeekable || p.config.ZstdLongDistanceMatching) && p.config.Algorithm != "zstd" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("zstd_seekable and zstd_long_distance_matching require zstd compression, "+
+				"use a .zst output or format"))
+	}
+	if p.config.ZstdSeekable && p.config.ZstdLongDistanceMatching {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("zstd_long_distance_matching cannot be used with zstd_seekable, "+
+				"the frames of the seekable format are compressed independently"))
+	}
+
 	if len(errs.Errors) > 0 {
 		return errs
 	}
@@ -144,12 +236,28 @@ func (p *PostProcessor) PostProcess(
 		return nil, false, false, fmt.Errorf(
 			"Unable to create dir for archive %s: %s", target, err)
 	}
-	outputFile, err := os.Create(target)
+	// The output file is split in parts when split_size is set.
+	var split *splitWriter
+	var file io.WriteCloser
+	if p.config.splitSize > 0 {
+		split, err = newSplitWriter(target, p.config.splitSize)
+		file = split
+	} else {
+		file, err = os.Create(target)
+	}
 	if err != nil {
 		return nil, false, false, fmt.Errorf(
 			"Unable to create archive %s: %s", target, err)
 	}
-	defer outputFile.Close()
+	outputFile := &closeOnce{WriteCloser: file}
+	// The parts of an archive that could not be completed are removed.
+	completed := false
+	defer func() {
+		outputFile.Close()
+		if split != nil && !completed {
+			split.remove()
+		}
+	}()
 
 	// Setup output interface. If we're using compression, output is a
 	// compression writer. Otherwise it's just a file.
@@ -163,7 +271,6 @@ func (p *PostProcessor) PostProcess(
 		if err != nil {
 			return nil, false, false, fmt.Errorf(errTmpl, p.config.Algorithm, err)
 		}
-		defer output.Close()
 	case "bzip2":
 		ui.Say(fmt.Sprintf("Using bzip2 compression with 1 core for %s (library does not support MT)",
 			target))
@@ -171,7 +278,6 @@ func (p *PostProcessor) PostProcess(
 		if err != nil {
 			return nil, false, false, fmt.Errorf(errTmpl, p.config.Algorithm, err)
 		}
-		defer output.Close()
 	case "lz4":
 		ui.Say(fmt.Sprintf("Using lz4 compression with %d cores for %s",
 			runtime.GOMAXPROCS(-1), target))
@@ -179,7 +285,6 @@ func (p *PostProcessor) PostProcess(
 		if err != nil {
 			return nil, false, false, fmt.Errorf(errTmpl, p.config.Algorithm, err)
 		}
-		defer output.Close()
 	case "xz":
 		ui.Say(fmt.Sprintf("Using xz compression with 1 core for %s (library does not support MT)",
 			target))
@@ -187,7 +292,6 @@ func (p *PostProcessor) PostProcess(
 		if err != nil {
 			return nil, false, false, fmt.Errorf(errTmpl, p.config.Algorithm, err)
 		}
-		defer output.Close()
 	case "pgzip":
 		ui.Say(fmt.Sprintf("Using pgzip compression with %d cores for %s",
 			runtime.GOMAXPROCS(-1), target))
@@ -196,10 +300,24 @@ func (p *PostProcessor) PostProcess(
 			return nil, false, false,
 				fmt.Errorf(errTmpl, p.config.Algorithm, err)
 		}
-		defer output.Close()
+	case "zstd":
+		ui.Say(fmt.Sprintf("Using zstd compression with %d cores for %s",
+			runtime.GOMAXPROCS(-1), target))
+		if p.config.ZstdSeekable {
+			output, err = makeZstdSeekableWriter(outputFile, p.config.CompressionLevel,
+				runtime.GOMAXPROCS(-1), p.config.zstdFrameSize)
+		} else {
+			output, err = makeZstdWriter(outputFile, p.config.CompressionLevel,
+				runtime.GOMAXPROCS(-1), p.config.ZstdLongDistanceMatching)
+		}
+		if err != nil {
+			return nil, false, false, fmt.Errorf(errTmpl, p.config.Algorithm, err)
+		}
 	default:
 		output = outputFile
 	}
+	output = &closeOnce{WriteCloser: output}
+	defer output.Close()
 
 	compression := p.config.Algorithm
 	if compression == "" {
@@ -210,13 +328,13 @@ func (p *PostProcessor) PostProcess(
 	switch p.config.Archive {
 	case "tar":
 		ui.Say(fmt.Sprintf("Tarring %s with %s", target, compression))
-		err = createTarArchive(artifact.Files(), output)
+		err = createTarArchive(artifact.Files(), output, p.config.reproducibleTime())
 		if err != nil {
 			return nil, false, false, fmt.Errorf("Error creating tar: %s", err)
 		}
 	case "zip":
 		ui.Say(fmt.Sprintf("Zipping %s", target))
-		err = createZipArchive(artifact.Files(), output)
+		err = createZipArchive(artifact.Files(), output, p.config.reproducibleTime())
 		if err != nil {
 			return nil, false, false, fmt.Errorf("Error creating zip: %s", err)
 		}
@@ -245,6 +363,24 @@ func (p *PostProcessor) PostProcess(
 		}
 	}
 
+	if err := output.Close(); err != nil {
+		return nil, false, false, fmt.Errorf("Failed to finish archive %s: %s", target, err)
+	}
+	if err := outputFile.Close(); err != nil {
+		return nil, false, false, fmt.Errorf("Failed to finish archive %s: %s", target, err)
+	}
+
+	if split != nil {
+		if err := split.writeIndex(); err != nil {
+			return nil, false, false, fmt.Errorf("Failed to finish archive %s: %s", target, err)
+		}
+		newArtifact.Parts = split.paths[:len(split.paths)-1]
+		newArtifact.Index = split.paths[len(split.paths)-1]
+		ui.Say(fmt.Sprintf("Archive %s split in %d parts, indexed in %s",
+			target, len(newArtifact.Parts), newArtifact.Index))
+	}
+
+	completed = true
 	ui.Say(fmt.Sprintf("Archive %s completed", target))
 
 	return newArtifact, false, false, nil
@@ -261,6 +397,8 @@ func (config *Config) detectFromFilename() {
 		"bgzf":  "bgzf",
 		"xz":    "xz",
 		"bzip2": "bzip2",
+		"zst":   "zstd",
+		"zstd":  "zstd",
 	}
 
 	if config.Format == "" {
@@ -365,7 +503,36 @@ func makeXZWriter(output io.WriteCloser) (io.WriteCloser, error) {
 	return xzwriter, nil
 }
 
+// reproducibleTime returns the timestamp of archive entries when archives
+// must be reproducible, or nil.
+func (c *Config) reproducibleTime() *time.Time {
+	if !c.Reproducible {
+		return nil
+	}
+	modTime := time.Unix(c.SourceDateEpoch, 0).UTC()
+	return &modTime
+}
+
+// reproducibleMode keeps the file type and whether a file is executable,
+// so archives do not depend on the umask of the build.
+func reproducibleMode(mode os.FileMode) os.FileMode {
+	if mode&0111 != 0 {
+		return mode.Type() | 0755
+	}
+	return mode.Type() | 0644
+}
+
+// sortedFiles returns a sorted copy of files, so entries of reproducible
+// archives do not depend on the order the builder listed them in.
+func sortedFiles(files []string) []string {
+	files = append([]string{}, files...)
+	sort.Strings(files)
+	return files
+}
+
 func makePgzipWriter(output io.WriteCloser, compressionLevel int) (io.WriteCloser, error) {
+	// The gzip header is left without name and timestamp, so identical
+	// inputs give identical outputs.
 	gzipWriter, err := pgzip.NewWriterLevel(output, compressionLevel)
 	if err != nil {
 		return nil, ErrInvalidCompressionLevel
@@ -374,10 +541,16 @@ func makePgzipWriter(output io.WriteCloser, compressionLevel int) (io.WriteClose
 	return gzipWriter, nil
 }
 
-func createTarArchive(files []string, output io.WriteCloser) error {
+// createTarArchive writes files to a tar stream. When modTime is set, the
+// archive is reproducible: entries are sorted and their metadata normalized.
+func createTarArchive(files []string, output io.WriteCloser, modTime *time.Time) error {
 	archive := tar.NewWriter(output)
 	defer archive.Close()
 
+	if modTime != nil {
+		files = sortedFiles(files)
+	}
+
 	for _, path := range files {
 		file, err := os.Open(path)
 		if err != nil {
@@ -398,6 +571,13 @@ func createTarArchive(files []string, output io.WriteCloser) error {
 		// workaround for archive format on go >=1.10
 		setHeaderFormat(header)
 
+		if modTime != nil {
+			header.ModTime = *modTime
+			header.Uid, header.Gid = 0, 0
+			header.Uname, header.Gname = "", ""
+			header.Mode = int64(reproducibleMode(fi.Mode()).Perm())
+		}
+
 		if err := archive.WriteHeader(header); err != nil {
 			return fmt.Errorf("Failed to write tar header for %s: %s", path, err)
 		}
@@ -409,10 +589,16 @@ func createTarArchive(files []string, output io.WriteCloser) error {
 	return nil
 }
 
-func createZipArchive(files []string, output io.WriteCloser) error {
+// createZipArchive writes files to a zip stream. When modTime is set, the
+// archive is reproducible: entries are sorted and their metadata normalized.
+func createZipArchive(files []string, output io.WriteCloser, modTime *time.Time) error {
 	archive := zip.NewWriter(output)
 	defer archive.Close()
 
+	if modTime != nil {
+		files = sortedFiles(files)
+	}
+
 	for _, path := range files {
 		path = filepath.ToSlash(path)
 
@@ -422,7 +608,23 @@ func createZipArchive(files []string, output io.WriteCloser) error {
 		}
 		defer source.Close()
 
-		target, err := archive.Create(path)
+		var target io.Writer
+		if modTime != nil {
+			var fi os.FileInfo
+			fi, err = source.Stat()
+			if err != nil {
+				return fmt.Errorf("Unable to get fileinfo for %s: %s", path, err)
+			}
+			header := &zip.FileHeader{
+				Name:     path,
+				Method:   zip.Deflate,
+				Modified: *modTime,
+			}
+			header.SetMode(reproducibleMode(fi.Mode()))
+			target, err = archive.CreateHeader(header)
+		} else {
+			target, err = archive.Create(path)
+		}
 		if err != nil {
 			return fmt.Errorf("Failed to add zip header for %s: %s", path, err)
 		}
@@ -10,19 +10,25 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName     *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType   *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion   *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug         *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce         *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError       *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars      map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	OutputPath          *string           `mapstructure:"output" cty:"output" hcl:"output"`
-	Format              *string           `mapstructure:"format" cty:"format" hcl:"format"`
-	CompressionLevel    *int              `mapstructure:"compression_level" cty:"compression_level" hcl:"compression_level"`
-	Archive             *string           `cty:"archive" hcl:"archive"`
-	Algorithm           *string           `cty:"algorithm" hcl:"algorithm"`
+	PackerBuildName          *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType        *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion        *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug              *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce              *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError            *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars           map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars      []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	OutputPath               *string           `mapstructure:"output" cty:"output" hcl:"output"`
+	Format                   *string           `mapstructure:"format" cty:"format" hcl:"format"`
+	CompressionLevel         *int              `mapstructure:"compression_level" cty:"compression_level" hcl:"compression_level"`
+	ZstdLongDistanceMatching *bool             `mapstructure:"zstd_long_distance_matching" cty:"zstd_long_distance_matching" hcl:"zstd_long_distance_matching"`
+	ZstdSeekable             *bool             `mapstructure:"zstd_seekable" cty:"zstd_seekable" hcl:"zstd_seekable"`
+	ZstdSeekableFrameSize    *string           `mapstructure:"zstd_seekable_frame_size" cty:"zstd_seekable_frame_size" hcl:"zstd_seekable_frame_size"`
+	SplitSize                *string           `mapstructure:"split_size" cty:"split_size" hcl:"split_size"`
+	Reproducible             *bool             `mapstructure:"reproducible" cty:"reproducible" hcl:"reproducible"`
+	SourceDateEpoch          *int64            `mapstructure:"source_date_epoch" cty:"source_date_epoch" hcl:"source_date_epoch"`
+	Archive                  *string           `cty:"archive" hcl:"archive"`
+	Algorithm                *string           `cty:"algorithm" hcl:"algorithm"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -37,19 +43,25 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"packer_build_name":          &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
-		"packer_builder_type":        &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
-		"packer_core_version":        &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
-		"packer_debug":               &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
-		"packer_force":               &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
-		"packer_on_error":            &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
-		"packer_user_variables":      &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
-		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
-		"output":                     &hcldec.AttrSpec{Name: "output", Type: cty.String, Required: false},
-		"format":                     &hcldec.AttrSpec{Name: "format", Type: cty.String, Required: false},
-		"compression_level":          &hcldec.AttrSpec{Name: "compression_level", Type: cty.Number, Required: false},
-		"archive":                    &hcldec.AttrSpec{Name: "archive", Type: cty.String, Required: false},
-		"algorithm":                  &hcldec.AttrSpec{Name: "algorithm", Type: cty.String, Required: false},
+		"packer_build_name":           &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":         &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":         &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":                &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":                &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":             &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":       &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables":  &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"output":                      &hcldec.AttrSpec{Name: "output", Type: cty.String, Required: false},
+		"format":                      &hcldec.AttrSpec{Name: "format", Type: cty.String, Required: false},
+		"compression_level":           &hcldec.AttrSpec{Name: "compression_level", Type: cty.Number, Required: false},
+		"zstd_long_distance_matching": &hcldec.AttrSpec{Name: "zstd_long_distance_matching", Type: cty.Bool, Required: false},
+		"zstd_seekable":               &hcldec.AttrSpec{Name: "zstd_seekable", Type: cty.Bool, Required: false},
+		"zstd_seekable_frame_size":    &hcldec.AttrSpec{Name: "zstd_seekable_frame_size", Type: cty.String, Required: false},
+		"split_size":                  &hcldec.AttrSpec{Name: "split_size", Type: cty.String, Required: false},
+		"reproducible":                &hcldec.AttrSpec{Name: "reproducible", Type: cty.Bool, Required: false},
+		"source_date_epoch":           &hcldec.AttrSpec{Name: "source_date_epoch", Type: cty.Number, Required: false},
+		"archive":                     &hcldec.AttrSpec{Name: "archive", Type: cty.String, Required: false},
+		"algorithm":                   &hcldec.AttrSpec{Name: "algorithm", Type: cty.String, Required: false},
 	}
 	return s
 }
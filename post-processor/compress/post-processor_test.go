@@ -18,6 +18,7 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template"
 	"github.com/hashicorp/packer/builder/file"
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
 )
 
@@ -52,6 +53,16 @@ func TestDetectFilename(t *testing.T) {
 		t.Error("Expected to find lz4 algorithm setting")
 	}
 
+	// Test .tar.zst
+	zstFilename := Config{OutputPath: "disk.raw.tar.zst"}
+	zstFilename.detectFromFilename()
+	if zstFilename.Archive != "tar" {
+		t.Error("Expected to find tar archive setting")
+	}
+	if zstFilename.Algorithm != "zstd" {
+		t.Error("Expected to find zstd algorithm setting")
+	}
+
 	// Test .archive.compress with some.extra.dots...
 	lotsOfDots := Config{OutputPath: "test.blah.bloo.blee.tar.lz4"}
 	lotsOfDots.detectFromFilename()
@@ -236,6 +247,27 @@ func TestArchive(t *testing.T) {
 			lz4Reader := lz4.NewReader(archive)
 			return io.ReadAll(lz4Reader)
 		},
+		"zst": func(archive *os.File) ([]byte, error) {
+			zstdReader, err := zstd.NewReader(archive)
+			if err != nil {
+				return nil, err
+			}
+			defer zstdReader.Close()
+			return io.ReadAll(zstdReader)
+		},
+		"tar.zst": func(archive *os.File) ([]byte, error) {
+			zstdReader, err := zstd.NewReader(archive)
+			if err != nil {
+				return nil, err
+			}
+			defer zstdReader.Close()
+			tarReader := tar.NewReader(zstdReader)
+			_, err = tarReader.Next()
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(tarReader)
+		},
 	}
 
 	tmpArchiveFile := "temp-archive-package"
@@ -0,0 +1,164 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package compress
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// archiveTwice archives files twice with create, changing their timestamps,
+// modes and order in between, and returns both archives.
+func archiveTwice(t *testing.T, files []string, create func([]string, io.WriteCloser) error) ([]byte, []byte) {
+	var first, second bytes.Buffer
+	gzipWriter, err := makePgzipWriter(nopWriteCloser{&first}, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := create(files, gzipWriter); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	later := time.Now().Add(time.Hour)
+	for _, path := range files {
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(files[0], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(files[1], 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	gzipWriter, err = makePgzipWriter(nopWriteCloser{&second}, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := create([]string{files[1], files[0]}, gzipWriter); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return first.Bytes(), second.Bytes()
+}
+
+func reproducibleInput(t *testing.T) []string {
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "b.txt"), filepath.Join(dir, "a.sh")}
+	if err := os.WriteFile(files[0], []byte("data"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(files[1], []byte("#!/bin/sh\n"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestReproducibleArchives(t *testing.T) {
+	modTime := time.Unix(1700000000, 0).UTC()
+
+	t.Run("tar", func(t *testing.T) {
+		first, second := archiveTwice(t, reproducibleInput(t), func(files []string, output io.WriteCloser) error {
+			return createTarArchive(files, output, &modTime)
+		})
+		if !bytes.Equal(first, second) {
+			t.Fatal("identical inputs gave different tar archives")
+		}
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		first, second := archiveTwice(t, reproducibleInput(t), func(files []string, output io.WriteCloser) error {
+			return createZipArchive(files, output, &modTime)
+		})
+		if !bytes.Equal(first, second) {
+			t.Fatal("identical inputs gave different zip archives")
+		}
+	})
+}
+
+func TestReproducibleMetadata(t *testing.T) {
+	files := reproducibleInput(t)
+	modTime := time.Unix(1700000000, 0).UTC()
+
+	var buf bytes.Buffer
+	if err := createTarArchive(files, nopWriteCloser{&buf}, &modTime); err != nil {
+		t.Fatal(err)
+	}
+	reader := tar.NewReader(&buf)
+	expected := []struct {
+		name string
+		mode int64
+	}{{"a.sh", 0755}, {"b.txt", 0644}}
+	for _, entry := range expected {
+		header, err := reader.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Name != entry.name || header.Mode != entry.mode {
+			t.Errorf("expected %s with mode %o, got %s with mode %o", entry.name, entry.mode, header.Name, header.Mode)
+		}
+		if !header.ModTime.Equal(modTime) || header.Uid != 0 || header.Gid != 0 || header.Uname != "" || header.Gname != "" {
+			t.Errorf("metadata of %s not normalized: %+v", header.Name, header)
+		}
+	}
+
+	buf.Reset()
+	if err := createZipArchive(files, nopWriteCloser{&buf}, &modTime); err != nil {
+		t.Fatal(err)
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, file := range zipReader.File {
+		if filepath.Base(file.Name) != expected[i].name || int64(file.Mode().Perm()) != expected[i].mode {
+			t.Errorf("expected %s with mode %o, got %s with mode %o", expected[i].name, expected[i].mode, file.Name, file.Mode())
+		}
+		if !file.Modified.Equal(modTime) {
+			t.Errorf("expected %s to be modified at %s, got %s", file.Name, modTime, file.Modified)
+		}
+	}
+}
+
+func TestReproducibleSourceDateEpoch(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"output": "out.tar.gz", "reproducible": true}); err != nil {
+		t.Fatal(err)
+	}
+	if modTime := p.config.reproducibleTime(); modTime == nil || modTime.Unix() != 1700000000 {
+		t.Fatalf("expected SOURCE_DATE_EPOCH to be used, got %v", modTime)
+	}
+
+	p = PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"output": "out.tar.gz", "reproducible": true, "source_date_epoch": 42}); err != nil {
+		t.Fatal(err)
+	}
+	if modTime := p.config.reproducibleTime(); modTime == nil || modTime.Unix() != 42 {
+		t.Fatalf("expected source_date_epoch to be used, got %v", modTime)
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	p = PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"output": "out.tar.gz", "reproducible": true}); err == nil {
+		t.Fatal("expected an invalid SOURCE_DATE_EPOCH to fail")
+	}
+}
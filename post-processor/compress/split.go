@@ -0,0 +1,178 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package compress
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// SplitIndex is written next to the parts of a split archive, as
+// `<output>.index.json`, to verify and reassemble them. The archive is the
+// concatenation of the parts, in order.
+type SplitIndex struct {
+	// The name of the archive once reassembled.
+	Name string `json:"name"`
+	// The size of the archive.
+	Size int64 `json:"size"`
+	// The SHA256 of the archive.
+	SHA256 string `json:"sha256"`
+	// The parts of the archive, in order.
+	Parts []SplitPart `json:"parts"`
+}
+
+type SplitPart struct {
+	// The name of the part, in the directory of the index.
+	Name string `json:"name"`
+	// The offset of the part in the archive.
+	Offset int64 `json:"offset"`
+	// The size of the part.
+	Size int64 `json:"size"`
+	// The SHA256 of the part.
+	SHA256 string `json:"sha256"`
+}
+
+// splitWriter writes the archive stream in volumes of at most size bytes,
+// named after target with a `.000`, `.001`, ... suffix. The index is only
+// written by writeIndex, once the archive is complete.
+type splitWriter struct {
+	target string
+	size   int64
+
+	part     *os.File
+	partHash hash.Hash
+	partSize int64
+	hash     hash.Hash
+	index    SplitIndex
+
+	// paths holds the paths of the parts written, followed by the path of
+	// the index once written.
+	paths []string
+}
+
+func newSplitWriter(target string, size int64) (*splitWriter, error) {
+	w := &splitWriter{
+		target: target,
+		size:   size,
+		hash:   sha256.New(),
+		index:  SplitIndex{Name: filepath.Base(target)},
+	}
+	// An empty archive still has a part, so it can be reassembled.
+	if err := w.nextPart(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *splitWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if w.partSize == w.size {
+			if err := w.nextPart(); err != nil {
+				return written, err
+			}
+		}
+		n := int(min(int64(len(p)), w.size-w.partSize))
+		n, err := w.part.Write(p[:n])
+		w.partHash.Write(p[:n])
+		w.hash.Write(p[:n])
+		w.partSize += int64(n)
+		w.index.Size += int64(n)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// nextPart closes the current part, and creates the next one.
+func (w *splitWriter) nextPart() error {
+	if err := w.closePart(); err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s.%03d", w.target, len(w.index.Parts))
+	part, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w.part = part
+	w.partHash = sha256.New()
+	w.partSize = 0
+	w.paths = append(w.paths, path)
+	return nil
+}
+
+func (w *splitWriter) closePart() error {
+	if w.part == nil {
+		return nil
+	}
+	if err := w.part.Close(); err != nil {
+		return err
+	}
+	w.index.Parts = append(w.index.Parts, SplitPart{
+		Name:   filepath.Base(w.part.Name()),
+		Offset: w.index.Size - w.partSize,
+		Size:   w.partSize,
+		SHA256: hex.EncodeToString(w.partHash.Sum(nil)),
+	})
+	w.part = nil
+	return nil
+}
+
+// Close closes the last part.
+func (w *splitWriter) Close() error {
+	return w.closePart()
+}
+
+// writeIndex writes the index of the parts, once the writer is closed.
+func (w *splitWriter) writeIndex() error {
+	w.index.SHA256 = hex.EncodeToString(w.hash.Sum(nil))
+
+	content, err := json.MarshalIndent(w.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := w.target + ".index.json"
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write the index of the parts: %s", err)
+	}
+	w.paths = append(w.paths, path)
+	return nil
+}
+
+// remove removes the files written, once the writer is closed, when the
+// archive could not be completed.
+func (w *splitWriter) remove() {
+	for _, path := range w.paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("[WARN] Failed to remove %s: %s", path, err)
+		}
+	}
+	w.paths = nil
+}
+
+// closeOnce lets a writer be closed explicitly to check the error, and
+// by a deferred call on error paths.
+type closeOnce struct {
+	io.WriteCloser
+	closed bool
+	err    error
+}
+
+func (c *closeOnce) Close() error {
+	if !c.closed {
+		c.closed = true
+		c.err = c.WriteCloser.Close()
+	}
+	return c.err
+}
@@ -0,0 +1,169 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package compress
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func readIndex(t *testing.T, path string) SplitIndex {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read the index: %s", err)
+	}
+	var index SplitIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		t.Fatalf("malformed index: %s", err)
+	}
+	return index
+}
+
+// reassemble concatenates the parts listed in the index, checking their
+// offset and checksum.
+func reassemble(t *testing.T, dir string, index SplitIndex) []byte {
+	var archive []byte
+	for i, part := range index.Parts {
+		content, err := os.ReadFile(filepath.Join(dir, part.Name))
+		if err != nil {
+			t.Fatalf("failed to read part %d: %s", i, err)
+		}
+		sum := sha256.Sum256(content)
+		if part.Offset != int64(len(archive)) || part.Size != int64(len(content)) || part.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Fatalf("part %d does not match the index: %+v", i, part)
+		}
+		archive = append(archive, content...)
+	}
+	sum := sha256.Sum256(archive)
+	if index.Size != int64(len(archive)) || index.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("the reassembled archive does not match the index: %+v", index)
+	}
+	return archive
+}
+
+func TestSplitWriter(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "disk.raw")
+	input := bytes.Repeat([]byte("0123456789"), 25)
+
+	w, err := newSplitWriter(target, 100)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, chunk := range [][]byte{input[:42], input[42:200], input[200:]} {
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := w.writeIndex(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{target + ".000", target + ".001", target + ".002", target + ".index.json"}
+	if len(w.paths) != len(expected) {
+		t.Fatalf("expected files %q, got %q", expected, w.paths)
+	}
+	for i := range expected {
+		if w.paths[i] != expected[i] {
+			t.Fatalf("expected files %q, got %q", expected, w.paths)
+		}
+	}
+
+	index := readIndex(t, target+".index.json")
+	if index.Name != "disk.raw" || len(index.Parts) != 3 || index.Parts[2].Size != 50 {
+		t.Fatalf("unexpected index %+v", index)
+	}
+	if archive := reassemble(t, dir, index); !bytes.Equal(archive, input) {
+		t.Fatal("the reassembled archive differs from the input")
+	}
+}
+
+func TestCompressSplit(t *testing.T) {
+	const config = `
+	{
+	    "post-processors": [
+	        {
+	            "type": "compress",
+	            "output": "split-package.tar.gz",
+	            "split_size": "64B"
+	        }
+	    ]
+	}
+	`
+
+	artifact := testArchive(t, config)
+	defer func() {
+		if err := artifact.Destroy(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	files := artifact.Files()
+	if len(files) < 3 || files[len(files)-1] != "split-package.tar.gz.index.json" {
+		t.Fatalf("expected several parts and the index, got %q", files)
+	}
+	if _, err := os.Stat("split-package.tar.gz"); !os.IsNotExist(err) {
+		t.Fatalf("the archive should only be written in parts: %v", err)
+	}
+
+	index := readIndex(t, "split-package.tar.gz.index.json")
+	if len(index.Parts) != len(files)-1 {
+		t.Fatalf("the index lists %d parts, the artifact %d", len(index.Parts), len(files)-1)
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(reassemble(t, ".", index)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+	if _, err := tarReader.Next(); err != nil {
+		t.Fatal(err)
+	}
+	found, err := io.ReadAll(tarReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(found) != expectedFileContents {
+		t.Errorf("Expected:\n%s\nFound:\n%s\n", expectedFileContents, string(found))
+	}
+}
+
+func TestCompressSplit_failure(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "disk.raw.gz")
+
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{
+		"output":     target,
+		"split_size": "64B",
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	artifact := &packersdk.MockArtifact{FilesValue: []string{filepath.Join(dir, "missing.raw")}}
+	if _, _, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), artifact); err == nil {
+		t.Fatal("should have errored")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("the parts and index of the failed archive should be removed, found %v", entries)
+	}
+}
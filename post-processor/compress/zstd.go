@@ -0,0 +1,174 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package compress
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// zstdLongWindowSize is the window used with zstd_long_distance_matching,
+	// the one of `zstd --long=27`, which zstd decompresses without options.
+	zstdLongWindowSize = 1 << 27
+
+	// Magic numbers of the seek table of the zstd seekable format, see
+	// https://github.com/facebook/zstd/blob/dev/contrib/seekable_format/zstd_seekable_compression_format.md
+	zstdSkippableFrameMagic = 0x184D2A5E
+	zstdSeekableMagic       = 0x8F92EAB1
+	// zstdSeekTableChecksumFlag is set in the seek table descriptor when the
+	// entries hold a checksum of the decompressed frames.
+	zstdSeekTableChecksumFlag = 1 << 7
+	// zstdMaxSeekableFrameSize keeps frame sizes within the 32 bits of the
+	// seek table entries, with room for incompressible data.
+	zstdMaxSeekableFrameSize = 1 << 30
+)
+
+// zstdEncoderLevel maps compression_level, from 1 to 9 like gzip, to one of
+// the zstd encoder levels.
+func zstdEncoderLevel(compressionLevel int) zstd.EncoderLevel {
+	switch {
+	case compressionLevel < 0:
+		return zstd.SpeedDefault
+	case compressionLevel <= 2:
+		return zstd.SpeedFastest
+	case compressionLevel <= 5:
+		return zstd.SpeedDefault
+	case compressionLevel <= 8:
+		return zstd.SpeedBetterCompression
+	default:
+		return zstd.SpeedBestCompression
+	}
+}
+
+func makeZstdWriter(output io.Writer, compressionLevel int, concurrency int, long bool) (io.WriteCloser, error) {
+	opts := []zstd.EOption{
+		zstd.WithEncoderLevel(zstdEncoderLevel(compressionLevel)),
+		zstd.WithEncoderConcurrency(concurrency),
+	}
+	if long {
+		opts = append(opts, zstd.WithWindowSize(zstdLongWindowSize))
+	}
+	return zstd.NewWriter(output, opts...)
+}
+
+func makeZstdSeekableWriter(output io.Writer, compressionLevel int, concurrency int, frameSize int) (io.WriteCloser, error) {
+	// Frames are compressed concurrently with EncodeAll, the encoder
+	// concurrency only applies to streams.
+	encoder, err := zstd.NewWriter(nil,
+		zstd.WithEncoderLevel(zstdEncoderLevel(compressionLevel)),
+		zstd.WithEncoderConcurrency(concurrency))
+	if err != nil {
+		return nil, err
+	}
+	return &zstdSeekableWriter{
+		output:      output,
+		encoder:     encoder,
+		frameSize:   frameSize,
+		concurrency: concurrency,
+	}, nil
+}
+
+// zstdSeekableWriter writes the zstd seekable format: the input is split in
+// independent frames of frameSize bytes, followed by a seek table mapping
+// decompressed offsets to frames, so consumers can read any part of the
+// input without decompressing what comes before.
+type zstdSeekableWriter struct {
+	output      io.Writer
+	encoder     *zstd.Encoder
+	frameSize   int
+	concurrency int
+
+	// buf holds the input not written in a frame yet.
+	buf     []byte
+	entries []zstdSeekTableEntry
+}
+
+type zstdSeekTableEntry struct {
+	compressedSize   uint32
+	decompressedSize uint32
+	checksum         uint32
+}
+
+func (w *zstdSeekableWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.frameSize*w.concurrency {
+		frames := len(w.buf) / w.frameSize
+		if err := w.writeFrames(w.buf[:frames*w.frameSize]); err != nil {
+			return 0, err
+		}
+		w.buf = append(w.buf[:0], w.buf[frames*w.frameSize:]...)
+	}
+	return len(p), nil
+}
+
+// writeFrames compresses data in frames of frameSize bytes, concurrently, and
+// writes them in order.
+func (w *zstdSeekableWriter) writeFrames(data []byte) error {
+	var chunks [][]byte
+	for len(data) > 0 {
+		n := min(w.frameSize, len(data))
+		chunks = append(chunks, data[:n])
+		data = data[n:]
+	}
+
+	frames := make([][]byte, len(chunks))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, w.concurrency)
+	for i, chunk := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, chunk []byte) {
+			defer wg.Done()
+			frames[i] = w.encoder.EncodeAll(chunk, nil)
+			<-sem
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for i, frame := range frames {
+		if _, err := w.output.Write(frame); err != nil {
+			return err
+		}
+		w.entries = append(w.entries, zstdSeekTableEntry{
+			compressedSize:   uint32(len(frame)),
+			decompressedSize: uint32(len(chunks[i])),
+			checksum:         uint32(xxhash.Sum64(chunks[i])),
+		})
+	}
+	return nil
+}
+
+// Close writes the remaining input and the seek table.
+func (w *zstdSeekableWriter) Close() error {
+	defer w.encoder.Close()
+
+	if err := w.writeFrames(w.buf); err != nil {
+		return err
+	}
+	w.buf = nil
+
+	const entrySize, footerSize = 12, 9
+	table := make([]byte, 8, 8+len(w.entries)*entrySize+footerSize)
+	binary.LittleEndian.PutUint32(table[0:], zstdSkippableFrameMagic)
+	binary.LittleEndian.PutUint32(table[4:], uint32(len(w.entries)*entrySize+footerSize))
+	for _, entry := range w.entries {
+		table = binary.LittleEndian.AppendUint32(table, entry.compressedSize)
+		table = binary.LittleEndian.AppendUint32(table, entry.decompressedSize)
+		table = binary.LittleEndian.AppendUint32(table, entry.checksum)
+	}
+	table = binary.LittleEndian.AppendUint32(table, uint32(len(w.entries)))
+	table = append(table, zstdSeekTableChecksumFlag)
+	table = binary.LittleEndian.AppendUint32(table, zstdSeekableMagic)
+
+	if _, err := w.output.Write(table); err != nil {
+		return fmt.Errorf("failed to write the seek table: %s", err)
+	}
+	return nil
+}
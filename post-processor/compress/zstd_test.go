@@ -0,0 +1,110 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestZstdSeekableWriter(t *testing.T) {
+	// Compressible data spanning several frames, the last one partial.
+	input := make([]byte, 10*1024+123)
+	rng := rand.New(rand.NewSource(1))
+	for i := range input {
+		input[i] = byte('a' + rng.Intn(4))
+	}
+
+	var compressed bytes.Buffer
+	w, err := makeZstdSeekableWriter(&compressed, 9, 2, 1024)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	// Write in odd chunks, so frames are cut from several writes.
+	for data := input; len(data) > 0; {
+		n := min(700, len(data))
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		data = data[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Any decoder reads the whole stream, skipping the seek table.
+	decoder, err := zstd.NewReader(bytes.NewReader(compressed.Bytes()))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer decoder.Close()
+	decompressed, err := io.ReadAll(decoder)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(decompressed, input) {
+		t.Fatal("decompressed data differs from the input")
+	}
+
+	// The footer of the seek table locates the frames.
+	data := compressed.Bytes()
+	footer := data[len(data)-9:]
+	if magic := binary.LittleEndian.Uint32(footer[5:]); magic != zstdSeekableMagic {
+		t.Fatalf("unexpected seekable magic %x", magic)
+	}
+	if footer[4] != zstdSeekTableChecksumFlag {
+		t.Fatalf("unexpected descriptor %x", footer[4])
+	}
+	frames := int(binary.LittleEndian.Uint32(footer))
+	if frames != 11 {
+		t.Fatalf("expected 11 frames, got %d", frames)
+	}
+
+	entries := data[len(data)-9-frames*12 : len(data)-9]
+	offset := 0
+	for i := 0; i < frames; i++ {
+		compressedSize := int(binary.LittleEndian.Uint32(entries[i*12:]))
+		decompressedSize := int(binary.LittleEndian.Uint32(entries[i*12+4:]))
+
+		// Each frame decompresses independently.
+		frame, err := decoder.DecodeAll(data[offset:offset+compressedSize], nil)
+		if err != nil {
+			t.Fatalf("frame %d: %s", i, err)
+		}
+		start := i * 1024
+		if len(frame) != decompressedSize || !bytes.Equal(frame, input[start:start+decompressedSize]) {
+			t.Fatalf("frame %d does not match the input", i)
+		}
+		offset += compressedSize
+	}
+}
+
+func TestCompressConfigure_zstdOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"seekable", map[string]interface{}{"output": "disk.raw.zst", "zstd_seekable": true, "zstd_seekable_frame_size": "4MiB"}, false},
+		{"long", map[string]interface{}{"output": "disk.tar.zst", "zstd_long_distance_matching": true}, false},
+		{"not zstd", map[string]interface{}{"output": "disk.raw.gz", "zstd_seekable": true}, true},
+		{"long and seekable", map[string]interface{}{"output": "disk.raw.zst", "zstd_seekable": true, "zstd_long_distance_matching": true}, true},
+		{"invalid frame size", map[string]interface{}{"output": "disk.raw.zst", "zstd_seekable_frame_size": "huge"}, true},
+		{"frame size too large", map[string]interface{}{"output": "disk.raw.zst", "zstd_seekable_frame_size": "2GiB"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p PostProcessor
+			err := p.Configure(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Configure() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
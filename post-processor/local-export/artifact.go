@@ -0,0 +1,59 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package local_export
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const BuilderId = "packer.post-processor.local-export"
+
+// Artifact holds the exported files, and the state of the input artifact.
+type Artifact struct {
+	files []string
+	// sources holds the file each of files was exported from.
+	sources []string
+	source  packersdk.Artifact
+	// backups maps the overwritten files to the name they are renamed to
+	// while the files are exported.
+	backups map[string]string
+}
+
+func (a *Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) Files() []string {
+	return a.files
+}
+
+// Id returns the ID of the input artifact.
+func (a *Artifact) Id() string {
+	return a.source.Id()
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("Exported files: %s", strings.Join(a.files, ", "))
+}
+
+// State returns the state of the input artifact, like its generated data.
+func (a *Artifact) State(name string) interface{} {
+	return a.source.State(name)
+}
+
+// Destroy removes the exported files.
+func (a *Artifact) Destroy() error {
+	var errs []error
+	for _, f := range a.files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,391 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package local_export
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+const (
+	ModeCopy     = "copy"
+	ModeHardlink = "hardlink"
+	ModeMove     = "move"
+
+	ConflictFail      = "fail"
+	ConflictOverwrite = "overwrite"
+	ConflictSuffix    = "suffix"
+)
+
+// The local-export post-processor copies, hard-links or moves the files of
+// an artifact to a destination directory, and returns an artifact made of
+// the exported files.
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The directory to export the files to, created if needed. This is a
+	// template with the generated data of the build, `BuildName` and
+	// `BuilderType`, like `exports/{{.BuildName}}/{{timestamp}}`.
+	Destination string `mapstructure:"destination" required:"true"`
+	// The name of the exported files. This is a template with the same data
+	// as `destination`, and the `FileName`, `FileBase` and `FileExt` of the
+	// exported file, like `{{.FileBase}}-{{user "version"}}{{.FileExt}}`.
+	// Defaults to `{{.FileName}}`.
+	FileName string `mapstructure:"file_name"`
+	// How to export the files: `copy`, the default, `hardlink` or `move`.
+	// Moving a file across file systems copies it and removes the
+	// original.
+	Mode string `mapstructure:"mode"`
+	// What to do when an exported file already exists: `fail`, the default,
+	// `overwrite`, or `suffix` to add `-1`, `-2`, ... to the name of the
+	// exported file. Overwritten files are put back when the export fails.
+	OnConflict string `mapstructure:"on_conflict"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         "local-export",
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{"destination", "file_name"},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if p.config.Destination == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("destination is required"))
+	} else if err := interpolate.Validate(p.config.Destination, &p.config.ctx); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error parsing destination template: %s", err))
+	}
+
+	if p.config.FileName == "" {
+		p.config.FileName = "{{.FileName}}"
+	}
+	if err := interpolate.Validate(p.config.FileName, &p.config.ctx); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error parsing file_name template: %s", err))
+	}
+
+	if p.config.Mode == "" {
+		p.config.Mode = ModeCopy
+	}
+	switch p.config.Mode {
+	case ModeCopy, ModeHardlink, ModeMove:
+	default:
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Unrecognized mode %q, expected %q, %q or %q",
+			p.config.Mode, ModeCopy, ModeHardlink, ModeMove))
+	}
+
+	if p.config.OnConflict == "" {
+		p.config.OnConflict = ConflictFail
+	}
+	switch p.config.OnConflict {
+	case ConflictFail, ConflictOverwrite, ConflictSuffix:
+	default:
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Unrecognized on_conflict %q, expected %q, %q or %q",
+			p.config.OnConflict, ConflictFail, ConflictOverwrite, ConflictSuffix))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	// The generated data is copied so that the variables added for
+	// interpolation don't end up in the state passed to the next
+	// post-processors.
+	generatedData := map[interface{}]interface{}{}
+	if stateData, ok := source.State("generated_data").(map[interface{}]interface{}); ok {
+		maps.Copy(generatedData, stateData)
+	}
+
+	// These are extra variables that will be made available for interpolation.
+	generatedData["BuildName"] = p.config.PackerBuildName
+	generatedData["BuilderType"] = p.config.PackerBuilderType
+	p.config.ctx.Data = generatedData
+
+	destination, err := interpolate.Render(p.config.Destination, &p.config.ctx)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error interpolating destination: %s", err)
+	}
+	if err := os.MkdirAll(destination, 0755); err != nil {
+		return nil, false, false, fmt.Errorf("Unable to create destination %s: %s", destination, err)
+	}
+
+	artifact := &Artifact{source: source, backups: map[string]string{}}
+	exported := map[string]bool{}
+	for _, src := range source.Files() {
+		name := filepath.Base(src)
+		generatedData["FileName"] = name
+		generatedData["FileExt"] = filepath.Ext(name)
+		generatedData["FileBase"] = strings.TrimSuffix(name, filepath.Ext(name))
+		name, err := interpolate.Render(p.config.FileName, &p.config.ctx)
+		if err != nil {
+			return nil, false, false, p.rollback(artifact, fmt.Errorf("Error interpolating file_name for %s: %s", src, err))
+		}
+
+		dst, err := p.target(filepath.Join(destination, name), exported)
+		if err != nil {
+			return nil, false, false, p.rollback(artifact, err)
+		}
+		backup, err := p.export(src, dst)
+		if backup != "" {
+			artifact.backups[dst] = backup
+		}
+		if err != nil {
+			return nil, false, false, p.rollback(artifact, err)
+		}
+		exported[dst] = true
+		artifact.files = append(artifact.files, dst)
+		artifact.sources = append(artifact.sources, src)
+		ui.Say(fmt.Sprintf("Exported %s to %s", src, dst))
+	}
+
+	// All the files are exported, the overwritten files are not needed
+	// to roll back anymore.
+	for dst, backup := range artifact.backups {
+		if err := os.Remove(backup); err != nil {
+			log.Printf("Unable to remove %s, the previous version of %s: %s", backup, dst, err)
+		}
+	}
+	artifact.backups = nil
+
+	// Moved files are gone, the input artifact is kept so that it isn't
+	// destroyed a second time.
+	if p.config.Mode == ModeMove {
+		return artifact, true, true, nil
+	}
+	return artifact, true, false, nil
+}
+
+// target returns where to export a file to dst, following the conflict
+// policy. exported holds the files already exported by this build, which
+// are never overwritten.
+func (p *PostProcessor) target(dst string, exported map[string]bool) (string, error) {
+	if _, err := os.Lstat(dst); os.IsNotExist(err) && !exported[dst] {
+		return dst, nil
+	}
+	switch {
+	case p.config.OnConflict == ConflictOverwrite && !exported[dst]:
+		return dst, nil
+	case p.config.OnConflict == ConflictSuffix:
+		ext := filepath.Ext(dst)
+		base := strings.TrimSuffix(dst, ext)
+		for i := 1; ; i++ {
+			candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+			if _, err := os.Lstat(candidate); os.IsNotExist(err) && !exported[candidate] {
+				return candidate, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("Unable to export to %s: the file already exists", dst)
+}
+
+// export copies, links or moves src to dst, and checks the result. A file
+// overwritten at dst is first renamed to the returned backup, so that it can
+// be put back if the export is rolled back.
+func (p *PostProcessor) export(src, dst string) (string, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read %s: %s", src, err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("Unable to export %s: not a regular file", src)
+	}
+	if existing, err := os.Stat(dst); err == nil && os.SameFile(info, existing) {
+		return "", fmt.Errorf("Unable to export %s: it is already at the destination", src)
+	}
+
+	var backup string
+	if _, err := os.Lstat(dst); err == nil {
+		backup = backupName(dst)
+		if err := os.Rename(dst, backup); err != nil {
+			return "", fmt.Errorf("Unable to overwrite %s: %s", dst, err)
+		}
+	}
+	return backup, p.transfer(src, dst, info)
+}
+
+// transfer copies, links or moves src to dst, following the mode.
+func (p *PostProcessor) transfer(src, dst string, info os.FileInfo) error {
+
+	switch p.config.Mode {
+	case ModeHardlink:
+		// Link to a temporary name, renamed once linked like copies.
+		tmp := tempName(dst)
+		if err := os.Link(src, tmp); err != nil {
+			return fmt.Errorf("Unable to link %s to %s: %s", src, dst, err)
+		}
+		if err := os.Rename(tmp, dst); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("Unable to link %s to %s: %s", src, dst, err)
+		}
+		linked, err := os.Stat(dst)
+		if err != nil || !os.SameFile(info, linked) {
+			return fmt.Errorf("Unable to link %s to %s: the destination is another file", src, dst)
+		}
+		return nil
+	case ModeMove:
+		return moveFile(src, dst, info)
+	default:
+		return copyFile(src, dst, info)
+	}
+}
+
+// moveFile renames src to dst, or copies it when renaming fails, like across
+// file systems, and removes it once copied.
+func moveFile(src, dst string, info os.FileInfo) error {
+	if err := os.Rename(src, dst); err == nil {
+		return verifySize(dst, info.Size())
+	} else {
+		log.Printf("Unable to rename %s to %s, copying it: %s", src, dst, err)
+	}
+	if err := copyFile(src, dst, info); err != nil {
+		return err
+	}
+	if err := os.Remove(src); err != nil {
+		return fmt.Errorf("Unable to remove %s once copied: %s", src, err)
+	}
+	return nil
+}
+
+// copyFile copies src to a temporary file next to dst, checks its size and
+// checksum, and renames it to dst.
+func copyFile(src, dst string, info os.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("Unable to read %s: %s", src, err)
+	}
+	defer in.Close()
+
+	tmp := tempName(dst)
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("Unable to create %s: %s", dst, err)
+	}
+	defer os.Remove(tmp)
+
+	srcHash := sha256.New()
+	_, err = io.Copy(out, io.TeeReader(in, srcHash))
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to copy %s to %s: %s", src, dst, err)
+	}
+
+	if err := verifySize(tmp, info.Size()); err != nil {
+		return err
+	}
+	dstSum, err := fileSHA256(tmp)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(srcHash.Sum(nil), dstSum) {
+		return fmt.Errorf("Unable to copy %s to %s: the checksum of the copy differs", src, dst)
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		return fmt.Errorf("Unable to copy %s to %s: %s", src, dst, err)
+	}
+	return nil
+}
+
+func verifySize(path string, size int64) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("Unable to check %s: %s", path, err)
+	}
+	if info.Size() != size {
+		return fmt.Errorf("Unable to export %s: expected %d bytes, found %d", path, size, info.Size())
+	}
+	return nil
+}
+
+func fileSHA256(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to check %s: %s", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("Unable to check %s: %s", path, err)
+	}
+	return h.Sum(nil), nil
+}
+
+// tempName returns a name next to path for files renamed to path once
+// complete.
+func tempName(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+}
+
+// backupName returns the name an overwritten file is renamed to until all
+// the files are exported.
+func backupName(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".backup")
+}
+
+// rollback removes the files exported before err, or moves them back to
+// where they were moved from, and puts back the files they overwrote.
+func (p *PostProcessor) rollback(artifact *Artifact, err error) error {
+	if p.config.Mode != ModeMove {
+		if destroyErr := artifact.Destroy(); destroyErr != nil {
+			log.Printf("Unable to remove the files exported before the error: %s", destroyErr)
+		}
+	} else {
+		for i, dst := range artifact.files {
+			src := artifact.sources[i]
+			info, moveErr := os.Stat(dst)
+			if moveErr == nil {
+				moveErr = moveFile(dst, src, info)
+			}
+			if moveErr != nil {
+				log.Printf("Unable to move %s back to %s after the error: %s", dst, src, moveErr)
+			}
+		}
+	}
+
+	for dst, backup := range artifact.backups {
+		if restoreErr := os.Rename(backup, dst); restoreErr != nil {
+			log.Printf("Unable to put back %s, the previous version of %s: %s", backup, dst, restoreErr)
+		}
+	}
+	return err
+}
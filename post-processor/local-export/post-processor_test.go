@@ -0,0 +1,255 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package local_export
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) []string {
+	var paths []string
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func testExport(t *testing.T, config map[string]interface{}, files []string) (packersdk.Artifact, bool, error) {
+	var p PostProcessor
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	source := &packersdk.MockArtifact{
+		IdValue:     "image-1234",
+		FilesValue:  files,
+		StateValues: map[string]interface{}{"generated_data": map[interface{}]interface{}{"ImageID": "1234"}},
+	}
+	artifact, _, forceOverride, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source)
+	return artifact, forceOverride, err
+}
+
+func assertContent(t *testing.T, path, expected string) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != expected {
+		t.Fatalf("expected %q in %s, got %q", expected, path, content)
+	}
+}
+
+func TestLocalExportModes(t *testing.T) {
+	for _, mode := range []string{ModeCopy, ModeHardlink, ModeMove} {
+		t.Run(mode, func(t *testing.T) {
+			src, dst := t.TempDir(), t.TempDir()
+			files := writeFiles(t, src, map[string]string{"image.raw": "Hello world!"})
+
+			artifact, forceOverride, err := testExport(t, map[string]interface{}{
+				"destination":       filepath.Join(dst, "{{.BuildName}}-{{.ImageID}}"),
+				"mode":              mode,
+				"packer_build_name": "vm",
+			}, files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			exported := filepath.Join(dst, "vm-1234", "image.raw")
+			if len(artifact.Files()) != 1 || artifact.Files()[0] != exported {
+				t.Fatalf("unexpected files: %v", artifact.Files())
+			}
+			assertContent(t, exported, "Hello world!")
+			info, err := os.Stat(exported)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0640 {
+				t.Errorf("expected the mode of the source, got %s", info.Mode())
+			}
+
+			_, err = os.Stat(files[0])
+			if moved := os.IsNotExist(err); moved != (mode == ModeMove) {
+				t.Errorf("unexpected source after %s: %v", mode, err)
+			}
+			if forceOverride != (mode == ModeMove) {
+				t.Errorf("expected forceOverride only when moving files")
+			}
+			if artifact.Id() != "image-1234" {
+				t.Errorf("expected the ID of the input artifact, got %q", artifact.Id())
+			}
+			generatedData, _ := artifact.State("generated_data").(map[interface{}]interface{})
+			if len(generatedData) != 1 || generatedData["ImageID"] != "1234" {
+				t.Errorf("expected the generated data of the input artifact, got %v", generatedData)
+			}
+
+			if err := artifact.Destroy(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(exported); !os.IsNotExist(err) {
+				t.Errorf("expected Destroy to remove the exported file: %v", err)
+			}
+		})
+	}
+}
+
+func TestLocalExportFileName(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	files := writeFiles(t, src, map[string]string{"disk.vmdk": "disk"})
+
+	artifact, _, err := testExport(t, map[string]interface{}{
+		"destination":           dst,
+		"file_name":             `{{.FileBase}}-{{user "version"}}{{.FileExt}}`,
+		"packer_user_variables": map[string]string{"version": "1.2.0"},
+	}, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := filepath.Join(dst, "disk-1.2.0.vmdk")
+	if artifact.Files()[0] != expected {
+		t.Fatalf("expected %s, got %v", expected, artifact.Files())
+	}
+	assertContent(t, expected, "disk")
+}
+
+func TestLocalExportConflicts(t *testing.T) {
+	cases := []struct {
+		onConflict string
+		fail       bool
+		exported   string
+	}{
+		{ConflictFail, true, ""},
+		{ConflictOverwrite, false, "image.raw"},
+		{ConflictSuffix, false, "image-2.raw"},
+	}
+	for _, c := range cases {
+		t.Run(c.onConflict, func(t *testing.T) {
+			src, dst := t.TempDir(), t.TempDir()
+			files := writeFiles(t, src, map[string]string{"image.raw": "new"})
+			writeFiles(t, dst, map[string]string{"image.raw": "old", "image-1.raw": "old"})
+
+			artifact, _, err := testExport(t, map[string]interface{}{
+				"destination": dst,
+				"on_conflict": c.onConflict,
+			}, files)
+			if c.fail {
+				if err == nil {
+					t.Fatal("expected an existing file to fail the export")
+				}
+				assertContent(t, filepath.Join(dst, "image.raw"), "old")
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if artifact.Files()[0] != filepath.Join(dst, c.exported) {
+				t.Fatalf("expected %s, got %v", c.exported, artifact.Files())
+			}
+			assertContent(t, artifact.Files()[0], "new")
+			if _, err := os.Stat(backupName(filepath.Join(dst, "image.raw"))); !os.IsNotExist(err) {
+				t.Errorf("expected the overwritten file to be removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestLocalExportSameNameRollback(t *testing.T) {
+	dst := t.TempDir()
+	first := writeFiles(t, t.TempDir(), map[string]string{"image.raw": "first"})
+	second := writeFiles(t, t.TempDir(), map[string]string{"image.raw": "second"})
+
+	// Two files exported to the same name in one build never overwrite
+	// each other, and the files already exported are removed.
+	_, _, err := testExport(t, map[string]interface{}{
+		"destination": dst,
+		"on_conflict": ConflictOverwrite,
+	}, append(first, second...))
+	if err == nil {
+		t.Fatal("expected files exported to the same name to fail")
+	}
+	entries, err := os.ReadDir(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected the exported files to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestLocalExportOverwriteRollback(t *testing.T) {
+	for _, mode := range []string{ModeCopy, ModeHardlink, ModeMove} {
+		t.Run(mode, func(t *testing.T) {
+			dst := t.TempDir()
+			writeFiles(t, dst, map[string]string{"image.raw": "old"})
+			first := writeFiles(t, t.TempDir(), map[string]string{"image.raw": "first"})
+			second := writeFiles(t, t.TempDir(), map[string]string{"image.raw": "second"})
+
+			// The files overwritten before the error are put back.
+			_, _, err := testExport(t, map[string]interface{}{
+				"destination": dst,
+				"mode":        mode,
+				"on_conflict": ConflictOverwrite,
+			}, append(first, second...))
+			if err == nil {
+				t.Fatal("expected files exported to the same name to fail")
+			}
+			assertContent(t, filepath.Join(dst, "image.raw"), "old")
+			assertContent(t, first[0], "first")
+			entries, err := os.ReadDir(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected only the overwritten file, found %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestLocalExportMoveRollback(t *testing.T) {
+	dst := t.TempDir()
+	first := writeFiles(t, t.TempDir(), map[string]string{"image.raw": "first"})
+	second := writeFiles(t, t.TempDir(), map[string]string{"image.raw": "second"})
+
+	// The files already moved are moved back on error.
+	_, _, err := testExport(t, map[string]interface{}{
+		"destination": dst,
+		"mode":        ModeMove,
+	}, append(first, second...))
+	if err == nil {
+		t.Fatal("expected files exported to the same name to fail")
+	}
+	assertContent(t, first[0], "first")
+	assertContent(t, second[0], "second")
+	entries, err := os.ReadDir(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected the moved files to be moved back, found %d entries", len(entries))
+	}
+}
+
+func TestLocalExportConfigure(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"missing destination": {},
+		"bad mode":            {"destination": "out", "mode": "symlink"},
+		"bad on_conflict":     {"destination": "out", "on_conflict": "skip"},
+		"bad template":        {"destination": "{{.BuildName"},
+	}
+	for name, config := range cases {
+		var p PostProcessor
+		if err := p.Configure(config); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -10,6 +10,8 @@ const BuilderId = "packer.post-processor.manifest"
 type ArtifactFile struct {
 	Name string `json:"name"`
 	Size int64  `json:"size"`
+	// SHA256 is the hex encoded SHA256 of regular files.
+	SHA256 string `json:"sha256,omitempty"`
 }
 
 type Artifact struct {
@@ -20,6 +22,36 @@ type Artifact struct {
 	ArtifactId    string            `json:"artifact_id"`
 	PackerRunUUID string            `json:"packer_run_uuid"`
 	CustomData    map[string]string `json:"custom_data"`
+	// Status is the outcome of the build: `success`, `failed` or
+	// `cancelled`. Failed and cancelled builds have no files nor artifact ID.
+	Status string `json:"status,omitempty"`
+	// Error is why the build failed or was cancelled.
+	Error string `json:"error,omitempty"`
+	// Duration is the time from the start of the build to its manifest
+	// entry, in seconds.
+	Duration float64 `json:"duration_seconds,omitempty"`
+	// PackerVersion is the version of Packer that ran the build.
+	PackerVersion string `json:"packer_version,omitempty"`
+	// Plugins holds the version of the plugins used by the build, by name.
+	Plugins map[string]string `json:"plugins,omitempty"`
+	// PluginResources holds the resources used by the plugins of the build,
+	// by name, when Packer samples them.
+	PluginResources map[string]PluginResources `json:"plugin_resources,omitempty"`
+	// GeneratedData is the data generated by the builder, without
+	// credentials.
+	GeneratedData map[string]string `json:"generated_data,omitempty"`
+	// StateData holds the artifact state keys listed in include_state.
+	StateData map[string]interface{} `json:"state,omitempty"`
+}
+
+type PluginResources struct {
+	// PeakRSS is the highest resident set size of the plugin processes, in
+	// bytes.
+	PeakRSS int64 `json:"peak_rss_bytes"`
+	// CPUSeconds is the CPU time used by the plugin processes.
+	CPUSeconds float64 `json:"cpu_seconds"`
+	// Samples is the number of samples taken.
+	Samples int64 `json:"samples"`
 }
 
 func (a *Artifact) BuilderId() string {
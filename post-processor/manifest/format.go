@@ -0,0 +1,213 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	// FormatEnv writes `KEY=value` lines, see envVars.
+	FormatEnv = "env"
+)
+
+// envRunUUIDKey holds the run UUID in env manifests, like last_run_uuid.
+const envRunUUIDKey = "PACKER_RUN_UUID"
+
+// decodeManifest parses a json or yaml manifest file.
+func decodeManifest(format string, contents []byte, manifestFile *ManifestFile) error {
+	if format != FormatYAML {
+		return json.Unmarshal(contents, manifestFile)
+	}
+
+	// YAML manifests use the same fields as JSON ones, converting them
+	// to JSON lets them share the struct tags.
+	var value interface{}
+	if err := yaml.Unmarshal(contents, &value); err != nil {
+		return err
+	}
+	contents, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(contents, manifestFile)
+}
+
+// encodeManifest formats a json or yaml manifest file.
+func encodeManifest(format string, manifestFile *ManifestFile) ([]byte, error) {
+	out, err := json.MarshalIndent(manifestFile, "", "  ")
+	if err != nil || format != FormatYAML {
+		return out, err
+	}
+
+	// Going through a node keeps the order of the fields.
+	var node yaml.Node
+	if err := yaml.Unmarshal(out, &node); err != nil {
+		return nil, err
+	}
+	resetStyle(&node)
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// resetStyle drops the JSON flow style and quoting of the nodes read from
+// JSON, for block style YAML. Strings that would be read back as another
+// type are still quoted.
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+// decodeEnv parses the `KEY=value` lines of an env manifest, as written by
+// encodeEnv.
+func decodeEnv(contents []byte) (map[string]string, error) {
+	vars := map[string]string{}
+	rest := string(contents)
+	for line := 1; rest != ""; line++ {
+		var text string
+		text, rest, _ = strings.Cut(rest, "\n")
+		text = strings.TrimLeft(text, " \t")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=value", line)
+		}
+		if !strings.HasPrefix(value, "'") {
+			vars[key] = strings.TrimRight(value, " \t\r")
+			continue
+		}
+
+		// Quoted values can hold new lines, so they are read from the
+		// start of the value up to the end of the line of their closing
+		// quote.
+		unquoted, remainder, err := unquoteEnv(value + "\n" + rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		line += strings.Count(unquoted, "\n")
+		rest = remainder
+		vars[key] = unquoted
+	}
+	return vars, nil
+}
+
+// unquoteEnv reads a value quoted by quoteEnv at the start of s, and returns
+// it with what follows the end of its line.
+func unquoteEnv(s string) (string, string, error) {
+	var value strings.Builder
+	for {
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated quoted value")
+		}
+		value.WriteString(s[1 : end+1])
+		s = s[end+2:]
+		if !strings.HasPrefix(s, `\''`) {
+			break
+		}
+		value.WriteByte('\'')
+		s = s[2:]
+	}
+
+	trailing, rest, _ := strings.Cut(s, "\n")
+	if strings.TrimSpace(trailing) != "" {
+		return "", "", fmt.Errorf("unexpected %q after quoted value", trailing)
+	}
+	return value.String(), rest, nil
+}
+
+var safeEnvValue = regexp.MustCompile(`^[A-Za-z0-9_./:,@+-]*$`)
+
+// encodeEnv formats vars as sorted `KEY=value` lines, quoting values that
+// shells would not read as is.
+func encodeEnv(vars map[string]string) []byte {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, key := range keys {
+		value := vars[key]
+		if !safeEnvValue.MatchString(value) {
+			value = quoteEnv(value)
+		}
+		fmt.Fprintf(&buf, "%s=%s\n", key, value)
+	}
+	return buf.Bytes()
+}
+
+// quoteEnv single quotes value, so that shells do not expand `$`, backticks
+// or backslashes in it. Each single quote of value closes the quoted string,
+// is escaped with a backslash, and opens a new quoted string.
+func quoteEnv(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+var envKeyInvalidChars = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+// envKey joins parts in an upper case variable name.
+func envKey(parts ...string) string {
+	return envKeyInvalidChars.ReplaceAllString(strings.ToUpper(strings.Join(parts, "_")), "_")
+}
+
+// envVars flattens the entry of a build in variables prefixed with the
+// name of the build:
+//
+//   - <NAME>_ARTIFACT_ID, <NAME>_BUILDER_TYPE and <NAME>_BUILD_TIME.
+//   - <NAME>_<KEY> for artifact IDs in the `key:value,key:value` format used
+//     by builders creating an artifact per region, for example
+//     AMI_US_EAST_1=ami-123 for a build named `ami`.
+//   - <NAME>_<KEY> for custom_data and include_state keys. State values
+//     that are not strings are JSON encoded.
+func envVars(artifact *Artifact) map[string]string {
+	vars := map[string]string{
+		envKey(artifact.BuildName, "artifact_id"):  artifact.ArtifactId,
+		envKey(artifact.BuildName, "builder_type"): artifact.BuilderType,
+	}
+	if artifact.BuildTime != 0 {
+		vars[envKey(artifact.BuildName, "build_time")] = strconv.FormatInt(artifact.BuildTime, 10)
+	}
+	if strings.Contains(artifact.ArtifactId, ":") {
+		for _, part := range strings.Split(artifact.ArtifactId, ",") {
+			if key, value, ok := strings.Cut(part, ":"); ok {
+				vars[envKey(artifact.BuildName, key)] = value
+			}
+		}
+	}
+	for key, value := range artifact.CustomData {
+		vars[envKey(artifact.BuildName, key)] = value
+	}
+	for key, value := range artifact.StateData {
+		if s, ok := value.(string); ok {
+			vars[envKey(artifact.BuildName, key)] = s
+		} else if out, err := json.Marshal(value); err == nil {
+			vars[envKey(artifact.BuildName, key)] = string(out)
+		}
+	}
+	return vars
+}
@@ -8,13 +8,17 @@ package manifest
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/gofrs/flock"
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/common"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
@@ -26,8 +30,21 @@ type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
 	// The manifest will be written to this file. This defaults to
-	// `packer-manifest.json`.
+	// `packer-manifest.json`, or `packer-manifest.yaml` and
+	// `packer-manifest.env` for these formats.
 	OutputPath string `mapstructure:"output"`
+	// The format of the manifest: `json`, the default, `yaml`, with the same
+	// fields, or `env`, `KEY=value` lines flattening the entries of the builds,
+	// like `AMI_US_EAST_1=ami-123` for the `us-east-1:ami-123` artifact of a
+	// build named `ami`. Env manifests hold the latest value of each key
+	// rather than a history of the builds, failed and cancelled builds are
+	// not recorded there.
+	Format string `mapstructure:"format"`
+	// The artifact state keys to copy to the entry of the build, under
+	// `state`, like `atlas.artifact.metadata` for some builders. Like the
+	// recorded generated data, the state is written without the
+	// credentials builders generate and the values of sensitive variables.
+	IncludeState []string `mapstructure:"include_state"`
 	// Write only filename without the path to the manifest file. This defaults
 	// to false.
 	StripPath bool `mapstructure:"strip_path"`
@@ -37,16 +54,30 @@ type Config struct {
 	// engine](/packer/docs/templates/legacy_json_templates/engine). Therefore, you
 	// may use user variables and template functions in this field.
 	CustomData map[string]string `mapstructure:"custom_data"`
-	ctx        interpolate.Context
+	// How long to wait for other builds to finish writing the manifest
+	// file. Builds take an advisory lock on `<output>.lock` while they
+	// update the manifest, and fail if they can't take it in time. This
+	// defaults to `1m`.
+	LockTimeout time.Duration `mapstructure:"lock_timeout"`
+	// Keep only the builds of the last `max_runs` Packer runs in the
+	// manifest file. This defaults to 0, keeping all of them.
+	MaxRuns int `mapstructure:"max_runs"`
+
+	ctx interpolate.Context
 }
 
 type PostProcessor struct {
 	config Config
 }
 
+// ManifestSchemaVersion is the version of the format of manifest files.
+// Files written before it was introduced have no version.
+const ManifestSchemaVersion = 1
+
 type ManifestFile struct {
-	Builds      []Artifact `json:"builds"`
-	LastRunUUID string     `json:"last_run_uuid"`
+	SchemaVersion int        `json:"schema_version"`
+	Builds        []Artifact `json:"builds"`
+	LastRunUUID   string     `json:"last_run_uuid"`
 }
 
 func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
@@ -64,14 +95,30 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return err
 	}
 
+	if p.config.Format == "" {
+		p.config.Format = FormatJSON
+	}
+	switch p.config.Format {
+	case FormatJSON, FormatYAML, FormatEnv:
+	default:
+		return fmt.Errorf("Unrecognized format %q, expected %q, %q or %q", p.config.Format, FormatJSON, FormatYAML, FormatEnv)
+	}
+
 	if p.config.OutputPath == "" {
-		p.config.OutputPath = "packer-manifest.json"
+		p.config.OutputPath = "packer-manifest." + p.config.Format
 	}
 
 	if err = interpolate.Validate(p.config.OutputPath, &p.config.ctx); err != nil {
 		return fmt.Errorf("Error parsing target template: %s", err)
 	}
 
+	if p.config.LockTimeout == 0 {
+		p.config.LockTimeout = time.Minute
+	}
+	if p.config.MaxRuns < 0 {
+		return fmt.Errorf("max_runs must not be negative")
+	}
+
 	return nil
 }
 
@@ -101,6 +148,11 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source
 		af := ArtifactFile{}
 		if fi, err = os.Stat(name); err == nil {
 			af.Size = fi.Size()
+			if fi.Mode().IsRegular() {
+				if af.SHA256, err = fileSHA256(name); err != nil {
+					return source, true, true, err
+				}
+			}
 		}
 		if p.config.StripPath {
 			af.Name = filepath.Base(name)
@@ -109,6 +161,15 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source
 		}
 		artifact.ArtifactFiles = append(artifact.ArtifactFiles, af)
 	}
+	sensitiveValues := p.sensitiveValues()
+	for _, key := range p.config.IncludeState {
+		if value := source.State(key); value != nil {
+			if artifact.StateData == nil {
+				artifact.StateData = map[string]interface{}{}
+			}
+			artifact.StateData[key] = redact(jsonValue(value), sensitiveValues)
+		}
+	}
 	artifact.ArtifactId = source.Id()
 	artifact.CustomData = p.config.CustomData
 	artifact.BuilderType = p.config.PackerBuilderType
@@ -117,6 +178,41 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source
 	if p.config.StripTime {
 		artifact.BuildTime = 0
 	}
+	// Packer also runs the manifest post-processor for the builds that
+	// failed or were cancelled, with their status in the build metadata.
+	artifact.Status = "success"
+	artifact.GeneratedData = p.recordedGeneratedData(generatedData)
+	if metadata := stringKeys(source.State("build_metadata")); metadata != nil {
+		if status, ok := metadata["status"].(string); ok && status != "" {
+			artifact.Status = status
+		}
+		if buildErr, ok := metadata["error"].(string); ok {
+			artifact.Error = buildErr
+		}
+		if startedAt, err := time.Parse(time.RFC3339Nano, fmt.Sprint(metadata["started_at"])); err == nil && !p.config.StripTime {
+			artifact.Duration = time.Since(startedAt).Seconds()
+		}
+		if version, ok := metadata["packer_version"].(string); ok {
+			artifact.PackerVersion = version
+		}
+		for name, version := range stringKeys(metadata["plugins"]) {
+			if artifact.Plugins == nil {
+				artifact.Plugins = map[string]string{}
+			}
+			artifact.Plugins[name] = fmt.Sprint(version)
+		}
+		for name, value := range stringKeys(metadata["plugin_resources"]) {
+			usage := stringKeys(value)
+			if artifact.PluginResources == nil {
+				artifact.PluginResources = map[string]PluginResources{}
+			}
+			resources := PluginResources{}
+			resources.PeakRSS, _ = usage["peak_rss_bytes"].(int64)
+			resources.CPUSeconds, _ = usage["cpu_seconds"].(float64)
+			resources.Samples, _ = usage["samples"].(int64)
+			artifact.PluginResources[name] = resources
+		}
+	}
 	// Since each post-processor runs in a different process we need a way to
 	// coordinate between various post-processors in a single packer run. We do
 	// this by setting a UUID per run and tracking this in the manifest file.
@@ -126,19 +222,27 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source
 	// the file before we proceed.
 	artifact.PackerRunUUID = os.Getenv("PACKER_RUN_UUID")
 
-	// Create a lock file with exclusive access. If this fails we will retry
-	// after a delay.
-	lockFilename := p.config.OutputPath + ".lock"
-	for i := 0; i < 3; i++ {
-		// The file should not be locked for very long so we'll keep this short.
-		time.Sleep((time.Duration(i) * 200 * time.Millisecond))
-		_, err = os.OpenFile(lockFilename, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
-		if err == nil {
-			break
+	if p.config.Format == FormatEnv && artifact.Status != "success" {
+		// Env manifests hold the latest values of successful builds.
+		ui.Say(fmt.Sprintf("Not recording the %s build in %s", artifact.Status, p.config.OutputPath))
+		return source, true, true, nil
+	}
+
+	// Take an advisory lock while updating the manifest, so that builds
+	// running in parallel don't overwrite each other's entries. The lock file
+	// is kept, removing it would let another build lock a new file while
+	// the old one is still locked.
+	lockCtx, cancel := context.WithTimeout(ctx, p.config.LockTimeout)
+	defer cancel()
+	lock := flock.New(p.config.OutputPath + ".lock")
+	locked, err := lock.TryLockContext(lockCtx, 100*time.Millisecond)
+	if !locked {
+		if err == nil || errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timeout after %s", p.config.LockTimeout)
 		}
-		log.Printf("Error locking manifest file for reading and writing. Will sleep and retry. %s", err)
+		return source, true, true, fmt.Errorf("Unable to lock %s: %s", p.config.OutputPath, err)
 	}
-	defer os.Remove(lockFilename)
+	defer lock.Unlock()
 
 	// Read the current manifest file from disk
 	var contents []byte
@@ -146,13 +250,48 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source
 		return source, true, true, fmt.Errorf("Unable to open %s for reading: %s", p.config.OutputPath, err)
 	}
 
-	// Parse the manifest file JSON, if we have one
+	var out []byte
+	if p.config.Format == FormatEnv {
+		out, err = p.updateEnv(contents, artifact)
+	} else {
+		out, err = p.update(contents, artifact)
+	}
+	if err != nil {
+		return source, true, true, err
+	}
+
+	// Write to disk
+	if err = writeFileAtomic(p.config.OutputPath, out, 0664); err != nil {
+		return source, true, true, fmt.Errorf("Unable to write %s: %s", p.config.OutputPath, err)
+	}
+
+	// The manifest should never delete the artifacts it is set to record, so it
+	// forcibly sets "keep" to true.
+	return source, true, true, nil
+}
+
+func createInterpolatedCustomData(config *Config, customData string) (string, error) {
+	interpolatedCmd, err := interpolate.Render(customData, &config.ctx)
+	if err != nil {
+		return "", fmt.Errorf("Error interpolating custom data: %s", err)
+	}
+	return interpolatedCmd, nil
+}
+
+// update adds artifact to the json or yaml manifest file in contents, and
+// returns the new content.
+func (p *PostProcessor) update(contents []byte, artifact *Artifact) ([]byte, error) {
+	// Parse the manifest file, if we have one
 	manifestFile := &ManifestFile{}
 	if len(contents) > 0 {
-		if err = json.Unmarshal(contents, manifestFile); err != nil {
-			return source, true, true, fmt.Errorf("Unable to parse content from %s: %s", p.config.OutputPath, err)
+		if err := decodeManifest(p.config.Format, contents, manifestFile); err != nil {
+			return nil, fmt.Errorf("Unable to parse content from %s: %s", p.config.OutputPath, err)
 		}
 	}
+	if manifestFile.SchemaVersion > ManifestSchemaVersion {
+		return nil, fmt.Errorf("Unable to update %s: schema version %d is newer than %d, the version supported by this version of Packer",
+			p.config.OutputPath, manifestFile.SchemaVersion, ManifestSchemaVersion)
+	}
 
 	// If -force is set and we are not on same run, truncate the file. Otherwise
 	// we will continue to add new builds to the existing manifest file.
@@ -161,27 +300,215 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source
 	}
 
 	// Add the current artifact to the manifest file
+	manifestFile.SchemaVersion = ManifestSchemaVersion
 	manifestFile.Builds = append(manifestFile.Builds, *artifact)
 	manifestFile.LastRunUUID = os.Getenv("PACKER_RUN_UUID")
+	if p.config.MaxRuns > 0 {
+		manifestFile.Builds = lastRuns(manifestFile.Builds, p.config.MaxRuns)
+	}
+
+	out, err := encodeManifest(p.config.Format, manifestFile)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to marshal %s %s", strings.ToUpper(p.config.Format), err)
+	}
+	return out, nil
+}
+
+// updateEnv adds the variables of artifact to the env manifest file in
+// contents, and returns the new content.
+func (p *PostProcessor) updateEnv(contents []byte, artifact *Artifact) ([]byte, error) {
+	vars, err := decodeEnv(contents)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse content from %s: %s", p.config.OutputPath, err)
+	}
+
+	// Like for json, -force truncates the file of a previous run.
+	if p.config.PackerForce && os.Getenv("PACKER_RUN_UUID") != vars[envRunUUIDKey] {
+		vars = map[string]string{}
+	}
+
+	for key, value := range envVars(artifact) {
+		vars[key] = value
+	}
+	vars[envRunUUIDKey] = os.Getenv("PACKER_RUN_UUID")
+	return encodeEnv(vars), nil
+}
+
+// fileSHA256 returns the hex encoded SHA256 of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("Unable to open %s: %s", path, err)
+	}
+	defer f.Close()
 
-	// Write JSON to disk
-	if out, err := json.MarshalIndent(manifestFile, "", "  "); err == nil {
-		if err = os.WriteFile(p.config.OutputPath, out, 0664); err != nil {
-			return source, true, true, fmt.Errorf("Unable to write %s: %s", p.config.OutputPath, err)
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("Unable to compute the SHA256 of %s: %s", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// jsonValue converts the maps read from the state of artifacts going
+// through RPC, which have interface{} keys, so that value can be marshalled
+// to JSON.
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			result[fmt.Sprint(k)] = jsonValue(v)
 		}
-	} else {
-		return source, true, true, fmt.Errorf("Unable to marshal JSON %s", err)
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			result[k] = jsonValue(v)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, v := range value {
+			result[i] = jsonValue(v)
+		}
+		return result
+	case []byte:
+		return string(value)
 	}
+	return value
+}
 
-	// The manifest should never delete the artifacts it is set to record, so it
-	// forcibly sets "keep" to true.
-	return source, true, true, nil
+// generatedCredentials lists the generated data keys holding the
+// credentials builders generate, which are never recorded in the manifest.
+var generatedCredentials = map[string]bool{
+	"Password":      true,
+	"SSHPrivateKey": true,
+	"WinRMPassword": true,
 }
 
-func createInterpolatedCustomData(config *Config, customData string) (string, error) {
-	interpolatedCmd, err := interpolate.Render(customData, &config.ctx)
+// sensitiveValues returns the values of the sensitive variables, which are
+// never recorded in the manifest.
+func (p *PostProcessor) sensitiveValues() map[string]bool {
+	values := map[string]bool{}
+	for _, name := range p.config.PackerSensitiveVars {
+		if value := p.config.PackerUserVars[name]; value != "" {
+			values[value] = true
+		}
+	}
+	return values
+}
+
+// recordedGeneratedData returns the generated data of the build to record in
+// the manifest, without the credentials builders generate and the values of
+// sensitive variables.
+func (p *PostProcessor) recordedGeneratedData(generatedData interface{}) map[string]string {
+	sensitiveValues := p.sensitiveValues()
+
+	recorded := map[string]string{}
+	for key, value := range stringKeys(generatedData) {
+		if generatedCredentials[key] {
+			continue
+		}
+		if value := fmt.Sprint(value); !sensitiveValues[value] {
+			recorded[key] = value
+		}
+	}
+	if len(recorded) == 0 {
+		return nil
+	}
+	return recorded
+}
+
+// redact returns value, converted by jsonValue, without the map entries
+// holding generated credentials or the value of a sensitive variable. The
+// values of sensitive variables found elsewhere, like in lists, are replaced
+// with `<sensitive>`.
+func redact(value interface{}, sensitiveValues map[string]bool) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			if generatedCredentials[k] {
+				continue
+			}
+			if s, ok := v.(string); ok && sensitiveValues[s] {
+				continue
+			}
+			result[k] = redact(v, sensitiveValues)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, v := range value {
+			result[i] = redact(v, sensitiveValues)
+		}
+		return result
+	case string:
+		if sensitiveValues[value] {
+			return "<sensitive>"
+		}
+	}
+	return value
+}
+
+// stringKeys returns value as a map with string keys, if it is a map. Maps
+// read from the state of artifacts going through RPC have interface{} keys.
+func stringKeys(value interface{}) map[string]interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return value
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			result[fmt.Sprint(k)] = v
+		}
+		return result
+	}
+	return nil
+}
+
+// lastRuns returns the builds of the last maxRuns Packer runs.
+func lastRuns(builds []Artifact, maxRuns int) []Artifact {
+	runs := map[string]bool{}
+	first := len(builds)
+	for ; first > 0; first-- {
+		runUUID := builds[first-1].PackerRunUUID
+		if !runs[runUUID] && len(runs) == maxRuns {
+			break
+		}
+		runs[runUUID] = true
+	}
+	var kept []Artifact
+	for _, build := range builds[:first] {
+		if runs[build.PackerRunUUID] {
+			kept = append(kept, build)
+		}
+	}
+	return append(kept, builds[first:]...)
+}
+
+// writeFileAtomic writes data to a temporary file next to path, and renames
+// it to path, so that readers never see a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
 	if err != nil {
-		return "", fmt.Errorf("Error interpolating custom data: %s", err)
+		return err
 	}
-	return interpolatedCmd, nil
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
 }
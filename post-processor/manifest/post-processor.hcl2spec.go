@@ -19,9 +19,13 @@ type FlatConfig struct {
 	PackerUserVars      map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
 	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
 	OutputPath          *string           `mapstructure:"output" cty:"output" hcl:"output"`
+	Format              *string           `mapstructure:"format" cty:"format" hcl:"format"`
+	IncludeState        []string          `mapstructure:"include_state" cty:"include_state" hcl:"include_state"`
 	StripPath           *bool             `mapstructure:"strip_path" cty:"strip_path" hcl:"strip_path"`
 	StripTime           *bool             `mapstructure:"strip_time" cty:"strip_time" hcl:"strip_time"`
 	CustomData          map[string]string `mapstructure:"custom_data" cty:"custom_data" hcl:"custom_data"`
+	LockTimeout         *string           `mapstructure:"lock_timeout" cty:"lock_timeout" hcl:"lock_timeout"`
+	MaxRuns             *int              `mapstructure:"max_runs" cty:"max_runs" hcl:"max_runs"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -45,9 +49,13 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"packer_user_variables":      &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
 		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
 		"output":                     &hcldec.AttrSpec{Name: "output", Type: cty.String, Required: false},
+		"format":                     &hcldec.AttrSpec{Name: "format", Type: cty.String, Required: false},
+		"include_state":              &hcldec.AttrSpec{Name: "include_state", Type: cty.List(cty.String), Required: false},
 		"strip_path":                 &hcldec.AttrSpec{Name: "strip_path", Type: cty.Bool, Required: false},
 		"strip_time":                 &hcldec.AttrSpec{Name: "strip_time", Type: cty.Bool, Required: false},
 		"custom_data":                &hcldec.AttrSpec{Name: "custom_data", Type: cty.Map(cty.String), Required: false},
+		"lock_timeout":               &hcldec.AttrSpec{Name: "lock_timeout", Type: cty.String, Required: false},
+		"max_runs":                   &hcldec.AttrSpec{Name: "max_runs", Type: cty.Number, Required: false},
 	}
 	return s
 }
@@ -0,0 +1,54 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package oci_push
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+const BuilderId = "packer.post-processor.oci-push"
+
+// Artifact is the pushed OCI artifact. Its ID is the digest reference of
+// the artifact, like `registry.example.com/images/ubuntu@sha256:...`.
+type Artifact struct {
+	digest    name.Digest
+	referrers []name.Digest
+	options   []remote.Option
+}
+
+func (a *Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	return a.digest.String()
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("Pushed OCI artifact %s with %d referrers", a.digest, len(a.referrers))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	return nil
+}
+
+// Destroy deletes the artifact and its referrers from the registry. Tags
+// of the artifact are deleted along with it.
+func (a *Artifact) Destroy() error {
+	var errs []error
+	for _, ref := range append(a.referrers, a.digest) {
+		if err := remote.Delete(ref, a.options...); err != nil {
+			errs = append(errs, fmt.Errorf("Unable to delete %s: %s", ref, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,145 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package oci_push
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	// The config of artifacts, which carry no image config.
+	mediaTypeEmptyJSON = "application/vnd.oci.empty.v1+json"
+	// The annotation holding the file name of layers, used by clients like
+	// `oras pull` to name the files they write.
+	annotationTitle = "org.opencontainers.image.title"
+)
+
+var emptyJSON = []byte("{}")
+
+// blob is a layer of an artifact manifest, read from a file or from memory.
+type blob struct {
+	mediaType types.MediaType
+	digest    v1.Hash
+	size      int64
+	open      func() (io.ReadCloser, error)
+}
+
+var _ partial.CompressedLayer = (*blob)(nil)
+
+func (b *blob) Digest() (v1.Hash, error)            { return b.digest, nil }
+func (b *blob) Compressed() (io.ReadCloser, error)  { return b.open() }
+func (b *blob) Size() (int64, error)                { return b.size, nil }
+func (b *blob) MediaType() (types.MediaType, error) { return b.mediaType, nil }
+
+// fileBlob returns a blob read from path, hashing it once.
+func fileBlob(path string, mediaType types.MediaType) (*blob, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+	return &blob{
+		mediaType: mediaType,
+		digest:    v1.Hash{Algorithm: "sha256", Hex: fmt.Sprintf("%x", h.Sum(nil))},
+		size:      size,
+		open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+	}, nil
+}
+
+// contentBlob returns a blob holding content.
+func contentBlob(content []byte, mediaType types.MediaType) *blob {
+	return &blob{
+		mediaType: mediaType,
+		digest:    v1.Hash{Algorithm: "sha256", Hex: fmt.Sprintf("%x", sha256.Sum256(content))},
+		size:      int64(len(content)),
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(content)), nil
+		},
+	}
+}
+
+// artifactImage is an OCI image manifest describing an artifact, following
+// the OCI 1.1 guidance: the artifact type is set, the config is empty and
+// the files are the layers.
+type artifactImage struct {
+	manifest []byte
+	blobs    map[v1.Hash]*blob
+}
+
+var _ partial.CompressedImageCore = (*artifactImage)(nil)
+
+// newArtifactImage returns an artifact made of blobs, titled with the names
+// of titles. subject, when set, is the manifest the artifact refers to.
+func newArtifactImage(artifactType string, blobs []*blob, titles []string, subject *v1.Descriptor, annotations map[string]string) (*artifactImage, error) {
+	img := &artifactImage{blobs: map[v1.Hash]*blob{}}
+	manifest := v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		ArtifactType:  artifactType,
+		Config: v1.Descriptor{
+			MediaType: mediaTypeEmptyJSON,
+			Digest:    contentBlob(emptyJSON, mediaTypeEmptyJSON).digest,
+			Size:      int64(len(emptyJSON)),
+		},
+		Layers:      []v1.Descriptor{},
+		Subject:     subject,
+		Annotations: annotations,
+	}
+	for i, b := range blobs {
+		manifest.Layers = append(manifest.Layers, v1.Descriptor{
+			MediaType:   b.mediaType,
+			Digest:      b.digest,
+			Size:        b.size,
+			Annotations: map[string]string{annotationTitle: titles[i]},
+		})
+		img.blobs[b.digest] = b
+	}
+
+	var err error
+	img.manifest, err = json.Marshal(manifest)
+	return img, err
+}
+
+func (img *artifactImage) RawManifest() ([]byte, error) { return img.manifest, nil }
+
+func (img *artifactImage) RawConfigFile() ([]byte, error) { return emptyJSON, nil }
+
+func (img *artifactImage) MediaType() (types.MediaType, error) { return types.OCIManifestSchema1, nil }
+
+func (img *artifactImage) LayerByDigest(h v1.Hash) (partial.CompressedLayer, error) {
+	if b, ok := img.blobs[h]; ok {
+		return b, nil
+	}
+	if h == contentBlob(emptyJSON, mediaTypeEmptyJSON).digest {
+		return contentBlob(emptyJSON, mediaTypeEmptyJSON), nil
+	}
+	return nil, fmt.Errorf("unknown blob %s", h)
+}
+
+// descriptor returns the descriptor of img, to refer to it as a subject.
+func (img *artifactImage) descriptor(artifactType string) v1.Descriptor {
+	return v1.Descriptor{
+		MediaType:    types.OCIManifestSchema1,
+		ArtifactType: artifactType,
+		Digest:       contentBlob(img.manifest, types.OCIManifestSchema1).digest,
+		Size:         int64(len(img.manifest)),
+	}
+}
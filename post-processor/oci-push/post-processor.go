@@ -0,0 +1,423 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package oci_push
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/hashicorp/packer/internal/provenance"
+)
+
+const (
+	DefaultArtifactType = "application/vnd.hashicorp.packer.artifact.v1"
+	DefaultMediaType    = "application/octet-stream"
+
+	mediaTypeCycloneDX = "application/vnd.cyclonedx+json"
+	mediaTypeSPDX      = "application/spdx+json"
+	mediaTypeInToto    = "application/vnd.in-toto+json"
+	mediaTypeDSSE      = "application/vnd.dsse.envelope.v1+json"
+)
+
+// The oci-push post-processor pushes the files of an artifact to an OCI
+// registry as an OCI artifact, with the SBOMs and attestations of the build
+// attached as referrers.
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The repository to push the artifact to, like
+	// `registry.example.com/images/ubuntu`. This is a template with the
+	// generated data of the build, `BuildName` and `BuilderType`.
+	Repository string `mapstructure:"repository" required:"true"`
+	// The tags to push the artifact with, templates with the same data as
+	// `repository`. Without tags the artifact is only referenced by its
+	// digest.
+	Tags []string `mapstructure:"tags"`
+	// The `artifactType` of the manifest. Defaults to
+	// `application/vnd.hashicorp.packer.artifact.v1`.
+	ArtifactType string `mapstructure:"artifact_type"`
+	// The media types of the files by pattern of their name, like
+	// `{ "*.qcow2" = "application/vnd.qemu.qcow2" }`. The longest matching
+	// pattern is used, and files matching none are
+	// `application/octet-stream`.
+	MediaTypes map[string]string `mapstructure:"media_types"`
+	// Annotations of the manifest. Values are templates with the same data
+	// as `repository`.
+	Annotations map[string]string `mapstructure:"annotations"`
+
+	// Attach the SBOMs downloaded by the `hcp-sbom` provisioners of the
+	// build as referrers of the artifact. Defaults to true.
+	AttachSBOMs config.Trilean `mapstructure:"attach_sboms"`
+	// Glob patterns of the attestation files to attach as referrers of the
+	// artifact. Defaults to the provenance and SBOM attestations, and their
+	// Sigstore bundles, written by the `provenance` post-processor, when
+	// they exist.
+	Attestations []string `mapstructure:"attestations"`
+	// The `output_dir` of the `provenance` post-processor, to find its
+	// attestations in. Defaults to the directory of the first file of the
+	// artifact, like the `provenance` post-processor.
+	ProvenanceOutputDir string `mapstructure:"provenance_output_dir"`
+
+	// The user name to authenticate to the registry with. Defaults to the
+	// credentials of the Docker configuration.
+	Username string `mapstructure:"username"`
+	// The password or token to authenticate to the registry with.
+	Password string `mapstructure:"password"`
+	// Talk to the registry over plain HTTP. Registries on localhost always
+	// accept plain HTTP.
+	Insecure bool `mapstructure:"insecure"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         "oci-push",
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{"repository", "tags", "annotations"},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if p.config.Repository == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("repository is required"))
+	}
+	templates := append([]string{p.config.Repository}, p.config.Tags...)
+	for _, value := range p.config.Annotations {
+		templates = append(templates, value)
+	}
+	for _, template := range templates {
+		if err := interpolate.Validate(template, &p.config.ctx); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error parsing template %q: %s", template, err))
+		}
+	}
+
+	if p.config.ArtifactType == "" {
+		p.config.ArtifactType = DefaultArtifactType
+	}
+
+	for pattern := range p.config.MediaTypes {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Invalid media_types pattern %q: %s", pattern, err))
+		}
+	}
+	for _, pattern := range p.config.Attestations {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Invalid attestations pattern %q: %s", pattern, err))
+		}
+	}
+
+	if p.config.Password != "" && p.config.Username == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("password requires username"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// referrer is a file attached to the artifact.
+type referrer struct {
+	title     string
+	mediaType types.MediaType
+	content   []byte
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	var generatedData map[interface{}]interface{}
+	stateData := source.State("generated_data")
+	if stateData != nil {
+		// Make sure it's not a nil map so we can assign to it later.
+		generatedData = stateData.(map[interface{}]interface{})
+	}
+	// If stateData has a nil map generatedData will be nil
+	// and we need to make sure it's not
+	if generatedData == nil {
+		generatedData = make(map[interface{}]interface{})
+	}
+
+	// These are extra variables that will be made available for interpolation.
+	generatedData["BuildName"] = p.config.PackerBuildName
+	generatedData["BuilderType"] = p.config.PackerBuilderType
+	p.config.ctx.Data = generatedData
+
+	files := source.Files()
+	if len(files) == 0 {
+		return nil, false, false, fmt.Errorf("Unable to push artifact %s: it has no files", source.Id())
+	}
+
+	repoName, err := interpolate.Render(p.config.Repository, &p.config.ctx)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Error interpolating repository: %s", err)
+	}
+	var nameOpts []name.Option
+	if p.config.Insecure {
+		nameOpts = append(nameOpts, name.Insecure)
+	}
+	repo, err := name.NewRepository(repoName, nameOpts...)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Invalid repository %q: %s", repoName, err)
+	}
+	var tags []name.Tag
+	for _, tag := range p.config.Tags {
+		tag, err := interpolate.Render(tag, &p.config.ctx)
+		if err != nil {
+			return nil, false, false, fmt.Errorf("Error interpolating tag: %s", err)
+		}
+		ref, err := name.NewTag(repo.Name()+":"+tag, nameOpts...)
+		if err != nil {
+			return nil, false, false, fmt.Errorf("Invalid tag %q: %s", tag, err)
+		}
+		tags = append(tags, ref)
+	}
+	annotations := map[string]string{}
+	for key, value := range p.config.Annotations {
+		annotations[key], err = interpolate.Render(value, &p.config.ctx)
+		if err != nil {
+			return nil, false, false, fmt.Errorf("Error interpolating annotation %s: %s", key, err)
+		}
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+
+	referrers, err := p.referrers(source)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	blobs := make([]*blob, 0, len(files))
+	titles := make([]string, 0, len(files))
+	seen := map[string]string{}
+	for _, path := range files {
+		title := filepath.Base(path)
+		if other, ok := seen[title]; ok {
+			return nil, false, false, fmt.Errorf("Unable to push both %s and %s: files of an artifact need distinct names", other, path)
+		}
+		seen[title] = path
+		b, err := fileBlob(path, types.MediaType(p.mediaType(title)))
+		if err != nil {
+			return nil, false, false, fmt.Errorf("Unable to read %s: %s", path, err)
+		}
+		blobs = append(blobs, b)
+		titles = append(titles, title)
+	}
+
+	img, err := newArtifactImage(p.config.ArtifactType, blobs, titles, nil, annotations)
+	if err != nil {
+		return nil, false, false, err
+	}
+	subject := img.descriptor(p.config.ArtifactType)
+	artifact := &Artifact{
+		digest:  repo.Digest(subject.Digest.String()),
+		options: p.remoteOptions(ctx),
+	}
+
+	ui.Say(fmt.Sprintf("Pushing %d files to %s", len(files), artifact.digest))
+	if err := push(artifact.digest, img, artifact.options); err != nil {
+		return nil, false, false, fmt.Errorf("Unable to push %s: %s", artifact.digest, err)
+	}
+	for _, tag := range tags {
+		if err := remote.Put(tag, img, artifact.options...); err != nil {
+			return nil, false, false, fmt.Errorf("Unable to tag %s: %s", tag, err)
+		}
+		ui.Message(fmt.Sprintf("Tagged %s", tag))
+	}
+
+	for _, r := range referrers {
+		b := contentBlob(r.content, r.mediaType)
+		refImg, err := newArtifactImage(string(r.mediaType), []*blob{b}, []string{r.title}, &subject, nil)
+		if err != nil {
+			return nil, false, false, err
+		}
+		ref := repo.Digest(refImg.descriptor(string(r.mediaType)).Digest.String())
+		if err := push(ref, refImg, artifact.options); err != nil {
+			return nil, false, false, fmt.Errorf("Unable to attach %s: %s", r.title, err)
+		}
+		artifact.referrers = append(artifact.referrers, ref)
+		ui.Message(fmt.Sprintf("Attached %s as %s", r.title, ref.DigestStr()))
+	}
+
+	return artifact, true, false, nil
+}
+
+// push uploads the blobs and the manifest of img.
+func push(ref name.Reference, img *artifactImage, opts []remote.Option) error {
+	image, err := partial.CompressedToImage(img)
+	if err != nil {
+		return err
+	}
+	return remote.Write(ref, image, opts...)
+}
+
+func (p *PostProcessor) remoteOptions(ctx context.Context) []remote.Option {
+	opts := []remote.Option{remote.WithContext(ctx)}
+	if p.config.Username != "" {
+		opts = append(opts, remote.WithAuth(&authn.Basic{
+			Username: p.config.Username,
+			Password: p.config.Password,
+		}))
+	} else {
+		opts = append(opts, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	}
+	return opts
+}
+
+// mediaType returns the media type of the file named fileName.
+func (p *PostProcessor) mediaType(fileName string) string {
+	patterns := make([]string, 0, len(p.config.MediaTypes))
+	for pattern := range p.config.MediaTypes {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, fileName); ok {
+			return p.config.MediaTypes[pattern]
+		}
+	}
+	return DefaultMediaType
+}
+
+// referrers returns the SBOMs and attestations to attach to the artifact.
+func (p *PostProcessor) referrers(source packersdk.Artifact) ([]referrer, error) {
+	var referrers []referrer
+
+	if !p.config.AttachSBOMs.False() {
+		sboms, _ := source.State("build_sboms").([]interface{})
+		for i, value := range sboms {
+			sbom := stringKeys(value)
+			content, _ := sbom["content"].(string)
+			if b, ok := sbom["content"].([]byte); ok {
+				content = string(b)
+			}
+			sbomName, _ := sbom["name"].(string)
+			if sbomName == "" {
+				sbomName = fmt.Sprintf("sbom-%d", i)
+			}
+			r := referrer{title: sbomName + ".cdx.json", mediaType: mediaTypeCycloneDX, content: []byte(content)}
+			if strings.EqualFold(fmt.Sprint(sbom["format"]), "spdx") {
+				r.title, r.mediaType = sbomName+".spdx.json", mediaTypeSPDX
+			}
+			referrers = append(referrers, r)
+		}
+	}
+
+	var paths []string
+	if p.config.Attestations == nil {
+		for _, path := range p.provenanceOutputs(source) {
+			if _, err := os.Stat(path); err == nil {
+				paths = append(paths, path)
+			}
+		}
+	}
+	for _, pattern := range p.config.Attestations {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid attestations pattern %q: %s", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("No attestation file matches %q", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read attestation %s: %s", path, err)
+		}
+		referrers = append(referrers, referrer{
+			title:     filepath.Base(path),
+			mediaType: attestationMediaType(content),
+			content:   content,
+		})
+	}
+	return referrers, nil
+}
+
+// provenanceOutputs returns the paths of the attestations, and of their
+// Sigstore bundles, that the `provenance` post-processor writes for source.
+func (p *PostProcessor) provenanceOutputs(source packersdk.Artifact) []string {
+	dir := provenance.OutputDir(source, p.config.ProvenanceOutputDir)
+	stem := provenance.OutputStem(source, p.config.PackerBuildName)
+	var paths []string
+	for _, path := range []string{
+		provenance.StatementPath(dir, stem),
+		provenance.SBOMAttestationPath(dir, stem),
+	} {
+		paths = append(paths, path, provenance.SigstoreBundlePath(path))
+	}
+	return paths
+}
+
+// attestationMediaType tells DSSE envelopes, in-toto statements and
+// Sigstore bundles apart.
+func attestationMediaType(content []byte) types.MediaType {
+	var doc struct {
+		PayloadType string `json:"payloadType"`
+		Type        string `json:"_type"`
+		MediaType   string `json:"mediaType"`
+	}
+	if err := json.Unmarshal(content, &doc); err == nil {
+		switch {
+		case doc.PayloadType != "":
+			return mediaTypeDSSE
+		case doc.Type != "":
+			return mediaTypeInToto
+		case strings.HasPrefix(doc.MediaType, "application/vnd.dev.sigstore.bundle"):
+			return types.MediaType(doc.MediaType)
+		}
+	}
+	return "application/json"
+}
+
+// stringKeys returns value as a map with string keys, as state maps read
+// through RPC have interface{} keys.
+func stringKeys(value interface{}) map[string]interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return value
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			result[fmt.Sprint(k)] = v
+		}
+		return result
+	}
+	return nil
+}
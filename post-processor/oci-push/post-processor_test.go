@@ -0,0 +1,315 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package oci_push
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func newRegistry(t *testing.T, referrers bool) string {
+	server := httptest.NewServer(registry.New(registry.WithReferrersSupport(referrers)))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func testPush(t *testing.T, config map[string]interface{}, source packersdk.Artifact) (packersdk.Artifact, error) {
+	var p PostProcessor
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	artifact, _, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source)
+	return artifact, err
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) []string {
+	var paths []string
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func fetchManifest(t *testing.T, ref name.Reference) *v1.Manifest {
+	t.Helper()
+	desc, err := remote.Get(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := v1.ParseManifest(strings.NewReader(string(desc.Manifest)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manifest
+}
+
+func fetchBlob(t *testing.T, repo name.Repository, digest v1.Hash) string {
+	t.Helper()
+	layer, err := remote.Layer(repo.Digest(digest.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := layer.Compressed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestOCIPush(t *testing.T) {
+	for _, referrersAPI := range []bool{true, false} {
+		host := newRegistry(t, referrersAPI)
+		dir := t.TempDir()
+		files := writeFiles(t, dir, map[string]string{"image.qcow2": "disk"})
+		writeFiles(t, dir, map[string]string{
+			"vm.image.qcow2.provenance.json": `{"_type": "https://in-toto.io/Statement/v1"}`,
+		})
+		source := &packersdk.MockArtifact{
+			FilesValue: files,
+			StateValues: map[string]interface{}{
+				"generated_data": map[interface{}]interface{}{"ImageID": "1234"},
+				// As read through RPC.
+				"build_sboms": []interface{}{map[interface{}]interface{}{
+					"name":    "vm",
+					"format":  "SPDX",
+					"content": `{"spdxVersion": "SPDX-2.3"}`,
+				}},
+			},
+		}
+
+		artifact, err := testPush(t, map[string]interface{}{
+			"repository":        host + "/images/{{.BuildName}}",
+			"tags":              []string{"{{.ImageID}}", "latest"},
+			"artifact_type":     "application/vnd.example.disk.v1",
+			"media_types":       map[string]string{"*": "application/octet-stream", "*.qcow2": "application/vnd.example.qcow2"},
+			"annotations":       map[string]string{"org.opencontainers.image.version": "{{.ImageID}}"},
+			"packer_build_name": "vm",
+		}, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		digest, err := name.NewDigest(artifact.Id())
+		if err != nil {
+			t.Fatalf("expected a digest reference, got %q: %s", artifact.Id(), err)
+		}
+		repo := digest.Context()
+		if repo.RepositoryStr() != "images/vm" {
+			t.Errorf("unexpected repository %s", repo)
+		}
+
+		manifest := fetchManifest(t, digest)
+		if manifest.ArtifactType != "application/vnd.example.disk.v1" || manifest.Config.MediaType != mediaTypeEmptyJSON {
+			t.Errorf("unexpected manifest %+v", manifest)
+		}
+		if manifest.Annotations["org.opencontainers.image.version"] != "1234" {
+			t.Errorf("unexpected annotations %v", manifest.Annotations)
+		}
+		layer := manifest.Layers[0]
+		if len(manifest.Layers) != 1 || layer.MediaType != "application/vnd.example.qcow2" || layer.Annotations[annotationTitle] != "image.qcow2" {
+			t.Fatalf("unexpected layers %+v", manifest.Layers)
+		}
+		if content := fetchBlob(t, repo, layer.Digest); content != "disk" {
+			t.Errorf("unexpected content %q", content)
+		}
+		for _, tag := range []string{"1234", "latest"} {
+			desc, err := remote.Head(repo.Tag(tag))
+			if err != nil || desc.Digest.String() != digest.DigestStr() {
+				t.Errorf("expected tag %s to point at the artifact: %v", tag, err)
+			}
+		}
+
+		index, err := remote.Referrers(digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		referrers, err := index.IndexManifest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := map[string]string{}
+		for _, desc := range referrers.Manifests {
+			m := fetchManifest(t, repo.Digest(desc.Digest.String()))
+			if m.Subject == nil || m.Subject.Digest.String() != digest.DigestStr() {
+				t.Errorf("unexpected subject %+v", m.Subject)
+			}
+			found[m.ArtifactType] = fetchBlob(t, repo, m.Layers[0].Digest)
+		}
+		expected := map[string]string{
+			mediaTypeSPDX:   `{"spdxVersion": "SPDX-2.3"}`,
+			mediaTypeInToto: `{"_type": "https://in-toto.io/Statement/v1"}`,
+		}
+		if len(found) != len(expected) || found[mediaTypeSPDX] != expected[mediaTypeSPDX] || found[mediaTypeInToto] != expected[mediaTypeInToto] {
+			t.Errorf("referrers API %t: unexpected referrers %v", referrersAPI, found)
+		}
+
+		if err := artifact.Destroy(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := remote.Head(digest); err == nil {
+			t.Error("expected Destroy to delete the artifact")
+		}
+	}
+}
+
+func TestOCIPushAttestations(t *testing.T) {
+	host := newRegistry(t, true)
+	dir := t.TempDir()
+	files := writeFiles(t, dir, map[string]string{"image.raw": "disk"})
+	writeFiles(t, dir, map[string]string{
+		"signed.json": `{"payloadType": "application/vnd.in-toto+json", "payload": "", "signatures": []}`,
+	})
+
+	artifact, err := testPush(t, map[string]interface{}{
+		"repository":   host + "/images/vm",
+		"attestations": []string{filepath.Join(dir, "*.json")},
+		"attach_sboms": false,
+	}, &packersdk.MockArtifact{FilesValue: files})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := name.NewDigest(artifact.Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := remote.Referrers(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	referrers, err := index.IndexManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(referrers.Manifests) != 1 {
+		t.Fatalf("expected 1 referrer, got %+v", referrers.Manifests)
+	}
+	// The test registry lists the config media type of referrers instead of
+	// their artifactType, read it from the manifest.
+	m := fetchManifest(t, digest.Context().Digest(referrers.Manifests[0].Digest.String()))
+	if m.ArtifactType != mediaTypeDSSE || m.Layers[0].Annotations[annotationTitle] != "signed.json" {
+		t.Errorf("expected the DSSE envelope, got %+v", m)
+	}
+
+	_, err = testPush(t, map[string]interface{}{
+		"repository":   host + "/images/vm",
+		"attestations": []string{filepath.Join(dir, "missing-*.json")},
+	}, &packersdk.MockArtifact{FilesValue: files})
+	if err == nil {
+		t.Fatal("expected an attestation pattern matching nothing to fail")
+	}
+}
+
+func TestOCIPushProvenanceOutputDir(t *testing.T) {
+	host := newRegistry(t, true)
+	files := writeFiles(t, t.TempDir(), map[string]string{"image.raw": "disk"})
+	outputDir := t.TempDir()
+	writeFiles(t, outputDir, map[string]string{
+		"vm.image.raw.provenance.json":          `{"_type": "https://in-toto.io/Statement/v1"}`,
+		"vm.image.raw.provenance.sigstore.json": `{"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json"}`,
+	})
+
+	artifact, err := testPush(t, map[string]interface{}{
+		"repository":            host + "/images/vm",
+		"provenance_output_dir": outputDir,
+		"packer_build_name":     "vm",
+	}, &packersdk.MockArtifact{FilesValue: files})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := name.NewDigest(artifact.Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := remote.Referrers(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	referrers, err := index.IndexManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]string{}
+	for _, desc := range referrers.Manifests {
+		m := fetchManifest(t, digest.Context().Digest(desc.Digest.String()))
+		found[m.Layers[0].Annotations[annotationTitle]] = m.ArtifactType
+	}
+	expected := map[string]string{
+		"vm.image.raw.provenance.json":          mediaTypeInToto,
+		"vm.image.raw.provenance.sigstore.json": "application/vnd.dev.sigstore.bundle.v0.3+json",
+	}
+	if len(found) != len(expected) {
+		t.Fatalf("unexpected referrers %v", found)
+	}
+	for title, mediaType := range expected {
+		if found[title] != mediaType {
+			t.Errorf("unexpected referrers %v", found)
+		}
+	}
+}
+
+func TestOCIPushDuplicateNames(t *testing.T) {
+	host := newRegistry(t, true)
+	first := writeFiles(t, t.TempDir(), map[string]string{"image.raw": "first"})
+	second := writeFiles(t, t.TempDir(), map[string]string{"image.raw": "second"})
+
+	_, err := testPush(t, map[string]interface{}{"repository": host + "/images/vm"},
+		&packersdk.MockArtifact{FilesValue: append(first, second...)})
+	if err == nil {
+		t.Fatal("expected files with the same name to fail")
+	}
+}
+
+func TestOCIPushManifestJSON(t *testing.T) {
+	img, err := newArtifactImage(DefaultArtifactType, []*blob{contentBlob([]byte("disk"), DefaultMediaType)}, []string{"image.raw"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest map[string]interface{}
+	if err := json.Unmarshal(img.manifest, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := manifest["subject"]; ok {
+		t.Errorf("expected no subject, got %s", img.manifest)
+	}
+	if _, ok := manifest["annotations"]; ok {
+		t.Errorf("expected no annotations, got %s", img.manifest)
+	}
+}
+
+func TestOCIPushConfigure(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"missing repository":    {},
+		"bad template":          {"repository": "{{.BuildName"},
+		"bad media type":        {"repository": "r", "media_types": map[string]string{"[": "a/b"}},
+		"bad attestation":       {"repository": "r", "attestations": []string{"["}},
+		"password without user": {"repository": "r", "password": "p"},
+	}
+	for name, config := range cases {
+		var p PostProcessor
+		if err := p.Configure(config); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -17,7 +17,6 @@ import (
 	"sort"
 	"strings"
 	"time"
-	"unicode"
 
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/hcl/v2/hcldec"
@@ -308,7 +307,7 @@ func (p *PostProcessor) writeAttestation(ctx context.Context, ui packersdk.Ui, s
 		return fmt.Errorf("marshal canonical attestation payload: %w", err)
 	}
 
-	bundlePath := sigstoreBundleOutputPath(outputPath)
+	bundlePath := internalprovenance.SigstoreBundlePath(outputPath)
 	bundleJSON := []byte(nil)
 	var envelope internalattestation.Envelope
 	if backendConfig.Mode == internalattestation.SigningModeKeyless {
@@ -628,13 +627,7 @@ func redactSensitiveVariables(userVariables map[string]string, sensitiveKeys []s
 }
 
 func (p *PostProcessor) outputPaths(source packersdk.Artifact) (outputPaths, error) {
-	baseDir := p.config.OutputDir
-	if baseDir == "" && len(source.Files()) > 0 {
-		baseDir = filepath.Dir(source.Files()[0])
-	}
-	if baseDir == "" {
-		baseDir = "."
-	}
+	baseDir := internalprovenance.OutputDir(source, p.config.OutputDir)
 
 	if err := os.MkdirAll(baseDir, 0755); err != nil {
 		return outputPaths{}, fmt.Errorf("create output dir %q: %w", baseDir, err)
@@ -653,53 +646,15 @@ func (p *PostProcessor) outputPaths(source packersdk.Artifact) (outputPaths, err
 	return outputPaths{
 		BaseDir:             baseDir,
 		Stem:                name,
-		ProvenanceStatement: filepath.Join(baseDir, name+".provenance.json"),
+		ProvenanceStatement: internalprovenance.StatementPath(baseDir, name),
 		SBOMRaw:             sbomRaw,
-		SBOMAttestation:     filepath.Join(baseDir, name+".sbom.att.json"),
+		SBOMAttestation:     internalprovenance.SBOMAttestationPath(baseDir, name),
 	}, nil
 }
 
-// outputStem returns the base filename used for all provenance outputs. When a
-// build name is available it is prefixed so that parallel builds writing to a
-// shared output directory cannot collide on the same output paths.
+// outputStem returns the base filename used for all provenance outputs.
 func (p *PostProcessor) outputStem(source packersdk.Artifact) string {
-	base := artifactStem(source)
-
-	buildName := sanitizeFilename(strings.TrimSpace(p.config.PackerBuildName))
-	if buildName == "" || base == buildName || strings.HasPrefix(base, buildName+".") {
-		return base
-	}
-
-	return buildName + "." + base
-}
-
-func artifactStem(source packersdk.Artifact) string {
-	if files := source.Files(); len(files) > 0 {
-		return filepath.Base(files[0])
-	}
-
-	return sanitizeFilename(fmt.Sprintf("%s-%s", source.BuilderId(), source.Id()))
-}
-
-func sigstoreBundleOutputPath(attestationPath string) string {
-	if strings.HasSuffix(attestationPath, ".json") {
-		return strings.TrimSuffix(attestationPath, ".json") + ".sigstore.json"
-	}
-
-	return attestationPath + ".sigstore.json"
-}
-
-func sanitizeFilename(value string) string {
-	return strings.Map(func(r rune) rune {
-		switch {
-		case unicode.IsLetter(r), unicode.IsDigit(r):
-			return r
-		case r == '.', r == '-', r == '_':
-			return r
-		default:
-			return '_'
-		}
-	}, value)
+	return internalprovenance.OutputStem(source, p.config.PackerBuildName)
 }
 
 // atomicWriteFile writes data to path atomically by writing to a temporary file
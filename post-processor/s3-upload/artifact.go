@@ -0,0 +1,60 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package s3_upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const BuilderId = "packer.post-processor.s3-upload"
+
+// Artifact holds the uploaded objects. Its ID is the URL of the objects,
+// separated by commas.
+type Artifact struct {
+	client *s3.Client
+	bucket string
+	keys   []string
+	urls   []string
+}
+
+func (a *Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	return strings.Join(a.urls, ",")
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("Uploaded objects: %s", strings.Join(a.urls, ", "))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	return nil
+}
+
+// Destroy deletes the uploaded objects.
+func (a *Artifact) Destroy() error {
+	var errs []error
+	for _, key := range a.keys {
+		_, err := a.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+			Bucket: aws.String(a.bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Unable to delete %s: %s", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
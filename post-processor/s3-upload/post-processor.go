@@ -0,0 +1,307 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package s3_upload
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/dustin/go-humanize"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+const (
+	// The part sizes accepted by S3.
+	minPartSize = 5 * 1024 * 1024
+	maxPartSize = 5 * 1024 * 1024 * 1024
+)
+
+// The s3-upload post-processor uploads the files of an artifact to an S3
+// bucket, or to any storage with an S3-compatible API like MinIO.
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The URL of the S3-compatible API, like `http://localhost:9000`.
+	// Defaults to AWS S3.
+	Endpoint string `mapstructure:"endpoint"`
+	// The region of the bucket. Defaults to the region of the AWS
+	// configuration, or `us-east-1`.
+	Region string `mapstructure:"region"`
+	// Address buckets in the path of the URLs, as most S3-compatible
+	// storages expect, instead of in the host name.
+	ForcePathStyle bool `mapstructure:"force_path_style"`
+	// The access key to authenticate with. Defaults to the credentials of
+	// the AWS configuration and environment.
+	AccessKey string `mapstructure:"access_key"`
+	// The secret key to authenticate with.
+	SecretKey string `mapstructure:"secret_key"`
+	// The session token of temporary credentials.
+	SessionToken string `mapstructure:"session_token"`
+	// The profile of the AWS configuration files to use.
+	Profile string `mapstructure:"profile"`
+
+	// The bucket to upload the files to.
+	Bucket string `mapstructure:"bucket" required:"true"`
+	// The key of the uploaded objects. This is a template with the
+	// generated data of the build, `BuildName`, `BuilderType` and the
+	// `FileName` of the uploaded file. Defaults to
+	// `{{.BuildName}}/{{.FileName}}`.
+	Key string `mapstructure:"key"`
+	// Tags to set on the uploaded objects. Values are templates with the
+	// same data as `key`.
+	Tags map[string]string `mapstructure:"tags"`
+
+	// The server-side encryption of the objects: `AES256`, `aws:kms` or
+	// `aws:kms:dsse`. Defaults to the encryption of the bucket.
+	ServerSideEncryption string `mapstructure:"server_side_encryption"`
+	// The KMS key to encrypt the objects with, for the `aws:kms`
+	// encryptions. Defaults to the AWS managed key.
+	SSEKMSKeyId string `mapstructure:"sse_kms_key_id"`
+
+	// The size of the parts of multipart uploads, between `5MiB` and
+	// `5GiB`. Files up to this size are uploaded in one request. Defaults
+	// to `16MiB`, increased for files of more than 10000 parts.
+	PartSize string `mapstructure:"part_size"`
+	// The number of parts uploaded at the same time. Defaults to 4.
+	Concurrency int `mapstructure:"concurrency"`
+	// Keep the multipart uploads of failed builds in the bucket, and resume
+	// them in the next build: only the parts that are missing or that
+	// changed are uploaded again. Without it, failed uploads are aborted.
+	// Incomplete uploads are billed until they are completed or aborted,
+	// a lifecycle rule can clean them up.
+	Resume bool `mapstructure:"resume"`
+
+	partSize int64
+	ctx      interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         "s3-upload",
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{"key", "tags"},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if p.config.Bucket == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("bucket is required"))
+	}
+
+	if p.config.Endpoint != "" {
+		if u, err := url.Parse(p.config.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("endpoint must be a URL like https://host:port"))
+		}
+	}
+
+	if (p.config.AccessKey == "") != (p.config.SecretKey == "") {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("access_key and secret_key must be set together"))
+	}
+
+	if p.config.Key == "" {
+		p.config.Key = "{{.BuildName}}/{{.FileName}}"
+	}
+	if err := interpolate.Validate(p.config.Key, &p.config.ctx); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error parsing key template: %s", err))
+	}
+	for name, value := range p.config.Tags {
+		if err := interpolate.Validate(value, &p.config.ctx); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error parsing template of tag %s: %s", name, err))
+		}
+	}
+
+	switch types.ServerSideEncryption(p.config.ServerSideEncryption) {
+	case "", types.ServerSideEncryptionAes256:
+		if p.config.SSEKMSKeyId != "" {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("sse_kms_key_id requires the aws:kms or aws:kms:dsse server_side_encryption"))
+		}
+	case types.ServerSideEncryptionAwsKms, types.ServerSideEncryptionAwsKmsDsse:
+	default:
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Unrecognized server_side_encryption %q, expected %q, %q or %q",
+			p.config.ServerSideEncryption, types.ServerSideEncryptionAes256,
+			types.ServerSideEncryptionAwsKms, types.ServerSideEncryptionAwsKmsDsse))
+	}
+
+	if p.config.PartSize == "" {
+		p.config.PartSize = "16MiB"
+	}
+	partSize, err := humanize.ParseBytes(p.config.PartSize)
+	switch {
+	case err != nil:
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Invalid part_size: %s", err))
+	case partSize < minPartSize || partSize > maxPartSize:
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("part_size must be between 5MiB and 5GiB"))
+	default:
+		p.config.partSize = int64(partSize)
+	}
+
+	if p.config.Concurrency == 0 {
+		p.config.Concurrency = 4
+	}
+	if p.config.Concurrency < 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("concurrency must be positive"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	var generatedData map[interface{}]interface{}
+	stateData := source.State("generated_data")
+	if stateData != nil {
+		// Make sure it's not a nil map so we can assign to it later.
+		generatedData = stateData.(map[interface{}]interface{})
+	}
+	// If stateData has a nil map generatedData will be nil
+	// and we need to make sure it's not
+	if generatedData == nil {
+		generatedData = make(map[interface{}]interface{})
+	}
+
+	// These are extra variables that will be made available for interpolation.
+	generatedData["BuildName"] = p.config.PackerBuildName
+	generatedData["BuilderType"] = p.config.PackerBuilderType
+	p.config.ctx.Data = generatedData
+
+	client, region, err := p.newClient(ctx)
+	if err != nil {
+		return nil, false, false, err
+	}
+	u := &uploader{
+		client:      client,
+		bucket:      p.config.Bucket,
+		partSize:    p.config.partSize,
+		concurrency: p.config.Concurrency,
+		resume:      p.config.Resume,
+		sse:         types.ServerSideEncryption(p.config.ServerSideEncryption),
+	}
+	if p.config.SSEKMSKeyId != "" {
+		u.sseKMSKeyId = aws.String(p.config.SSEKMSKeyId)
+	}
+
+	artifact := &Artifact{client: client, bucket: p.config.Bucket}
+	for _, path := range source.Files() {
+		generatedData["FileName"] = filepath.Base(path)
+		key, err := interpolate.Render(p.config.Key, &p.config.ctx)
+		if err != nil {
+			return nil, false, false, fmt.Errorf("Error interpolating key for %s: %s", path, err)
+		}
+		tagging, err := p.tagging()
+		if err != nil {
+			return nil, false, false, err
+		}
+
+		objectURL := p.objectURL(region, key)
+		ui.Say(fmt.Sprintf("Uploading %s to %s", path, objectURL))
+		if err := u.upload(ctx, ui, path, key, tagging); err != nil {
+			return nil, false, false, fmt.Errorf("Unable to upload %s: %s", path, err)
+		}
+		artifact.keys = append(artifact.keys, key)
+		artifact.urls = append(artifact.urls, objectURL)
+	}
+
+	return artifact, true, false, nil
+}
+
+// newClient returns a client for the endpoint, and the region used.
+func (p *PostProcessor) newClient(ctx context.Context) (*s3.Client, string, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if p.config.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(p.config.Region))
+	}
+	if p.config.Profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(p.config.Profile))
+	}
+	if p.config.AccessKey != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			p.config.AccessKey, p.config.SecretKey, p.config.SessionToken)))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, "", fmt.Errorf("Unable to load the AWS configuration: %s", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if p.config.Endpoint != "" {
+			o.BaseEndpoint = aws.String(p.config.Endpoint)
+		}
+		o.UsePathStyle = p.config.ForcePathStyle
+		// The SHA256 checksums of the uploads are set explicitly, the
+		// checksums added by default are not supported by every
+		// S3-compatible storage.
+		o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+	})
+	return client, cfg.Region, nil
+}
+
+// tagging renders the tags as the query string expected by S3.
+func (p *PostProcessor) tagging() (string, error) {
+	names := make([]string, 0, len(p.config.Tags))
+	for name := range p.config.Tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		value, err := interpolate.Render(p.config.Tags[name], &p.config.ctx)
+		if err != nil {
+			return "", fmt.Errorf("Error interpolating tag %s: %s", name, err)
+		}
+		values = append(values, url.QueryEscape(name)+"="+url.QueryEscape(value))
+	}
+	return strings.Join(values, "&"), nil
+}
+
+// objectURL returns the URL of the object at key.
+func (p *PostProcessor) objectURL(region, key string) string {
+	path := (&url.URL{Path: "/" + key}).EscapedPath()
+	if p.config.Endpoint == "" {
+		if p.config.ForcePathStyle {
+			return fmt.Sprintf("https://s3.%s.amazonaws.com/%s%s", region, p.config.Bucket, path)
+		}
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", p.config.Bucket, region, path)
+	}
+
+	endpoint, _ := url.Parse(p.config.Endpoint)
+	if p.config.ForcePathStyle {
+		return strings.TrimSuffix(endpoint.String(), "/") + "/" + p.config.Bucket + path
+	}
+	endpoint.Host = p.config.Bucket + "." + endpoint.Host
+	return strings.TrimSuffix(endpoint.String(), "/") + path
+}
@@ -0,0 +1,222 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package s3_upload
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func testConfig(s *fakeS3, config map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"endpoint":          s.URL,
+		"region":            "us-east-1",
+		"force_path_style":  true,
+		"access_key":        "minioadmin",
+		"secret_key":        "minioadmin",
+		"bucket":            "images",
+		"packer_build_name": "vm",
+	}
+	for k, v := range config {
+		c[k] = v
+	}
+	return c
+}
+
+func testUpload(t *testing.T, config map[string]interface{}, files ...string) (packersdk.Artifact, error) {
+	var p PostProcessor
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	source := &packersdk.MockArtifact{
+		FilesValue:  files,
+		StateValues: map[string]interface{}{"generated_data": map[interface{}]interface{}{"ImageID": "1234"}},
+	}
+	artifact, _, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source)
+	return artifact, err
+}
+
+func writeFile(t *testing.T, name string, size int) (string, []byte) {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, data
+}
+
+func TestS3UploadObject(t *testing.T) {
+	s := newFakeS3(t)
+	path, data := writeFile(t, "image.raw", 1024)
+
+	artifact, err := testUpload(t, testConfig(s, map[string]interface{}{
+		"tags":                   map[string]string{"image-id": "{{.ImageID}}", "build": "{{.BuildName}}"},
+		"server_side_encryption": "aws:kms",
+		"sse_kms_key_id":         "alias/images",
+	}), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := s.URL + "/images/vm/image.raw"; artifact.Id() != expected {
+		t.Errorf("expected the ID %s, got %s", expected, artifact.Id())
+	}
+	object := s.object("vm/image.raw")
+	if object == nil || !bytes.Equal(object.data, data) {
+		t.Fatal("the object was not uploaded")
+	}
+	if sum := object.header.Get("X-Amz-Checksum-Sha256"); sum != checksum(data) {
+		t.Errorf("expected the checksum of the file to be sent, got %q", sum)
+	}
+	if tagging := object.header.Get("X-Amz-Tagging"); tagging != "build=vm&image-id=1234" {
+		t.Errorf("unexpected tags %q", tagging)
+	}
+	if sse := object.header.Get("X-Amz-Server-Side-Encryption"); sse != "aws:kms" {
+		t.Errorf("unexpected encryption %q", sse)
+	}
+	if key := object.header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"); key != "alias/images" {
+		t.Errorf("unexpected KMS key %q", key)
+	}
+
+	if err := artifact.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if s.object("vm/image.raw") != nil {
+		t.Error("expected Destroy to delete the object")
+	}
+}
+
+func TestS3UploadMultipart(t *testing.T) {
+	s := newFakeS3(t)
+	path, data := writeFile(t, "disk.vmdk", 11*1024*1024)
+
+	_, err := testUpload(t, testConfig(s, map[string]interface{}{
+		"key":         "{{.BuildName}}/{{.ImageID}}/{{.FileName}}",
+		"part_size":   "5MiB",
+		"concurrency": 2,
+		"tags":        map[string]string{"build": "{{.BuildName}}"},
+	}), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	object := s.object("vm/1234/disk.vmdk")
+	if object == nil || !bytes.Equal(object.data, data) {
+		t.Fatal("the object was not uploaded")
+	}
+	if s.partUploads != 3 {
+		t.Errorf("expected 3 parts, got %d", s.partUploads)
+	}
+	if tagging := object.header.Get("X-Amz-Tagging"); tagging != "build=vm" {
+		t.Errorf("unexpected tags %q", tagging)
+	}
+}
+
+func TestS3UploadResume(t *testing.T) {
+	s := newFakeS3(t)
+	path, data := writeFile(t, "disk.vmdk", 11*1024*1024)
+	config := testConfig(s, map[string]interface{}{
+		"part_size":   "5MiB",
+		"concurrency": 1,
+		"resume":      true,
+	})
+
+	s.failPart = 2
+	if _, err := testUpload(t, config, path); err == nil {
+		t.Fatal("expected the upload to fail")
+	}
+	if len(s.uploads) != 1 {
+		t.Fatalf("expected the failed upload to be kept, found %d uploads", len(s.uploads))
+	}
+
+	// The first part is not uploaded again.
+	s.failPart = 0
+	s.partUploads = 0
+	if _, err := testUpload(t, config, path); err != nil {
+		t.Fatal(err)
+	}
+	if s.partUploads != 2 {
+		t.Errorf("expected the 2 missing parts to be uploaded, got %d", s.partUploads)
+	}
+	if object := s.object("vm/disk.vmdk"); object == nil || !bytes.Equal(object.data, data) {
+		t.Fatal("the object was not uploaded")
+	}
+	if len(s.uploads) != 0 {
+		t.Errorf("expected the upload to be completed, found %d uploads", len(s.uploads))
+	}
+}
+
+func TestS3UploadAbort(t *testing.T) {
+	s := newFakeS3(t)
+	path, _ := writeFile(t, "disk.vmdk", 11*1024*1024)
+
+	s.failPart = 2
+	_, err := testUpload(t, testConfig(s, map[string]interface{}{"part_size": "5MiB"}), path)
+	if err == nil {
+		t.Fatal("expected the upload to fail")
+	}
+	if len(s.uploads) != 0 {
+		t.Errorf("expected the failed upload to be aborted, found %d uploads", len(s.uploads))
+	}
+}
+
+func TestS3UploadChecksumMismatch(t *testing.T) {
+	for _, size := range []int{1024, 11 * 1024 * 1024} {
+		s := newFakeS3(t)
+		path, _ := writeFile(t, "image.raw", size)
+
+		s.corrupt = true
+		_, err := testUpload(t, testConfig(s, map[string]interface{}{"part_size": "5MiB"}), path)
+		if err == nil {
+			t.Errorf("expected an object of %d bytes with another checksum to fail the upload", size)
+		}
+	}
+}
+
+func TestS3UploadObjectURL(t *testing.T) {
+	cases := []struct {
+		endpoint  string
+		pathStyle bool
+		expected  string
+	}{
+		{"", false, "https://images.s3.eu-west-1.amazonaws.com/vm/disk%201.raw"},
+		{"", true, "https://s3.eu-west-1.amazonaws.com/images/vm/disk%201.raw"},
+		{"http://localhost:9000", true, "http://localhost:9000/images/vm/disk%201.raw"},
+		{"https://storage.example.com/", false, "https://images.storage.example.com/vm/disk%201.raw"},
+	}
+	for _, c := range cases {
+		p := PostProcessor{config: Config{Endpoint: c.endpoint, ForcePathStyle: c.pathStyle, Bucket: "images"}}
+		if url := p.objectURL("eu-west-1", "vm/disk 1.raw"); url != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, url)
+		}
+	}
+}
+
+func TestS3UploadConfigure(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"missing bucket":       {},
+		"bad endpoint":         {"bucket": "b", "endpoint": "localhost:9000"},
+		"partial credentials":  {"bucket": "b", "access_key": "a"},
+		"bad encryption":       {"bucket": "b", "server_side_encryption": "rot13"},
+		"kms key without kms":  {"bucket": "b", "sse_kms_key_id": "alias/k"},
+		"part size too small":  {"bucket": "b", "part_size": "1MiB"},
+		"bad part size":        {"bucket": "b", "part_size": "large"},
+		"negative concurrency": {"bucket": "b", "concurrency": -1},
+		"bad key template":     {"bucket": "b", "key": "{{.FileName"},
+	}
+	for name, config := range cases {
+		var p PostProcessor
+		if err := p.Configure(config); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package s3_upload
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a stand-in for MinIO implementing the requests used by the
+// post-processor, on path-style URLs.
+type fakeS3 struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	uploads map[string]*fakeUpload
+	nextId  int
+
+	// Requests to upload this part fail.
+	failPart int
+	// Corrupt the objects once their checksum is verified.
+	corrupt bool
+	// The number of UploadPart requests.
+	partUploads int
+}
+
+type fakeObject struct {
+	data     []byte
+	checksum string
+	header   http.Header
+}
+
+type fakeUpload struct {
+	key       string
+	initiated time.Time
+	header    http.Header
+	parts     map[int][]byte
+}
+
+type fakePart struct {
+	PartNumber     int
+	ETag           string
+	Size           int
+	ChecksumSHA256 string
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	s := &fakeS3{objects: map[string]*fakeObject{}, uploads: map[string]*fakeUpload{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func (s *fakeS3) object(key string) *fakeObject {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects[key]
+}
+
+func (s *fakeS3) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	query := r.URL.Query()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if sum := r.Header.Get("X-Amz-Checksum-Sha256"); sum != "" && sum != checksum(body) {
+		writeError(w, http.StatusBadRequest, "BadDigest")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.nextId++
+		id := fmt.Sprintf("upload-%d", s.nextId)
+		s.uploads[id] = &fakeUpload{key: key, initiated: time.Now(), header: r.Header, parts: map[int][]byte{}}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Key      string
+			UploadId string
+		}{Key: key, UploadId: id})
+
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		upload, ok := s.uploads[query.Get("uploadId")]
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		if number == s.failPart {
+			writeError(w, http.StatusBadRequest, "InvalidRequest")
+			return
+		}
+		s.partUploads++
+		upload.parts[number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, number))
+		w.Header().Set("X-Amz-Checksum-Sha256", checksum(body))
+
+	case r.Method == http.MethodGet && query.Has("uploads"):
+		type upload struct {
+			Key               string
+			UploadId          string
+			Initiated         string
+			ChecksumAlgorithm string
+		}
+		result := struct {
+			XMLName     xml.Name `xml:"ListMultipartUploadsResult"`
+			IsTruncated bool
+			Upload      []upload
+		}{}
+		for id, u := range s.uploads {
+			if strings.HasPrefix(u.key, query.Get("prefix")) {
+				result.Upload = append(result.Upload, upload{u.key, id, u.initiated.Format(time.RFC3339), "SHA256"})
+			}
+		}
+		writeXML(w, result)
+
+	case r.Method == http.MethodGet && query.Has("uploadId"):
+		upload, ok := s.uploads[query.Get("uploadId")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		result := struct {
+			XMLName     xml.Name `xml:"ListPartsResult"`
+			IsTruncated bool
+			Part        []fakePart
+		}{}
+		for number, data := range upload.parts {
+			result.Part = append(result.Part, fakePart{number, fmt.Sprintf(`"%d"`, number), len(data), checksum(data)})
+		}
+		writeXML(w, result)
+
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		upload, ok := s.uploads[query.Get("uploadId")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		var complete struct {
+			Part []fakePart
+		}
+		if err := xml.Unmarshal(body, &complete); err != nil {
+			writeError(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		var data []byte
+		h := sha256.New()
+		for i, part := range complete.Part {
+			partData, ok := upload.parts[part.PartNumber]
+			if !ok || part.PartNumber != i+1 || part.ChecksumSHA256 != checksum(partData) {
+				writeError(w, http.StatusBadRequest, "InvalidPart")
+				return
+			}
+			data = append(data, partData...)
+			sum := sha256.Sum256(partData)
+			h.Write(sum[:])
+		}
+		sum := fmt.Sprintf("%s-%d", base64.StdEncoding.EncodeToString(h.Sum(nil)), len(complete.Part))
+		s.store(upload.key, data, sum, upload.header)
+		delete(s.uploads, query.Get("uploadId"))
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Key     string
+		}{Key: upload.key})
+
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(s.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodPut:
+		s.store(key, body, checksum(body), r.Header)
+
+	case r.Method == http.MethodHead:
+		object, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.data)))
+		if r.Header.Get("X-Amz-Checksum-Mode") == "ENABLED" {
+			w.Header().Set("X-Amz-Checksum-Sha256", object.checksum)
+		}
+
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (s *fakeS3) store(key string, data []byte, sum string, header http.Header) {
+	if s.corrupt {
+		data = append([]byte{data[0] ^ 1}, data[1:]...)
+		sum = checksum(data)
+	}
+	s.objects[key] = &fakeObject{data: data, checksum: sum, header: header}
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
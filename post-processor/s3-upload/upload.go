@@ -0,0 +1,275 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package s3_upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"golang.org/x/sync/errgroup"
+)
+
+// The number of parts of a multipart upload is limited by S3.
+const maxParts = 10000
+
+// uploader uploads files to a bucket, with a SHA256 checksum verified by
+// the storage for each request and compared once the object is complete.
+type uploader struct {
+	client      *s3.Client
+	bucket      string
+	partSize    int64
+	concurrency int
+	resume      bool
+	sse         types.ServerSideEncryption
+	sseKMSKeyId *string
+}
+
+func (u *uploader) upload(ctx context.Context, ui packersdk.Ui, path, key, tagging string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if size <= u.partSize {
+		return u.putObject(ctx, f, size, key, tagging)
+	}
+	return u.multipartUpload(ctx, ui, f, size, key, tagging)
+}
+
+func (u *uploader) putObject(ctx context.Context, f *os.File, size int64, key, tagging string) error {
+	sum, err := sha256Sum(io.NewSectionReader(f, 0, size))
+	if err != nil {
+		return err
+	}
+	checksum := base64.StdEncoding.EncodeToString(sum)
+	_, err = u.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(u.bucket),
+		Key:                  aws.String(key),
+		Body:                 io.NewSectionReader(f, 0, size),
+		ContentLength:        aws.Int64(size),
+		ChecksumAlgorithm:    types.ChecksumAlgorithmSha256,
+		ChecksumSHA256:       aws.String(checksum),
+		ServerSideEncryption: u.sse,
+		SSEKMSKeyId:          u.sseKMSKeyId,
+		Tagging:              optional(tagging),
+	})
+	if err != nil {
+		return err
+	}
+	return u.verify(ctx, key, size, checksum)
+}
+
+func (u *uploader) multipartUpload(ctx context.Context, ui packersdk.Ui, f *os.File, size int64, key, tagging string) error {
+	partSize := u.partSize
+	if (size+partSize-1)/partSize > maxParts {
+		partSize = (size + maxParts - 1) / maxParts
+	}
+	count := int((size + partSize - 1) / partSize)
+
+	uploadId, uploaded, err := u.resumableUpload(ctx, key)
+	if err != nil {
+		return err
+	}
+	if uploadId == "" {
+		out, err := u.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:               aws.String(u.bucket),
+			Key:                  aws.String(key),
+			ChecksumAlgorithm:    types.ChecksumAlgorithmSha256,
+			ServerSideEncryption: u.sse,
+			SSEKMSKeyId:          u.sseKMSKeyId,
+			Tagging:              optional(tagging),
+		})
+		if err != nil {
+			return err
+		}
+		uploadId = aws.ToString(out.UploadId)
+	}
+
+	completed := false
+	defer func() {
+		if completed || u.resume {
+			return
+		}
+		_, err := u.client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(u.bucket),
+			Key:      aws.String(key),
+			UploadId: aws.String(uploadId),
+		})
+		if err != nil {
+			log.Printf("Unable to abort the upload of %s: %s", key, err)
+		}
+	}()
+
+	parts := make([]types.CompletedPart, count)
+	sums := make([][]byte, count)
+	var reused atomic.Int64
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(u.concurrency)
+	for i := 0; i < count; i++ {
+		g.Go(func() error {
+			number := int32(i + 1)
+			offset := int64(i) * partSize
+			length := min(partSize, size-offset)
+			sum, err := sha256Sum(io.NewSectionReader(f, offset, length))
+			if err != nil {
+				return err
+			}
+			sums[i] = sum
+			checksum := base64.StdEncoding.EncodeToString(sum)
+
+			if part, ok := uploaded[number]; ok && aws.ToInt64(part.Size) == length && aws.ToString(part.ChecksumSHA256) == checksum {
+				parts[i] = types.CompletedPart{PartNumber: part.PartNumber, ETag: part.ETag, ChecksumSHA256: part.ChecksumSHA256}
+				reused.Add(1)
+				return nil
+			}
+
+			out, err := u.client.UploadPart(gctx, &s3.UploadPartInput{
+				Bucket:            aws.String(u.bucket),
+				Key:               aws.String(key),
+				UploadId:          aws.String(uploadId),
+				PartNumber:        aws.Int32(number),
+				Body:              io.NewSectionReader(f, offset, length),
+				ContentLength:     aws.Int64(length),
+				ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+				ChecksumSHA256:    aws.String(checksum),
+			})
+			if err != nil {
+				return fmt.Errorf("part %d: %s", number, err)
+			}
+			parts[i] = types.CompletedPart{PartNumber: aws.Int32(number), ETag: out.ETag, ChecksumSHA256: aws.String(checksum)}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	if n := reused.Load(); n > 0 {
+		ui.Message(fmt.Sprintf("Resumed the upload of %s, %d of %d parts were already uploaded", key, n, count))
+	}
+
+	_, err = u.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(u.bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadId),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return err
+	}
+	completed = true
+
+	// The checksum of multipart objects is the checksum of the checksums
+	// of the parts, with the number of parts.
+	h := sha256.New()
+	for _, sum := range sums {
+		h.Write(sum)
+	}
+	return u.verify(ctx, key, size, fmt.Sprintf("%s-%d", base64.StdEncoding.EncodeToString(h.Sum(nil)), count))
+}
+
+// resumableUpload returns the ID and the parts of the last multipart upload
+// of key left by a previous build, when resuming uploads.
+func (u *uploader) resumableUpload(ctx context.Context, key string) (string, map[int32]types.Part, error) {
+	if !u.resume {
+		return "", nil, nil
+	}
+
+	var last *types.MultipartUpload
+	input := &s3.ListMultipartUploadsInput{Bucket: aws.String(u.bucket), Prefix: aws.String(key)}
+	for {
+		out, err := u.client.ListMultipartUploads(ctx, input)
+		if err != nil {
+			return "", nil, fmt.Errorf("Unable to list the multipart uploads: %s", err)
+		}
+		for i, upload := range out.Uploads {
+			// Uploads without SHA256 checksums can't be compared.
+			if aws.ToString(upload.Key) != key || upload.ChecksumAlgorithm != types.ChecksumAlgorithmSha256 {
+				continue
+			}
+			if last == nil || aws.ToTime(upload.Initiated).After(aws.ToTime(last.Initiated)) {
+				last = &out.Uploads[i]
+			}
+		}
+		if !aws.ToBool(out.IsTruncated) {
+			break
+		}
+		input.KeyMarker = out.NextKeyMarker
+		input.UploadIdMarker = out.NextUploadIdMarker
+	}
+	if last == nil {
+		return "", nil, nil
+	}
+
+	uploaded := map[int32]types.Part{}
+	partsInput := &s3.ListPartsInput{Bucket: aws.String(u.bucket), Key: aws.String(key), UploadId: last.UploadId}
+	for {
+		out, err := u.client.ListParts(ctx, partsInput)
+		if err != nil {
+			return "", nil, fmt.Errorf("Unable to list the parts of upload %s: %s", aws.ToString(last.UploadId), err)
+		}
+		for _, part := range out.Parts {
+			uploaded[aws.ToInt32(part.PartNumber)] = part
+		}
+		if !aws.ToBool(out.IsTruncated) {
+			break
+		}
+		partsInput.PartNumberMarker = out.NextPartNumberMarker
+	}
+	return aws.ToString(last.UploadId), uploaded, nil
+}
+
+// verify compares the size and checksum of the uploaded object with the
+// ones of the file.
+func (u *uploader) verify(ctx context.Context, key string, size int64, checksum string) error {
+	out, err := u.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(u.bucket),
+		Key:          aws.String(key),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to verify the uploaded object: %s", err)
+	}
+	if aws.ToInt64(out.ContentLength) != size {
+		return fmt.Errorf("the uploaded object has %d bytes, expected %d", aws.ToInt64(out.ContentLength), size)
+	}
+	if out.ChecksumSHA256 == nil {
+		log.Printf("The storage returned no checksum for %s, only its size was verified", key)
+		return nil
+	}
+	if aws.ToString(out.ChecksumSHA256) != checksum {
+		return fmt.Errorf("the checksum of the uploaded object is %s, expected %s", aws.ToString(out.ChecksumSHA256), checksum)
+	}
+	return nil
+}
+
+func sha256Sum(r io.Reader) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
@@ -12,7 +12,9 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
@@ -21,6 +23,7 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/hashicorp/packer-plugin-sdk/tmp"
+	"github.com/zclconf/go-cty/cty"
 )
 
 type Config struct {
@@ -67,8 +70,41 @@ type Config struct {
 	// the Packer run, but realize that there are situations where this may be
 	// unavoidable.
 	Generated bool `mapstructure:"generated" required:"false"`
+	// If true, uploaded directories are synchronized with the destination
+	// instead of being copied over: the SHA256 of the local files is compared
+	// with the one of the remote files, and only files that changed or are
+	// missing are uploaded. This requires a POSIX shell with `find` and
+	// `sha256sum` or `shasum` on the machine, and fails over WinRM or with
+	// Windows guests. Only valid when uploading.
+	Sync bool `mapstructure:"sync" required:"false"`
+	// If true, remote files in a synchronized directory that do not exist
+	// locally are deleted. Files matching `exclude` are never deleted.
+	// Requires `sync`.
+	Delete bool `mapstructure:"delete" required:"false"`
+	// Glob patterns of files and directories to skip when synchronizing a
+	// directory, for example `["*.log", ".git"]`. Patterns are matched
+	// against the path relative to the synchronized directory, using forward
+	// slashes, and against the base name of each file. Requires `sync`.
+	Exclude []string `mapstructure:"exclude" required:"false"`
+	// The permissions to set on the files uploaded by `sync`, in octal, for
+	// example `"0644"`. Files that are up to date are left untouched.
+	// Requires `sync`.
+	Mode string `mapstructure:"mode" required:"false"`
+	// The owner to set on the files uploaded by `sync`, as accepted by
+	// `chown`, for example `"app:app"`. Files that are up to date are left
+	// untouched. The provisioning user must be allowed to change the
+	// ownership of the files. Requires `sync`.
+	Owner string `mapstructure:"owner" required:"false"`
+	// Variables to render the files of `source` or `sources` with before
+	// uploading them. Files are rendered like with the `templatefile`
+	// function, and the data generated by the builder is available under
+	// `build`, for example `${build.Host}`. Directories are rendered file by
+	// file. This cannot be used with `content` or when downloading.
+	TemplateVars map[string]string `mapstructure:"template_vars" required:"false"`
 
 	ctx interpolate.Context
+	// vars are the decoded template variables, nil if none is set.
+	vars map[string]cty.Value
 }
 
 type Provisioner struct {
@@ -123,6 +159,46 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 			errors.New("Destination must be specified."))
 	}
 
+	if p.config.Sync && p.config.Direction != "upload" {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("sync can only be used when uploading."))
+	}
+
+	if !p.config.Sync && (p.config.Delete || len(p.config.Exclude) > 0 || p.config.Mode != "" || p.config.Owner != "") {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("delete, exclude, mode and owner require sync to be set."))
+	}
+
+	if p.config.TemplateVars != nil {
+		if p.config.Direction != "upload" {
+			errs = packersdk.MultiErrorAppend(errs,
+				errors.New("template_vars can only be used when uploading."))
+		}
+		if p.config.Content != "" {
+			errs = packersdk.MultiErrorAppend(errs,
+				errors.New("template_vars conflicts with content."))
+		}
+		vars, err := decodeTemplateVars(p.config.TemplateVars)
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+		p.config.vars = vars
+	}
+
+	for _, pattern := range p.config.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("Bad exclude pattern %q: %s", pattern, err))
+		}
+	}
+
+	if p.config.Mode != "" {
+		if _, err := strconv.ParseUint(p.config.Mode, 8, 32); err != nil {
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("Bad mode %q: must be an octal number such as 0644", p.config.Mode))
+		}
+	}
+
 	if errs != nil && len(errs.Errors) > 0 {
 		return errs
 	}
@@ -151,8 +227,15 @@ func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, comm packe
 
 	if p.config.Direction == "download" {
 		return p.ProvisionDownload(ui, comm)
+	} else if p.config.Sync {
+		connType, _ := generatedData["ConnType"].(string)
+		if err := checkSyncSupported(ctx, comm, connType); err != nil {
+			ui.Error(fmt.Sprintf("Sync failed: %s", err))
+			return err
+		}
+		return p.ProvisionUpload(ctx, ui, comm)
 	} else {
-		return p.ProvisionUpload(ui, comm)
+		return p.ProvisionUpload(ctx, ui, comm)
 	}
 }
 
@@ -207,7 +290,7 @@ func (p *Provisioner) ProvisionDownload(ui packersdk.Ui, comm packersdk.Communic
 	return nil
 }
 
-func (p *Provisioner) ProvisionUpload(ui packersdk.Ui, comm packersdk.Communicator) error {
+func (p *Provisioner) ProvisionUpload(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator) error {
 	dst, err := interpolate.Render(p.config.Destination, &p.config.ctx)
 	if err != nil {
 		return fmt.Errorf("Error interpolating destination: %s", err)
@@ -220,11 +303,28 @@ func (p *Provisioner) ProvisionUpload(ui packersdk.Ui, comm packersdk.Communicat
 
 		ui.Say(fmt.Sprintf("Uploading %s => %s", src, dst))
 
+		if p.config.vars != nil {
+			rendered, cleanup, err := p.renderSource(src)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			src = rendered
+		}
+
 		info, err := os.Stat(src)
 		if err != nil {
 			return err
 		}
 
+		if p.config.Sync {
+			if err := p.syncUpload(ctx, ui, comm, src, dst, info); err != nil {
+				ui.Error(fmt.Sprintf("Sync failed: %s", err))
+				return err
+			}
+			continue
+		}
+
 		// If we're uploading a directory, short circuit and do that
 		if info.IsDir() {
 			if err = comm.UploadDir(dst, src, nil); err != nil {
@@ -24,6 +24,12 @@ type FlatConfig struct {
 	Destination         *string           `mapstructure:"destination" required:"true" cty:"destination" hcl:"destination"`
 	Direction           *string           `mapstructure:"direction" required:"false" cty:"direction" hcl:"direction"`
 	Generated           *bool             `mapstructure:"generated" required:"false" cty:"generated" hcl:"generated"`
+	Sync                *bool             `mapstructure:"sync" required:"false" cty:"sync" hcl:"sync"`
+	Delete              *bool             `mapstructure:"delete" required:"false" cty:"delete" hcl:"delete"`
+	Exclude             []string          `mapstructure:"exclude" required:"false" cty:"exclude" hcl:"exclude"`
+	Mode                *string           `mapstructure:"mode" required:"false" cty:"mode" hcl:"mode"`
+	Owner               *string           `mapstructure:"owner" required:"false" cty:"owner" hcl:"owner"`
+	TemplateVars        map[string]string `mapstructure:"template_vars" required:"false" cty:"template_vars" hcl:"template_vars"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -52,6 +58,12 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"destination":                &hcldec.AttrSpec{Name: "destination", Type: cty.String, Required: false},
 		"direction":                  &hcldec.AttrSpec{Name: "direction", Type: cty.String, Required: false},
 		"generated":                  &hcldec.AttrSpec{Name: "generated", Type: cty.Bool, Required: false},
+		"sync":                       &hcldec.AttrSpec{Name: "sync", Type: cty.Bool, Required: false},
+		"delete":                     &hcldec.AttrSpec{Name: "delete", Type: cty.Bool, Required: false},
+		"exclude":                    &hcldec.AttrSpec{Name: "exclude", Type: cty.List(cty.String), Required: false},
+		"mode":                       &hcldec.AttrSpec{Name: "mode", Type: cty.String, Required: false},
+		"owner":                      &hcldec.AttrSpec{Name: "owner", Type: cty.String, Required: false},
+		"template_vars":              &hcldec.AttrSpec{Name: "template_vars", Type: cty.Map(cty.String), Required: false},
 	}
 	return s
 }
@@ -6,9 +6,14 @@ package file
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -502,3 +507,428 @@ func TestProvisionDownloadMkdirAll(t *testing.T) {
 		}
 	}
 }
+
+// localCommunicator runs commands and uploads files on the local machine, so
+// that sync can be tested against a real shell.
+type localCommunicator struct {
+	packersdk.MockCommunicator
+	commands []string
+	uploaded []string
+}
+
+func (c *localCommunicator) Start(ctx context.Context, cmd *packersdk.RemoteCmd) error {
+	c.commands = append(c.commands, cmd.Command)
+	sh := exec.CommandContext(ctx, "/bin/sh", "-c", cmd.Command)
+	sh.Stdout = cmd.Stdout
+	sh.Stderr = cmd.Stderr
+	err := sh.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		cmd.SetExited(exitErr.ExitCode())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cmd.SetExited(0)
+	return nil
+}
+
+func (c *localCommunicator) Upload(dst string, r io.Reader, fi *os.FileInfo) error {
+	c.uploaded = append(c.uploaded, dst)
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, content, 0600)
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProvisionerPrepare_Sync(t *testing.T) {
+	src := t.TempDir()
+
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"sync", map[string]interface{}{"sync": true, "delete": true, "exclude": []string{"*.log"}, "mode": "0640", "owner": "app"}, false},
+		{"delete without sync", map[string]interface{}{"delete": true}, true},
+		{"exclude without sync", map[string]interface{}{"exclude": []string{"*.log"}}, true},
+		{"bad pattern", map[string]interface{}{"sync": true, "exclude": []string{"[a"}}, true},
+		{"bad mode", map[string]interface{}{"sync": true, "mode": "rw-r--r--"}, true},
+		{"download", map[string]interface{}{"sync": true, "direction": "download"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config["source"] = src
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			var p Provisioner
+			err := p.Prepare(config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Prepare() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProvisionerProvision_Sync(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sync runs POSIX shell commands")
+	}
+
+	src := t.TempDir()
+	remote := t.TempDir()
+	writeTestFiles(t, src, map[string]string{
+		"app/unchanged.txt":   "unchanged",
+		"app/changed.txt":     "new content",
+		"app/new/file.txt":    "new file",
+		"app/debug.log":       "excluded",
+		"app/cache/data.bin":  "excluded dir",
+		"app/with 'quote.txt": "quoted",
+		"app/back\\slash.txt": "escaped",
+		"app/new\nline.txt":   "escaped",
+	})
+	writeTestFiles(t, remote, map[string]string{
+		"app/unchanged.txt":   "unchanged",
+		"app/back\\slash.txt": "escaped",
+		"app/new\nline.txt":   "escaped",
+		"app/changed.txt":     "old content",
+		"app/stale.txt":       "stale",
+		"app/remote.log":      "kept as excluded",
+	})
+
+	var p Provisioner
+	err := p.Prepare(map[string]interface{}{
+		"source":      filepath.Join(src, "app"),
+		"destination": remote + "/",
+		"sync":        true,
+		"delete":      true,
+		"exclude":     []string{"*.log", "cache"},
+		"mode":        "0640",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	b := bytes.NewBuffer(nil)
+	ui := &packersdk.BasicUi{
+		Writer: b,
+		PB:     &packersdk.NoopProgressTracker{},
+	}
+	comm := &localCommunicator{}
+	if err := p.Provision(context.Background(), ui, comm, make(map[string]interface{})); err != nil {
+		t.Fatalf("should successfully provision: %s", err)
+	}
+
+	wantUploaded := []string{
+		filepath.Join(remote, "app", "changed.txt"),
+		filepath.Join(remote, "app", "new", "file.txt"),
+		filepath.Join(remote, "app", "with 'quote.txt"),
+	}
+	if !reflect.DeepEqual(comm.uploaded, wantUploaded) {
+		t.Errorf("expected to upload %v, uploaded %v", wantUploaded, comm.uploaded)
+	}
+
+	for name, content := range map[string]string{
+		"app/unchanged.txt":   "unchanged",
+		"app/changed.txt":     "new content",
+		"app/new/file.txt":    "new file",
+		"app/with 'quote.txt": "quoted",
+		"app/remote.log":      "kept as excluded",
+	} {
+		got, err := os.ReadFile(filepath.Join(remote, name))
+		if err != nil {
+			t.Errorf("expected %s to exist: %s", name, err)
+			continue
+		}
+		if string(got) != content {
+			t.Errorf("unexpected content for %s: %q", name, got)
+		}
+		// Only the uploaded files get the mode.
+		wantMode := os.FileMode(0640)
+		if name == "app/remote.log" || name == "app/unchanged.txt" {
+			wantMode = 0644
+		}
+		fi, _ := os.Stat(filepath.Join(remote, name))
+		if fi.Mode().Perm() != wantMode {
+			t.Errorf("expected %s to have mode %s, got %s", name, wantMode, fi.Mode().Perm())
+		}
+	}
+	for _, name := range []string{"app/stale.txt", "app/debug.log", "app/cache/data.bin"} {
+		if _, err := os.Stat(filepath.Join(remote, name)); err == nil {
+			t.Errorf("expected %s to not exist", name)
+		}
+	}
+
+	if !strings.Contains(b.String(), "3 file(s) up to date, uploading 3 file(s), deleting 1 file(s)") {
+		t.Errorf("unexpected output: %s", b.String())
+	}
+
+	// A second run has nothing to do
+	comm = &localCommunicator{}
+	if err := p.Provision(context.Background(), ui, comm, make(map[string]interface{})); err != nil {
+		t.Fatalf("should successfully provision: %s", err)
+	}
+	if len(comm.uploaded) != 0 {
+		t.Errorf("expected no upload, uploaded %v", comm.uploaded)
+	}
+}
+
+func TestProvisionerProvision_SyncUnsupported(t *testing.T) {
+	var p Provisioner
+	err := p.Prepare(map[string]interface{}{
+		"source":      t.TempDir(),
+		"destination": "C:/app",
+		"sync":        true,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := &packersdk.BasicUi{
+		Writer:      io.Discard,
+		ErrorWriter: io.Discard,
+		PB:          &packersdk.NoopProgressTracker{},
+	}
+	comm := &packersdk.MockCommunicator{}
+	err = p.Provision(context.Background(), ui, comm, map[string]interface{}{"ConnType": "winrm"})
+	if err == nil || !strings.Contains(err.Error(), "WinRM") {
+		t.Fatalf("expected sync over WinRM to fail, got %v", err)
+	}
+
+	// Commands that do not run in a POSIX shell fail.
+	comm = &packersdk.MockCommunicator{StartExitStatus: 1}
+	err = p.Provision(context.Background(), ui, comm, map[string]interface{}{"ConnType": "ssh"})
+	if err == nil || !strings.Contains(err.Error(), "POSIX shell") {
+		t.Fatalf("expected sync without a POSIX shell to fail, got %v", err)
+	}
+	if comm.UploadCalled {
+		t.Error("expected no upload")
+	}
+}
+
+func TestProvisionerProvision_SyncFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sync runs POSIX shell commands")
+	}
+
+	src := t.TempDir()
+	remote := t.TempDir()
+	writeTestFiles(t, src, map[string]string{"payload.bin": "payload"})
+
+	var p Provisioner
+	err := p.Prepare(map[string]interface{}{
+		"source":      filepath.Join(src, "payload.bin"),
+		"destination": filepath.Join(remote, "target.bin"),
+		"sync":        true,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := &packersdk.BasicUi{
+		Writer: io.Discard,
+		PB:     &packersdk.NoopProgressTracker{},
+	}
+	for i, wantUploads := range []int{1, 0} {
+		comm := &localCommunicator{}
+		if err := p.Provision(context.Background(), ui, comm, make(map[string]interface{})); err != nil {
+			t.Fatalf("should successfully provision: %s", err)
+		}
+		if len(comm.uploaded) != wantUploads {
+			t.Errorf("run %d: expected %d upload(s), got %v", i, wantUploads, comm.uploaded)
+		}
+	}
+	got, _ := os.ReadFile(filepath.Join(remote, "target.bin"))
+	if string(got) != "payload" {
+		t.Errorf("unexpected content %q", got)
+	}
+}
+
+func TestProvisionerProvision_TemplateVars(t *testing.T) {
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{
+		"app.conf": "listen ${build.Host}:${port}\nname = ${upper(name)}\n",
+	})
+
+	var p Provisioner
+	err := p.Prepare(map[string]interface{}{
+		"source":      filepath.Join(src, "app.conf"),
+		"destination": "/etc/app/",
+		"template_vars": map[string]string{
+			"port": "8080",
+			"name": "app",
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := &packersdk.BasicUi{
+		Writer: io.Discard,
+		PB:     &packersdk.NoopProgressTracker{},
+	}
+	comm := &packersdk.MockCommunicator{}
+	err = p.Provision(context.Background(), ui, comm, map[string]interface{}{"Host": "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("should successfully provision: %s", err)
+	}
+
+	if comm.UploadPath != "/etc/app/app.conf" {
+		t.Errorf("unexpected upload path %q", comm.UploadPath)
+	}
+	if want := "listen 10.0.0.1:8080\nname = APP\n"; comm.UploadData != want {
+		t.Errorf("expected rendered content %q, got %q", want, comm.UploadData)
+	}
+}
+
+func TestProvisionerProvision_TemplateVarsBuildData(t *testing.T) {
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{
+		"users.conf": "%{ for user in split(\",\", users) ~}\n${user}:${port + 1}\n%{ endfor ~}\n${build.Disks.root}:${build.Count * 2}\n",
+	})
+
+	var p Provisioner
+	err := p.Prepare(map[string]interface{}{
+		"source":        filepath.Join(src, "users.conf"),
+		"destination":   "/etc/app/",
+		"template_vars": map[string]string{"users": "alice,bob", "port": "8080"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := &packersdk.BasicUi{
+		Writer: io.Discard,
+		PB:     &packersdk.NoopProgressTracker{},
+	}
+	comm := &packersdk.MockCommunicator{}
+	err = p.Provision(context.Background(), ui, comm, map[string]interface{}{
+		"Count": 2,
+		// As read through RPC.
+		"Disks": map[interface{}]interface{}{"root": "/dev/sda"},
+	})
+	if err != nil {
+		t.Fatalf("should successfully provision: %s", err)
+	}
+
+	if want := "alice:8081\nbob:8081\n/dev/sda:4\n"; comm.UploadData != want {
+		t.Errorf("expected rendered content %q, got %q", want, comm.UploadData)
+	}
+}
+
+func TestProvisionerRenderSource_Directory(t *testing.T) {
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{
+		"conf/a.conf":     "a = ${value}",
+		"conf/sub/b.conf": "b = ${build.ID}",
+	})
+
+	var p Provisioner
+	err := p.Prepare(map[string]interface{}{
+		"source":        filepath.Join(src, "conf") + "/",
+		"destination":   "/etc/app/",
+		"template_vars": map[string]string{"value": "42"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	p.config.ctx.Data = map[string]interface{}{"ID": "i-123"}
+
+	rendered, cleanup, err := p.renderSource(p.config.Sources[0])
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer cleanup()
+
+	if !strings.HasSuffix(rendered, string(filepath.Separator)) || filepath.Base(rendered) != "conf" {
+		t.Errorf("rendered path should keep the name and trailing slash of the source, got %q", rendered)
+	}
+	for name, want := range map[string]string{
+		"a.conf":     "a = 42",
+		"sub/b.conf": "b = i-123",
+	} {
+		got, err := os.ReadFile(filepath.Join(rendered, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if string(got) != want {
+			t.Errorf("expected %s to be rendered as %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestProvisionerPrepare_TemplateVars(t *testing.T) {
+	src := t.TempDir()
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+	}{
+		{"download", map[string]interface{}{"source": src, "direction": "download"}},
+		{"content", map[string]interface{}{"content": "foo"}},
+		{"reserved build", map[string]interface{}{"source": src, "template_vars": map[string]string{"build": "x"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config["template_vars"] = map[string]string{"foo": "bar"}
+			for k, v := range tt.config {
+				config[k] = v
+			}
+			var p Provisioner
+			if err := p.Prepare(config); err == nil {
+				t.Fatal("should have error")
+			}
+		})
+	}
+}
+
+func TestProvisionerProvision_SyncCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sync runs POSIX shell commands")
+	}
+
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{"app/file.txt": "content"})
+
+	var p Provisioner
+	err := p.Prepare(map[string]interface{}{
+		"source":      filepath.Join(src, "app"),
+		"destination": t.TempDir() + "/",
+		"sync":        true,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ui := &packersdk.BasicUi{
+		Writer:      io.Discard,
+		ErrorWriter: io.Discard,
+		PB:          &packersdk.NoopProgressTracker{},
+	}
+	comm := &localCommunicator{}
+	if err := p.Provision(ctx, ui, comm, make(map[string]interface{})); err == nil {
+		t.Fatal("expected a cancelled sync to fail")
+	}
+	if len(comm.uploaded) != 0 {
+		t.Errorf("expected no upload, uploaded %v", comm.uploaded)
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// syncBatchSize is the maximum number of paths passed to a single remote
+// command, to stay well below the maximum command line length.
+const syncBatchSize = 200
+
+// remoteSumCommand selects the tool used to compute remote checksums, both
+// output lines formatted as `<sha256>  <path>`.
+const remoteSumCommand = `if command -v sha256sum >/dev/null 2>&1; then sum=sha256sum; else sum="shasum -a 256"; fi`
+
+// syncCheckCommand succeeds when the remote end has the tools sync depends
+// on. It fails on Windows guests, where commands do not run in a POSIX
+// shell.
+const syncCheckCommand = `command -v find >/dev/null 2>&1 && { command -v sha256sum || command -v shasum; } >/dev/null 2>&1`
+
+// checkSyncSupported returns an error when sync cannot run on the remote
+// end: over WinRM, or without a POSIX shell with find and sha256sum or
+// shasum.
+func checkSyncSupported(ctx context.Context, comm packersdk.Communicator, connType string) error {
+	if connType == "winrm" {
+		return fmt.Errorf("sync is not supported over WinRM, it requires a POSIX shell on the machine")
+	}
+	if _, err := runRemote(ctx, comm, syncCheckCommand); err != nil {
+		return fmt.Errorf("sync requires a POSIX shell with find and sha256sum or shasum on the machine, Windows guests are not supported: %s", err)
+	}
+	return nil
+}
+
+// syncUpload uploads src to dst, skipping the files that already exist with
+// the same content on the remote end.
+func (p *Provisioner) syncUpload(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator, src, dst string, info os.FileInfo) error {
+	// root is the remote directory files are synchronized into, and
+	// localFiles maps the path of each file relative to root to its local
+	// path.
+	var root string
+	var localFiles map[string]string
+	var remoteSumCmd string
+	if info.IsDir() {
+		root = strings.TrimSuffix(dst, "/")
+		if !strings.HasSuffix(src, "/") && !strings.HasSuffix(src, string(filepath.Separator)) {
+			root = path.Join(root, filepath.Base(src))
+		}
+		files, err := p.listSyncFiles(src)
+		if err != nil {
+			return err
+		}
+		localFiles = files
+		remoteSumCmd = fmt.Sprintf(`%s; if [ -d %s ]; then cd %s && find . -type f -exec $sum {} +; fi`,
+			remoteSumCommand, shellQuote(root), shellQuote(root))
+	} else {
+		filedst := dst
+		if strings.HasSuffix(dst, "/") {
+			filedst = dst + filepath.Base(src)
+		}
+		root = path.Dir(filedst)
+		name := path.Base(filedst)
+		localFiles = map[string]string{name: src}
+		remoteSumCmd = fmt.Sprintf(`%s; if [ -f %s ]; then cd %s && $sum %s; fi`,
+			remoteSumCommand, shellQuote(filedst), shellQuote(root), shellQuote("./"+name))
+	}
+
+	ui.Say(fmt.Sprintf("Synchronizing %s => %s", src, root))
+
+	remoteSums, err := remoteChecksums(ctx, comm, remoteSumCmd)
+	if err != nil {
+		return fmt.Errorf("failed to list remote checksums: %s", err)
+	}
+
+	var toUpload []string
+	for rel, local := range localFiles {
+		localSum, err := fileChecksum(local)
+		if err != nil {
+			return err
+		}
+		if remoteSums[rel] != localSum {
+			toUpload = append(toUpload, rel)
+		}
+	}
+	sort.Strings(toUpload)
+
+	var toDelete []string
+	if p.config.Delete && info.IsDir() {
+		for rel := range remoteSums {
+			if _, ok := localFiles[rel]; !ok && !p.excluded(rel) {
+				toDelete = append(toDelete, rel)
+			}
+		}
+		sort.Strings(toDelete)
+	}
+
+	ui.Say(fmt.Sprintf("%d file(s) up to date, uploading %d file(s), deleting %d file(s)",
+		len(localFiles)-len(toUpload), len(toUpload), len(toDelete)))
+
+	dirs := map[string]bool{}
+	for _, rel := range toUpload {
+		dirs[path.Join(root, path.Dir(rel))] = true
+	}
+	var mkdirs []string
+	for dir := range dirs {
+		mkdirs = append(mkdirs, dir)
+	}
+	sort.Strings(mkdirs)
+	if err := runBatched(ctx, comm, "mkdir -p", mkdirs); err != nil {
+		return fmt.Errorf("failed to create remote directories: %s", err)
+	}
+
+	for _, rel := range toUpload {
+		// Uploads can't be interrupted, stop between them.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := uploadSyncFile(ui, comm, localFiles[rel], path.Join(root, rel)); err != nil {
+			return err
+		}
+	}
+
+	if err := runBatched(ctx, comm, "rm -f", remotePaths(root, toDelete)); err != nil {
+		return fmt.Errorf("failed to delete remote files: %s", err)
+	}
+
+	// Files that are up to date keep their mode and owner.
+	if p.config.Mode != "" {
+		if err := runBatched(ctx, comm, "chmod "+shellQuote(p.config.Mode), remotePaths(root, toUpload)); err != nil {
+			return fmt.Errorf("failed to set mode of remote files: %s", err)
+		}
+	}
+	if p.config.Owner != "" {
+		if err := runBatched(ctx, comm, "chown "+shellQuote(p.config.Owner), remotePaths(root, toUpload)); err != nil {
+			return fmt.Errorf("failed to set owner of remote files: %s", err)
+		}
+	}
+	return nil
+}
+
+// listSyncFiles returns the regular files under dir that are not excluded,
+// keyed by their slash separated path relative to dir.
+func (p *Provisioner) listSyncFiles(dir string) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(local string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, local)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if p.excluded(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files[rel] = local
+		}
+		return nil
+	})
+	return files, err
+}
+
+// excluded tells whether rel, or one of its parent directories, matches one
+// of the exclude patterns.
+func (p *Provisioner) excluded(rel string) bool {
+	for ; rel != "." && rel != "/" && rel != ""; rel = path.Dir(rel) {
+		for _, pattern := range p.config.Exclude {
+			if ok, _ := path.Match(pattern, rel); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func uploadSyncFile(ui packersdk.Ui, comm packersdk.Communicator, local, remote string) error {
+	f, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	pf := ui.TrackProgress(filepath.Base(local), 0, fi.Size(), f)
+	defer pf.Close()
+
+	if err := comm.Upload(remote, pf, &fi); err != nil {
+		return fmt.Errorf("failed to upload %s: %s", local, err)
+	}
+	return nil
+}
+
+func fileChecksum(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// remoteChecksums runs command, which outputs lines formatted like the ones
+// of sha256sum, and returns the checksums keyed by path.
+func remoteChecksums(ctx context.Context, comm packersdk.Communicator, command string) (map[string]string, error) {
+	stdout, err := runRemote(ctx, comm, command)
+	if err != nil {
+		return nil, err
+	}
+
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Lines of names with a backslash or a newline start with a
+		// backslash, and these characters are escaped in the name.
+		escaped := strings.HasPrefix(line, `\`)
+		sum, name, ok := strings.Cut(strings.TrimPrefix(line, `\`), " ")
+		if !ok || len(sum) != sha256.Size*2 || len(name) < 2 {
+			continue
+		}
+		// sha256sum separates the sum from the name with a space and a
+		// space or a star depending on the mode the file was read in.
+		name = name[1:]
+		if escaped {
+			name = unescapeSumName(name)
+		}
+		sums[strings.TrimPrefix(name, "./")] = strings.ToLower(sum)
+	}
+	return sums, scanner.Err()
+}
+
+// unescapeSumName reverts the escaping of names by sha256sum and shasum.
+func unescapeSumName(name string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r").Replace(name)
+}
+
+// runBatched runs command with paths as arguments, a few paths at a time.
+func runBatched(ctx context.Context, comm packersdk.Communicator, command string, paths []string) error {
+	for len(paths) > 0 {
+		n := min(len(paths), syncBatchSize)
+		args := make([]string, n)
+		for i, p := range paths[:n] {
+			args[i] = shellQuote(p)
+		}
+		if _, err := runRemote(ctx, comm, command+" -- "+strings.Join(args, " ")); err != nil {
+			return err
+		}
+		paths = paths[n:]
+	}
+	return nil
+}
+
+func runRemote(ctx context.Context, comm packersdk.Communicator, command string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := &packersdk.RemoteCmd{
+		Command: command,
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	}
+	if err := comm.Start(ctx, cmd); err != nil {
+		return nil, err
+	}
+	if status := cmd.Wait(); status != 0 {
+		return nil, fmt.Errorf("%q exited with status %d: %s", command, status, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func remotePaths(root string, rels []string) []string {
+	paths := make([]string, len(rels))
+	for i, rel := range rels {
+		paths[i] = path.Join(root, rel)
+	}
+	return paths
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
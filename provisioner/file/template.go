@@ -0,0 +1,183 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/tmp"
+	pkrfunction "github.com/hashicorp/packer/hcl2template/function"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// renderSource renders the file or the files of the directory at src with
+// the template variables into a temporary directory. It returns the path to
+// upload in place of src, which keeps the base name and trailing slash of
+// src, and a function removing the rendered files.
+func (p *Provisioner) renderSource(src string) (string, func(), error) {
+	dir, err := tmp.Dir("packer-file-template")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	vars := p.templateVars()
+	funcs := pkrfunction.Functions(".")
+
+	info, err := os.Stat(src)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	target := filepath.Join(dir, filepath.Base(filepath.Clean(src)))
+	if !info.IsDir() {
+		if err := renderFile(src, target, info.Mode().Perm(), vars, funcs); err != nil {
+			cleanup()
+			return "", nil, err
+		}
+		return target, cleanup, nil
+	}
+
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(target, rel), info.Mode().Perm())
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return renderFile(path, filepath.Join(target, rel), info.Mode().Perm(), vars, funcs)
+	})
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	if strings.HasSuffix(src, "/") || strings.HasSuffix(src, string(filepath.Separator)) {
+		target += string(filepath.Separator)
+	}
+	return target, cleanup, nil
+}
+
+// templateVars returns the variables available in templates: the configured
+// template variables, and the data generated by the builder under build.
+func (p *Provisioner) templateVars() cty.Value {
+	vars := map[string]cty.Value{}
+	for k, v := range p.config.vars {
+		vars[k] = v
+	}
+
+	build := map[string]cty.Value{}
+	if data, ok := p.config.ctx.Data.(map[string]interface{}); ok {
+		for k, v := range data {
+			val, err := ctyValue(v)
+			if err != nil {
+				log.Printf("[WARN] file: build variable %s is not available in templates: %s", k, err)
+				continue
+			}
+			build[k] = val
+		}
+	}
+	vars["build"] = cty.ObjectVal(build)
+
+	return cty.ObjectVal(vars)
+}
+
+// decodeTemplateVars returns the template variables set in template_vars as
+// cty values.
+func decodeTemplateVars(vars map[string]string) (map[string]cty.Value, error) {
+	res := map[string]cty.Value{}
+	for k, v := range vars {
+		res[k] = cty.StringVal(v)
+	}
+
+	if _, ok := res["build"]; ok {
+		return nil, errors.New("template_vars cannot set build, it is reserved for the build generated data.")
+	}
+	return res, nil
+}
+
+// ctyValue converts v, a value generated by a builder or a provisioner, to
+// a cty value of the same type.
+func ctyValue(v interface{}) (cty.Value, error) {
+	b, err := json.Marshal(stringKeys(v))
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return jsonValue(b)
+}
+
+func jsonValue(b []byte) (cty.Value, error) {
+	t, err := ctyjson.ImpliedType(b)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(b, t)
+}
+
+// stringKeys returns v with the maps it contains keyed by strings, since
+// maps read through RPC are keyed by interface{}.
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = stringKeys(e)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = stringKeys(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = stringKeys(e)
+		}
+		return l
+	}
+	return v
+}
+
+func renderFile(src, dst string, mode os.FileMode, vars cty.Value, funcs map[string]function.Function) error {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	val, err := pkrfunction.RenderTemplate(src, content, vars, funcs)
+	if err != nil {
+		return fmt.Errorf("Error rendering %s: %s", src, err)
+	}
+	val, err = convert.Convert(val, cty.String)
+	if err != nil || val.IsNull() || !val.IsKnown() {
+		return fmt.Errorf("Error rendering %s: the template must produce a string", src)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, []byte(val.AsString()), mode)
+}
@@ -0,0 +1,250 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package packages
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pkg is a package to install, Version is empty when any version will do.
+type pkg struct {
+	Name    string
+	Version string
+}
+
+// manager describes how to drive a package manager. Commands are returned
+// as argument lists, they are quoted for the remote shell by the
+// provisioner.
+type manager struct {
+	name    string
+	windows bool
+	// query returns the command printing the installed version of name. The
+	// command fails or prints nothing when the package is not installed.
+	query func(name string) []string
+	// parse extracts the installed version of name from the output of the
+	// query command.
+	parse func(name, output string) string
+	// refresh returns the command updating the package index before
+	// installing packages, or nil when it is not needed.
+	refresh []string
+	// install returns the commands installing pkgs.
+	install func(pkgs []pkg) [][]string
+	// pin returns the commands preventing pkgs from being upgraded, nil when
+	// installing a version already pins it.
+	pin func(pkgs []pkg) [][]string
+}
+
+var managers = map[string]*manager{
+	"apt": {
+		name: "apt",
+		query: func(name string) []string {
+			return []string{"dpkg-query", "-W", "-f=${Status}\t${Version}", name}
+		},
+		parse: func(_, output string) string {
+			status, version, ok := strings.Cut(output, "\t")
+			if !ok || !strings.HasSuffix(status, " installed") {
+				return ""
+			}
+			return strings.TrimSpace(version)
+		},
+		refresh: []string{"apt-get", "update", "-q"},
+		install: func(pkgs []pkg) [][]string {
+			cmd := []string{"env", "DEBIAN_FRONTEND=noninteractive", "apt-get", "install", "-y", "-q", "--allow-downgrades"}
+			return [][]string{append(cmd, joinVersions(pkgs, "=")...)}
+		},
+		pin: func(pkgs []pkg) [][]string {
+			return [][]string{append([]string{"apt-mark", "hold"}, names(pkgs)...)}
+		},
+	},
+	"dnf": {
+		name:  "dnf",
+		query: rpmQuery,
+		parse: trimmedOutput,
+		install: func(pkgs []pkg) [][]string {
+			return [][]string{append([]string{"dnf", "install", "-y", "--allowerasing"}, joinVersions(pkgs, "-")...)}
+		},
+		pin: func(pkgs []pkg) [][]string {
+			return [][]string{append([]string{"dnf", "versionlock", "add"}, names(pkgs)...)}
+		},
+	},
+	"yum": {
+		name:  "yum",
+		query: rpmQuery,
+		parse: trimmedOutput,
+		install: func(pkgs []pkg) [][]string {
+			return [][]string{append([]string{"yum", "install", "-y"}, joinVersions(pkgs, "-")...)}
+		},
+		pin: func(pkgs []pkg) [][]string {
+			return [][]string{append([]string{"yum", "versionlock", "add"}, names(pkgs)...)}
+		},
+	},
+	"zypper": {
+		name:  "zypper",
+		query: rpmQuery,
+		parse: trimmedOutput,
+		install: func(pkgs []pkg) [][]string {
+			cmd := []string{"zypper", "--non-interactive", "install", "--oldpackage"}
+			return [][]string{append(cmd, joinVersions(pkgs, "=")...)}
+		},
+		pin: func(pkgs []pkg) [][]string {
+			return [][]string{append([]string{"zypper", "--non-interactive", "addlock"}, names(pkgs)...)}
+		},
+	},
+	"apk": {
+		name: "apk",
+		query: func(name string) []string {
+			return []string{"apk", "list", "--installed", name}
+		},
+		parse: func(name, output string) string {
+			// Lines look like `nginx-1.24.0-r6 x86_64 {nginx} (BSD-2-Clause) [installed]`
+			for _, line := range strings.Split(output, "\n") {
+				fields := strings.Fields(line)
+				if len(fields) == 0 || !strings.HasPrefix(fields[0], name+"-") {
+					continue
+				}
+				version := strings.TrimPrefix(fields[0], name+"-")
+				if len(version) > 0 && version[0] >= '0' && version[0] <= '9' {
+					return version
+				}
+			}
+			return ""
+		},
+		refresh: []string{"apk", "update", "-q"},
+		install: func(pkgs []pkg) [][]string {
+			return [][]string{append([]string{"apk", "add", "-q"}, joinVersions(pkgs, "=")...)}
+		},
+		// apk records the requested version in /etc/apk/world, which
+		// already keeps the package from being upgraded.
+		pin: nil,
+	},
+	"choco": {
+		name:    "choco",
+		windows: true,
+		query: func(name string) []string {
+			return []string{"choco", "list", "--exact", name, "--limit-output"}
+		},
+		parse: func(name, output string) string {
+			// Lines look like `name|version`
+			for _, line := range strings.Split(output, "\n") {
+				n, version, ok := strings.Cut(strings.TrimSpace(line), "|")
+				if ok && strings.EqualFold(n, name) {
+					return version
+				}
+			}
+			return ""
+		},
+		install: func(pkgs []pkg) [][]string {
+			var cmds [][]string
+			for _, p := range pkgs {
+				cmd := []string{"choco", "install", p.Name, "-y", "--no-progress"}
+				if p.Version != "" {
+					cmd = append(cmd, "--version", p.Version, "--allow-downgrade")
+				}
+				cmds = append(cmds, cmd)
+			}
+			return cmds
+		},
+		pin: func(pkgs []pkg) [][]string {
+			var cmds [][]string
+			for _, p := range pkgs {
+				cmds = append(cmds, []string{"choco", "pin", "add", "--name=" + p.Name})
+			}
+			return cmds
+		},
+	},
+	"winget": {
+		name:    "winget",
+		windows: true,
+		query: func(name string) []string {
+			return []string{"winget", "list", "--id", name, "--exact", "--accept-source-agreements", "--disable-interactivity"}
+		},
+		parse: func(name, output string) string {
+			// winget prints a table, the version is the column following
+			// the package identifier.
+			for _, line := range strings.Split(output, "\n") {
+				fields := strings.Fields(line)
+				for i, field := range fields {
+					if strings.EqualFold(field, name) && i+1 < len(fields) {
+						return fields[i+1]
+					}
+				}
+			}
+			return ""
+		},
+		install: func(pkgs []pkg) [][]string {
+			var cmds [][]string
+			for _, p := range pkgs {
+				cmd := []string{"winget", "install", "--id", p.Name, "--exact", "--silent",
+					"--accept-package-agreements", "--accept-source-agreements", "--disable-interactivity"}
+				if p.Version != "" {
+					cmd = append(cmd, "--version", p.Version)
+				}
+				cmds = append(cmds, cmd)
+			}
+			return cmds
+		},
+		pin: func(pkgs []pkg) [][]string {
+			var cmds [][]string
+			for _, p := range pkgs {
+				cmd := []string{"winget", "pin", "add", "--id", p.Name, "--exact"}
+				if p.Version != "" {
+					cmd = append(cmd, "--version", p.Version)
+				}
+				cmds = append(cmds, cmd)
+			}
+			return cmds
+		},
+	},
+}
+
+// detectUnixCommand prints the first package manager found on the remote
+// host, the order matters as dnf based distributions may also ship yum.
+const detectUnixCommand = `for m in apt-get dnf yum zypper apk; do if command -v $m >/dev/null 2>&1; then echo $m; exit 0; fi; done; exit 1`
+
+// detectWindowsManagers lists the package managers looked up on Windows, in
+// order of preference.
+var detectWindowsManagers = []string{"choco", "winget"}
+
+func rpmQuery(name string) []string {
+	return []string{"rpm", "-q", "--qf", "%{VERSION}-%{RELEASE}", name}
+}
+
+func trimmedOutput(_, output string) string {
+	return strings.TrimSpace(output)
+}
+
+func names(pkgs []pkg) []string {
+	res := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		res[i] = p.Name
+	}
+	return res
+}
+
+// joinVersions returns the package names, followed by sep and their version
+// when there is one.
+func joinVersions(pkgs []pkg, sep string) []string {
+	res := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		res[i] = p.Name
+		if p.Version != "" {
+			res[i] = fmt.Sprintf("%s%s%s", p.Name, sep, p.Version)
+		}
+	}
+	return res
+}
+
+// versionMatches tells whether the installed version satisfies the
+// requested one. The requested version may omit the epoch of the installed
+// version, as well as its release when the installed version has one.
+func versionMatches(installed, requested string) bool {
+	if requested == "" {
+		return installed != ""
+	}
+	if _, v, ok := strings.Cut(installed, ":"); ok && !strings.Contains(requested, ":") {
+		installed = v
+	}
+	return installed == requested || strings.HasPrefix(installed, requested+"-")
+}
@@ -0,0 +1,348 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+// This package implements a provisioner for Packer that installs packages
+// with the package manager of the remote machine.
+package packages
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// VersionsDir is the remote directory the installed version of each package
+// is written to, for Packer to read it back as a build variable. On Windows,
+// it is relative to the root of the current drive.
+const VersionsDir = "/tmp/packer-packages"
+
+// OutputName returns the build variable the installed version of the name
+// package is published as: name with the characters other than letters,
+// digits, `-` and `_` replaced with `_`, followed by `_version`. It is
+// prefixed with `_` when name starts with a digit.
+func OutputName(name string) string {
+	name = invalidKeyChars.ReplaceAllString(name, "_") + "_version"
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// VersionFile returns the remote file the installed version of the name
+// package is written to.
+func VersionFile(name string) string {
+	return VersionsDir + "/" + invalidKeyChars.ReplaceAllString(name, "_")
+}
+
+var invalidKeyChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// validName matches the package names and versions that are accepted. They
+// are passed unquoted to the Windows package managers.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+:~@/-]*$`)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The packages to install, mapping package names to the version to
+	// install. An empty version installs the version picked by the package
+	// manager, or keeps the installed one. Versions are given the way the
+	// package manager expects them, the epoch and release can be omitted
+	// for the installed version to match. In HCL2 templates, the installed
+	// version of each package is published as a build variable named after
+	// the package, with the characters other than letters, digits, `-` and
+	// `_` replaced with `_`, followed by `_version`: `build.nginx_version`
+	// for `nginx`.
+	Packages map[string]string `mapstructure:"packages" required:"true"`
+	// The package manager to use, one of `apt`, `dnf`, `yum`, `zypper`,
+	// `apk`, `choco` or `winget`. By default, it is detected on the remote
+	// machine: `choco` or `winget` when connected with WinRM, and the first
+	// one found of `apt`, `dnf`, `yum`, `zypper` and `apk` otherwise.
+	Manager string `mapstructure:"manager" required:"false"`
+	// Prevent the package manager from upgrading the listed packages once
+	// installed. With `dnf` and `yum`, this requires the versionlock plugin.
+	// Packages installed with `apk` are always pinned to the requested
+	// version. Defaults to false.
+	Pin bool `mapstructure:"pin" required:"false"`
+	// Run the package manager through `sudo -n`. Ignored on Windows.
+	// Defaults to false.
+	UseSudo bool `mapstructure:"use_sudo" required:"false"`
+	ctx interpolate.Context
+}
+
+type Provisioner struct {
+	config Config
+}
+
+var _ packersdk.Provisioner = new(Provisioner)
+
+func (p *Provisioner) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *Provisioner) FlatConfig() interface{} { return p.config.FlatMapstructure() }
+
+func (p *Provisioner) Prepare(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         "packages",
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	if len(p.config.Packages) == 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("packages must be set"))
+	}
+	for name, version := range p.config.Packages {
+		if !validName.MatchString(name) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid package name %q", name))
+		}
+		if version != "" && !validName.MatchString(version) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid version %q for package %s", version, name))
+		}
+	}
+	if p.config.Manager != "" {
+		if _, ok := managers[p.config.Manager]; !ok {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("unknown package manager %q, "+
+				"must be one of apt, dnf, yum, zypper, apk, choco or winget", p.config.Manager))
+		}
+	}
+
+	return errs
+}
+
+func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator, generatedData map[string]interface{}) error {
+	m, err := p.manager(ctx, comm, generatedData)
+	if err != nil {
+		return err
+	}
+	ui.Say(fmt.Sprintf("Installing packages with %s", m.name))
+
+	var pkgs []pkg
+	for name, version := range p.config.Packages {
+		pkgs = append(pkgs, pkg{Name: name, Version: version})
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+
+	installed, err := p.installedVersions(ctx, comm, m, pkgs)
+	if err != nil {
+		return err
+	}
+
+	var toInstall []pkg
+	for _, pkg := range pkgs {
+		if versionMatches(installed[pkg.Name], pkg.Version) {
+			log.Printf("Package %s %s is already installed", pkg.Name, installed[pkg.Name])
+			continue
+		}
+		toInstall = append(toInstall, pkg)
+	}
+
+	if len(toInstall) == 0 {
+		ui.Say("All packages are already installed")
+	} else {
+		ui.Say(fmt.Sprintf("Installing %s", strings.Join(joinVersions(toInstall, " "), ", ")))
+		if m.refresh != nil {
+			if err := p.run(ctx, ui, comm, m, m.refresh); err != nil {
+				return fmt.Errorf("failed to update the package index: %s", err)
+			}
+		}
+		for _, cmd := range m.install(toInstall) {
+			if err := p.run(ctx, ui, comm, m, cmd); err != nil {
+				return fmt.Errorf("failed to install packages: %s", err)
+			}
+		}
+
+		installed, err = p.installedVersions(ctx, comm, m, pkgs)
+		if err != nil {
+			return err
+		}
+		for _, pkg := range toInstall {
+			switch {
+			case installed[pkg.Name] == "":
+				return fmt.Errorf("package %s is not installed after running %s", pkg.Name, m.name)
+			case !versionMatches(installed[pkg.Name], pkg.Version):
+				return fmt.Errorf("package %s: version %s was requested but %s is installed",
+					pkg.Name, pkg.Version, installed[pkg.Name])
+			}
+		}
+	}
+
+	if p.config.Pin && m.pin != nil {
+		ui.Say("Pinning packages")
+		for _, cmd := range m.pin(pkgs) {
+			if err := p.run(ctx, ui, comm, m, cmd); err != nil {
+				return fmt.Errorf("failed to pin packages: %s", err)
+			}
+		}
+	}
+
+	for _, pkg := range pkgs {
+		ui.Say(fmt.Sprintf("%s %s", pkg.Name, installed[pkg.Name]))
+	}
+
+	return p.writeVersions(ctx, comm, m, installed)
+}
+
+// manager returns the configured package manager, or detects the one of
+// the remote machine.
+func (p *Provisioner) manager(ctx context.Context, comm packersdk.Communicator, generatedData map[string]interface{}) (*manager, error) {
+	if p.config.Manager != "" {
+		return managers[p.config.Manager], nil
+	}
+
+	log.Println("Detecting the package manager of the remote host...")
+
+	if connType, _ := generatedData["ConnType"].(string); connType == "winrm" {
+		for _, name := range detectWindowsManagers {
+			_, status, err := runCapture(ctx, comm, "where "+name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to detect the package manager: %s", err)
+			}
+			if status == 0 {
+				return managers[name], nil
+			}
+		}
+		return nil, errors.New("no supported package manager found, install choco or winget, or set manager")
+	}
+
+	stdout, status, err := runCapture(ctx, comm, detectUnixCommand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect the package manager: %s", err)
+	}
+	name := strings.TrimSpace(stdout)
+	if name == "apt-get" {
+		name = "apt"
+	}
+	m, ok := managers[name]
+	if status != 0 || !ok {
+		return nil, errors.New("no supported package manager found, set manager to one of apt, dnf, yum, zypper or apk")
+	}
+	return m, nil
+}
+
+// installedVersions returns the installed version of pkgs, keyed by name.
+// Packages that are not installed have an empty version.
+func (p *Provisioner) installedVersions(ctx context.Context, comm packersdk.Communicator, m *manager, pkgs []pkg) (map[string]string, error) {
+	versions := make(map[string]string, len(pkgs))
+	for _, pkg := range pkgs {
+		stdout, status, err := runCapture(ctx, comm, p.command(m, m.query(pkg.Name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query the installed version of %s: %s", pkg.Name, err)
+		}
+		if status != 0 {
+			versions[pkg.Name] = ""
+			continue
+		}
+		versions[pkg.Name] = m.parse(pkg.Name, stdout)
+	}
+	return versions, nil
+}
+
+// writeVersions writes the installed versions to VersionsDir on the remote
+// machine, one file per package, for Packer to publish them as build
+// variables.
+func (p *Provisioner) writeVersions(ctx context.Context, comm packersdk.Communicator, m *manager, installed map[string]string) error {
+	dir := VersionsDir
+	mkdir := "mkdir -p " + dir
+	if m.windows {
+		dir = strings.ReplaceAll(dir, "/", `\`)
+		mkdir = fmt.Sprintf(`if not exist "%s" mkdir "%s"`, dir, dir)
+	}
+	_, status, err := runCapture(ctx, comm, mkdir)
+	if err != nil {
+		return fmt.Errorf("failed to create the versions directory %s: %s", dir, err)
+	}
+	if status != 0 {
+		return fmt.Errorf("failed to create the versions directory %s: %q exited with status %d", dir, mkdir, status)
+	}
+
+	names := make([]string, 0, len(installed))
+	for name := range installed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		dst := VersionFile(name)
+		if err := comm.Upload(dst, strings.NewReader(installed[name]), nil); err != nil {
+			return fmt.Errorf("failed to write the installed version of %s to %s: %s", name, dst, err)
+		}
+		log.Printf("Installed version of %s written to %s", name, dst)
+	}
+	return nil
+}
+
+// run runs args on the remote machine, streaming its output to ui.
+func (p *Provisioner) run(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator, m *manager, args []string) error {
+	command := p.command(m, args)
+	var stderr bytes.Buffer
+	cmd := &packersdk.RemoteCmd{
+		Command: command,
+		Stderr:  &stderr,
+	}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		return err
+	}
+	if status := cmd.ExitStatus(); status != 0 {
+		return fmt.Errorf("%q exited with status %d: %s", command, status, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// command quotes args into a command line for the remote machine.
+func (p *Provisioner) command(m *manager, args []string) string {
+	if m.windows {
+		// Names and versions are validated, they don't need quoting.
+		return strings.Join(args, " ")
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	command := strings.Join(quoted, " ")
+	if p.config.UseSudo {
+		command = "sudo -n " + command
+	}
+	return command
+}
+
+func runCapture(ctx context.Context, comm packersdk.Communicator, command string) (string, int, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := &packersdk.RemoteCmd{
+		Command: command,
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	}
+	if err := comm.Start(ctx, cmd); err != nil {
+		return "", 0, err
+	}
+	status := cmd.Wait()
+	if status != 0 {
+		log.Printf("%q exited with status %d: %s", command, status, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), status, nil
+}
+
+var safeShellWord = regexp.MustCompile(`^[A-Za-z0-9._/:+@=-]+$`)
+
+// shellQuote quotes s for a POSIX shell, unless it only holds characters
+// that need no quoting.
+func shellQuote(s string) string {
+	if safeShellWord.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
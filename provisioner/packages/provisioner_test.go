@@ -0,0 +1,292 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package packages
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// fakeComm simulates a remote host with apt, dpkg and choco.
+type fakeComm struct {
+	packersdk.MockCommunicator
+	// installed maps the installed packages to their version.
+	installed map[string]string
+	// available maps the packages of the repositories to their versions.
+	available map[string][]string
+	// windows makes the host look like a Windows one with choco.
+	windows  bool
+	commands []string
+	// uploads maps the uploaded paths to their content.
+	uploads map[string]string
+}
+
+func (c *fakeComm) Upload(path string, r io.Reader, fi *os.FileInfo) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if c.uploads == nil {
+		c.uploads = map[string]string{}
+	}
+	c.uploads[path] = string(content)
+	return nil
+}
+
+func (c *fakeComm) Start(ctx context.Context, cmd *packersdk.RemoteCmd) error {
+	c.commands = append(c.commands, cmd.Command)
+	stdout, stderr, status := c.run(cmd.Command)
+	if cmd.Stdout != nil {
+		_, _ = io.WriteString(cmd.Stdout, stdout)
+	}
+	if cmd.Stderr != nil {
+		_, _ = io.WriteString(cmd.Stderr, stderr)
+	}
+	cmd.SetExited(status)
+	return nil
+}
+
+func (c *fakeComm) run(command string) (string, string, int) {
+	fields := strings.Fields(strings.TrimPrefix(command, "sudo -n "))
+	switch {
+	case command == detectUnixCommand:
+		if c.windows {
+			return "", "'sh' is not recognized", 1
+		}
+		return "apt-get\n", "", 0
+	case command == "where choco":
+		if c.windows {
+			return `C:\ProgramData\chocolatey\bin\choco.exe`, "", 0
+		}
+		return "", "", 1
+	case fields[0] == "dpkg-query":
+		name := fields[len(fields)-1]
+		if version, ok := c.installed[name]; ok {
+			return "install ok installed\t" + version, "", 0
+		}
+		return "", fmt.Sprintf("dpkg-query: no packages found matching %s", name), 1
+	case fields[0] == "choco" && fields[1] == "list":
+		if version, ok := c.installed[fields[3]]; ok {
+			return fields[3] + "|" + version + "\n", "", 0
+		}
+		return "", "", 0
+	case fields[0] == "apt-get" && fields[1] == "update":
+		return "", "", 0
+	case fields[0] == "env" && fields[2] == "apt-get" && fields[3] == "install":
+		for _, arg := range fields[4:] {
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+			name, version, _ := strings.Cut(arg, "=")
+			versions := c.available[name]
+			if len(versions) == 0 {
+				return "", fmt.Sprintf("E: Unable to locate package %s", name), 100
+			}
+			if version == "" {
+				version = versions[len(versions)-1]
+			}
+			found := false
+			for _, v := range versions {
+				found = found || v == version
+			}
+			if !found {
+				return "", fmt.Sprintf("E: Version '%s' for '%s' was not found", version, name), 100
+			}
+			c.installed[name] = version
+		}
+		return "", "", 0
+	case fields[0] == "mkdir", fields[0] == "if":
+		return "", "", 0
+	case fields[0] == "apt-mark":
+		return "", "", 0
+	}
+	return "", "unknown command " + command, 127
+}
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"packages": map[string]string{
+			"curl":  "",
+			"nginx": "1.24.0-2ubuntu7",
+		},
+	}
+}
+
+func TestProvisionerPrepare(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"valid", testConfig(), false},
+		{"no packages", map[string]interface{}{}, true},
+		{"bad name", map[string]interface{}{"packages": map[string]string{"curl; rm -rf /": ""}}, true},
+		{"bad version", map[string]interface{}{"packages": map[string]string{"curl": "1.0 && reboot"}}, true},
+		{"known manager", map[string]interface{}{"packages": map[string]string{"curl": ""}, "manager": "zypper"}, false},
+		{"unknown manager", map[string]interface{}{"packages": map[string]string{"curl": ""}, "manager": "pacman"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Provisioner
+			err := p.Prepare(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Prepare() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProvisionerProvision(t *testing.T) {
+	config := testConfig()
+	config["pin"] = true
+	config["use_sudo"] = true
+
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm := &fakeComm{
+		installed: map[string]string{"curl": "8.5.0-2ubuntu10"},
+		available: map[string][]string{"nginx": {"1.24.0-2ubuntu7", "1.26.0-1"}},
+	}
+	if err := p.Provision(context.Background(), packersdk.TestUi(t), comm, map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"sudo -n env DEBIAN_FRONTEND=noninteractive apt-get install -y -q --allow-downgrades nginx=1.24.0-2ubuntu7",
+		"sudo -n apt-mark hold curl nginx",
+		"mkdir -p /tmp/packer-packages",
+	}
+	for _, cmd := range expected {
+		found := false
+		for _, c := range comm.commands {
+			found = found || c == cmd
+		}
+		if !found {
+			t.Errorf("expected command %q to run, got %q", cmd, comm.commands)
+		}
+	}
+
+	expectedUploads := map[string]string{
+		"/tmp/packer-packages/curl":  "8.5.0-2ubuntu10",
+		"/tmp/packer-packages/nginx": "1.24.0-2ubuntu7",
+	}
+	if !reflect.DeepEqual(comm.uploads, expectedUploads) {
+		t.Fatalf("expected the versions to be written to %v, got %v", expectedUploads, comm.uploads)
+	}
+}
+
+func TestProvisionerProvision_idempotent(t *testing.T) {
+	var p Provisioner
+	if err := p.Prepare(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm := &fakeComm{
+		installed: map[string]string{"curl": "8.5.0-2ubuntu10", "nginx": "1.24.0-2ubuntu7"},
+	}
+	if err := p.Provision(context.Background(), packersdk.TestUi(t), comm, map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, c := range comm.commands {
+		if strings.Contains(c, "apt-get") && c != detectUnixCommand {
+			t.Fatalf("nothing should be installed, got %q", comm.commands)
+		}
+	}
+}
+
+func TestProvisionerProvision_unknownVersion(t *testing.T) {
+	var p Provisioner
+	if err := p.Prepare(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm := &fakeComm{
+		installed: map[string]string{"curl": "8.5.0-2ubuntu10"},
+		available: map[string][]string{"nginx": {"1.26.0-1"}},
+	}
+	err := p.Provision(context.Background(), packersdk.TestUi(t), comm, map[string]interface{}{})
+	if err == nil {
+		t.Fatal("should have errored")
+	}
+	if !strings.Contains(err.Error(), "Version '1.24.0-2ubuntu7' for 'nginx' was not found") {
+		t.Fatalf("expected the error to tell the version is unknown, got: %s", err)
+	}
+}
+
+func TestProvisionerProvision_windows(t *testing.T) {
+	var p Provisioner
+	if err := p.Prepare(map[string]interface{}{
+		"packages": map[string]string{"git": "2.45.1"},
+		"use_sudo": true,
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm := &fakeComm{
+		installed: map[string]string{"git": "2.45.1"},
+		windows:   true,
+	}
+	generatedData := map[string]interface{}{"ConnType": "winrm"}
+	if err := p.Provision(context.Background(), packersdk.TestUi(t), comm, generatedData); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"where choco",
+		"choco list --exact git --limit-output",
+		`if not exist "\tmp\packer-packages" mkdir "\tmp\packer-packages"`,
+	}
+	if strings.Join(comm.commands, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected commands %q, got %q", expected, comm.commands)
+	}
+	if version := comm.uploads["/tmp/packer-packages/git"]; version != "2.45.1" {
+		t.Fatalf("expected the version of git to be written, got %q", version)
+	}
+}
+
+func TestVersionMatches(t *testing.T) {
+	tests := []struct {
+		installed, requested string
+		want                 bool
+	}{
+		{"1.24.0-2ubuntu7", "1.24.0-2ubuntu7", true},
+		{"1:1.24.0-2ubuntu7", "1.24.0-2ubuntu7", true},
+		{"1:1.24.0-2ubuntu7", "1:1.24.0-2ubuntu7", true},
+		{"1.20.1-14.el9", "1.20.1", true},
+		{"1.20.10-1.el9", "1.20.1", false},
+		{"1.24.0", "", true},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := versionMatches(tt.installed, tt.requested); got != tt.want {
+			t.Errorf("versionMatches(%q, %q) = %t, want %t", tt.installed, tt.requested, got, tt.want)
+		}
+	}
+}
+
+func TestOutputName(t *testing.T) {
+	tests := map[string]string{
+		"nginx":           "nginx_version",
+		"python3.12-venv": "python3_12-venv_version",
+		"7zip":            "_7zip_version",
+	}
+	for name, want := range tests {
+		if got := OutputName(name); got != want {
+			t.Errorf("OutputName(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if file := VersionFile("python3.12-venv"); file != "/tmp/packer-packages/python3_12-venv" {
+		t.Fatalf("unexpected version file %q", file)
+	}
+}
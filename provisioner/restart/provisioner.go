@@ -0,0 +1,259 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+// This package implements a provisioner for Packer that restarts Linux and
+// other Unix machines, and waits for them to come back.
+package restart
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+var DefaultRestartCommand = "shutdown -r now"
+
+// BootIDCommand prints an identifier that changes on every boot.
+var BootIDCommand = "cat /proc/sys/kernel/random/boot_id"
+
+var retryableSleep = 5 * time.Second
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The command used to restart the machine. Defaults to
+	// `shutdown -r now`, prefix it with `sudo` when not connected as root.
+	RestartCommand string `mapstructure:"restart_command"`
+
+	// The command printing an identifier that changes on every boot, used to
+	// tell that the machine restarted. Defaults to reading
+	// `/proc/sys/kernel/random/boot_id`, which is only available on Linux.
+	BootIDCommand string `mapstructure:"boot_id_command"`
+
+	// How long to wait for the restart command to return, the machine to
+	// restart and the communicator to reconnect, all together. Defaults to
+	// 5m.
+	RestartTimeout time.Duration `mapstructure:"restart_timeout"`
+
+	// Commands that must exit successfully for the machine to be considered
+	// ready once it restarted, like `systemctl is-system-running --wait`.
+	// They run in order and each one is retried until it succeeds or
+	// `ready_timeout` is exceeded.
+	ReadyChecks []string `mapstructure:"ready_checks"`
+
+	// How long to wait for the ready checks to succeed, once the machine
+	// restarted. Defaults to 5m.
+	ReadyTimeout time.Duration `mapstructure:"ready_timeout"`
+
+	ctx interpolate.Context
+}
+
+type Provisioner struct {
+	config Config
+}
+
+var _ packersdk.Provisioner = new(Provisioner)
+
+func (p *Provisioner) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *Provisioner) Prepare(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         "restart",
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.RestartCommand == "" {
+		p.config.RestartCommand = DefaultRestartCommand
+	}
+
+	if p.config.BootIDCommand == "" {
+		p.config.BootIDCommand = BootIDCommand
+	}
+
+	if p.config.RestartTimeout == 0 {
+		p.config.RestartTimeout = 5 * time.Minute
+	}
+
+	if p.config.ReadyTimeout == 0 {
+		p.config.ReadyTimeout = 5 * time.Minute
+	}
+
+	var errs error
+	if p.config.RestartTimeout < 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("restart_timeout must be positive"))
+	}
+	if p.config.ReadyTimeout < 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("ready_timeout must be positive"))
+	}
+	for i, check := range p.config.ReadyChecks {
+		if strings.TrimSpace(check) == "" {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("ready_checks[%d] is empty", i))
+		}
+	}
+
+	return errs
+}
+
+func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator, _ map[string]interface{}) error {
+	bootID, err := p.bootID(ctx, comm)
+	if err != nil {
+		return fmt.Errorf("failed to read the boot ID before restarting: %s", err)
+	}
+	if bootID == "" {
+		return fmt.Errorf("%q printed an empty boot ID", p.config.BootIDCommand)
+	}
+	log.Printf("Boot ID before restart: %s", bootID)
+
+	// The restart command and the wait for the boot ID to change share the
+	// restart timeout, so that a hung restart command doesn't extend it.
+	restartCtx, cancel := context.WithTimeout(ctx, p.config.RestartTimeout)
+	defer cancel()
+
+	ui.Say("Restarting machine")
+	if err := p.restart(restartCtx, ui, comm); err != nil {
+		return err
+	}
+
+	ui.Say(fmt.Sprintf("Waiting for machine to restart, with a %s timeout...", p.config.RestartTimeout))
+	start := time.Now()
+	if err := p.waitForRestart(restartCtx, comm, bootID); err != nil {
+		return err
+	}
+	ui.Say(fmt.Sprintf("Machine restarted after %s", time.Since(start).Round(time.Second)))
+
+	return p.waitForReadyChecks(ctx, ui, comm)
+}
+
+// restart runs the restart command, which either succeeds, or is cut when
+// the machine goes down.
+func (p *Provisioner) restart(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator) error {
+	cmd := &packersdk.RemoteCmd{Command: p.config.RestartCommand}
+	err := cmd.RunWithUi(ctx, comm, ui)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return err
+	case errors.Is(err, context.DeadlineExceeded):
+		// Some systems keep the connection open until it times out, the
+		// boot ID tells whether the machine restarted.
+		log.Printf("Restart command did not return, checking whether the machine restarted")
+		return nil
+	case err != nil:
+		// The connection may drop before the command returns.
+		log.Printf("Restart command interrupted, assuming the machine disconnected: %s", err)
+		return nil
+	}
+
+	switch status := cmd.ExitStatus(); status {
+	case 0:
+		return nil
+	case packersdk.CmdDisconnect:
+		log.Printf("Machine disconnected")
+		return nil
+	default:
+		return fmt.Errorf("Restart command exited with non-zero exit status: %d", status)
+	}
+}
+
+// waitForRestart waits for the communicator to reconnect to the machine and
+// for the boot ID to change, until ctx is done.
+func (p *Provisioner) waitForRestart(ctx context.Context, comm packersdk.Communicator, bootID string) error {
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("Timeout waiting for machine to restart: boot ID still %s after %s",
+					bootID, p.config.RestartTimeout)
+			}
+			return ctx.Err()
+		case <-time.After(retryableSleep):
+		}
+
+		newBootID, err := p.bootID(ctx, comm)
+		switch {
+		case err != nil:
+			log.Printf("Machine not reachable yet: %s", err)
+		case newBootID == "":
+			log.Printf("Machine not ready yet, empty boot ID")
+		case newBootID == bootID:
+			log.Printf("Machine not restarted yet")
+		default:
+			log.Printf("Boot ID after restart: %s", newBootID)
+			return nil
+		}
+	}
+}
+
+// waitForReadyChecks runs each ready check until it succeeds, and reports how
+// long each one took.
+func (p *Provisioner) waitForReadyChecks(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator) error {
+	if len(p.config.ReadyChecks) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.ReadyTimeout)
+	defer cancel()
+
+	for _, check := range p.config.ReadyChecks {
+		start := time.Now()
+		for {
+			var stderr bytes.Buffer
+			cmd := &packersdk.RemoteCmd{
+				Command: check,
+				Stderr:  &stderr,
+			}
+			err := comm.Start(ctx, cmd)
+			if err == nil {
+				if cmd.Wait() == 0 {
+					break
+				}
+				log.Printf("Ready check %q exited with status %d: %s", check, cmd.ExitStatus(), strings.TrimSpace(stderr.String()))
+			} else {
+				log.Printf("Ready check %q could not run: %s", check, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return ctx.Err()
+				}
+				err := fmt.Errorf("Ready check %q did not succeed within %s", check, p.config.ReadyTimeout)
+				ui.Error(err.Error())
+				return err
+			case <-time.After(retryableSleep):
+			}
+		}
+		ui.Say(fmt.Sprintf("Ready check %q succeeded after %s", check, time.Since(start).Round(time.Second)))
+	}
+	return nil
+}
+
+func (p *Provisioner) bootID(ctx context.Context, comm packersdk.Communicator) (string, error) {
+	var stdout bytes.Buffer
+	cmd := &packersdk.RemoteCmd{
+		Command: p.config.BootIDCommand,
+		Stdout:  &stdout,
+	}
+	if err := comm.Start(ctx, cmd); err != nil {
+		return "", err
+	}
+	if status := cmd.Wait(); status != 0 {
+		return "", fmt.Errorf("%q exited with status %d", p.config.BootIDCommand, status)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
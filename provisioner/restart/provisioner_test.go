@@ -0,0 +1,196 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package restart
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// fakeComm simulates a machine that restarts when the restart command runs.
+type fakeComm struct {
+	packersdk.MockCommunicator
+	// down is the number of boot ID reads failing once the restart command
+	// ran, as if the machine was unreachable.
+	down int
+	// restartStatus is the exit status of the restart command.
+	restartStatus int
+	// noRestart keeps the boot ID unchanged after the restart command.
+	noRestart bool
+	// hang keeps the restart command from returning.
+	hang bool
+	// checkFailures is the number of times the ready checks fail before
+	// succeeding.
+	checkFailures int
+
+	bootID    string
+	restarted bool
+	commands  []string
+}
+
+func (c *fakeComm) Start(ctx context.Context, cmd *packersdk.RemoteCmd) error {
+	c.commands = append(c.commands, cmd.Command)
+	switch cmd.Command {
+	case DefaultRestartCommand:
+		c.restarted = true
+		if !c.noRestart {
+			c.bootID = "after"
+		}
+		if c.hang {
+			return nil
+		}
+		cmd.SetExited(c.restartStatus)
+	case BootIDCommand:
+		if c.restarted && c.down > 0 {
+			c.down--
+			return errors.New("connection refused")
+		}
+		_, _ = io.WriteString(cmd.Stdout, c.bootID+"\n")
+		cmd.SetExited(0)
+	default:
+		if c.checkFailures > 0 {
+			c.checkFailures--
+			cmd.SetExited(1)
+			return nil
+		}
+		cmd.SetExited(0)
+	}
+	return nil
+}
+
+func testProvisioner(t *testing.T, config map[string]interface{}) *Provisioner {
+	retryableSleep = time.Millisecond
+	t.Cleanup(func() { retryableSleep = 5 * time.Second })
+
+	p := new(Provisioner)
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return p
+}
+
+func TestProvisionerPrepare(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"defaults", map[string]interface{}{}, false},
+		{"ready checks", map[string]interface{}{"ready_checks": []string{"systemctl is-system-running --wait"}}, false},
+		{"empty ready check", map[string]interface{}{"ready_checks": []string{" "}}, true},
+		{"negative timeout", map[string]interface{}{"restart_timeout": "-1m"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Provisioner
+			err := p.Prepare(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Prepare() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+
+	var p Provisioner
+	if err := p.Prepare(map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.RestartCommand != DefaultRestartCommand {
+		t.Errorf("unexpected restart_command %q", p.config.RestartCommand)
+	}
+	if p.config.RestartTimeout != 5*time.Minute || p.config.ReadyTimeout != 5*time.Minute {
+		t.Errorf("unexpected timeouts %s and %s", p.config.RestartTimeout, p.config.ReadyTimeout)
+	}
+}
+
+func TestProvisionerProvision(t *testing.T) {
+	p := testProvisioner(t, map[string]interface{}{
+		"ready_checks": []string{"systemctl is-system-running --wait"},
+	})
+
+	comm := &fakeComm{
+		bootID:        "before",
+		down:          2,
+		restartStatus: packersdk.CmdDisconnect,
+		checkFailures: 2,
+	}
+	if err := p.Provision(context.Background(), packersdk.TestUi(t), comm, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	checks := 0
+	for _, c := range comm.commands {
+		if c == "systemctl is-system-running --wait" {
+			checks++
+		}
+	}
+	if checks != 3 {
+		t.Fatalf("expected the ready check to run 3 times, got %q", comm.commands)
+	}
+}
+
+func TestProvisionerProvision_restartFailed(t *testing.T) {
+	p := testProvisioner(t, map[string]interface{}{})
+
+	comm := &fakeComm{bootID: "before", restartStatus: 1, noRestart: true}
+	err := p.Provision(context.Background(), packersdk.TestUi(t), comm, nil)
+	if err == nil || !strings.Contains(err.Error(), "non-zero exit status: 1") {
+		t.Fatalf("expected the restart command to fail, got: %v", err)
+	}
+}
+
+func TestProvisionerProvision_bootIDUnchanged(t *testing.T) {
+	p := testProvisioner(t, map[string]interface{}{"restart_timeout": "50ms"})
+
+	comm := &fakeComm{bootID: "before", noRestart: true}
+	err := p.Provision(context.Background(), packersdk.TestUi(t), comm, nil)
+	if err == nil || !strings.Contains(err.Error(), "boot ID still before") {
+		t.Fatalf("expected a restart timeout, got: %v", err)
+	}
+}
+
+func TestProvisionerProvision_readyTimeout(t *testing.T) {
+	p := testProvisioner(t, map[string]interface{}{
+		"ready_checks":  []string{"test -f /var/lib/cloud/instance/boot-finished"},
+		"ready_timeout": "50ms",
+	})
+
+	comm := &fakeComm{bootID: "before", checkFailures: 1 << 30}
+	err := p.Provision(context.Background(), packersdk.TestUi(t), comm, nil)
+	if err == nil || !strings.Contains(err.Error(), "did not succeed within 50ms") {
+		t.Fatalf("expected a ready check timeout, got: %v", err)
+	}
+}
+
+func TestProvisionerProvision_restartHangs(t *testing.T) {
+	p := testProvisioner(t, map[string]interface{}{"restart_timeout": "50ms"})
+
+	comm := &fakeComm{bootID: "before", noRestart: true, hang: true}
+	err := p.Provision(context.Background(), packersdk.TestUi(t), comm, nil)
+	if err == nil || !strings.Contains(err.Error(), "Timeout waiting for machine to restart") {
+		t.Fatalf("expected a restart timeout, got: %v", err)
+	}
+	expected := []string{BootIDCommand, DefaultRestartCommand}
+	if strings.Join(comm.commands, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected the restart command to use up the restart timeout, got %q", comm.commands)
+	}
+}
+
+func TestProvisionerProvision_cancelled(t *testing.T) {
+	p := testProvisioner(t, map[string]interface{}{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	comm := &fakeComm{bootID: "before", noRestart: true, hang: true}
+	err := p.Provision(ctx, packersdk.TestUi(t), comm, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancellation error, got: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright IBM Corp. 2024, 2025
+// SPDX-License-Identifier: BUSL-1.1
+
+package restart
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/masterzen/winrm"
+)
+
+// PendingUpdateRebootKey is set by Windows Update while installed updates
+// wait for a reboot.
+const PendingUpdateRebootKey = "HKLM:SOFTWARE\\Microsoft\\Windows\\CurrentVersion\\WindowsUpdate\\Auto Update\\RebootRequired"
+
+// ReadyProbe is a condition to wait for once the machine restarted. Exactly
+// one of `service`, `port`, `file_exists`, `powershell_expr` and
+// `pending_reboot_clear` must be set.
+//
+// ```hcl
+//
+//	ready_probes {
+//	  service = "W3SVC"
+//	}
+//	ready_probes {
+//	  port    = 443
+//	  timeout = "2m"
+//	}
+//	ready_probes {
+//	  pending_reboot_clear = true
+//	}
+//
+// ```
+type ReadyProbe struct {
+	// Wait for the service with this name to be running.
+	Service string `mapstructure:"service"`
+	// Wait for this TCP port to accept connections on the machine.
+	Port int `mapstructure:"port"`
+	// Wait for this file or directory to exist.
+	FileExists string `mapstructure:"file_exists"`
+	// Wait for this PowerShell expression to evaluate to true.
+	PowershellExpr string `mapstructure:"powershell_expr"`
+	// Wait for the registry keys telling a reboot is pending, see
+	// `registry_keys`, and the key set by Windows Update to be cleared.
+	PendingRebootClear bool `mapstructure:"pending_reboot_clear"`
+	// How long to wait for the probe to succeed. Defaults to
+	// `restart_timeout`.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+func (r *ReadyProbe) validate() error {
+	set := 0
+	for _, ok := range []bool{r.Service != "", r.Port != 0, r.FileExists != "", r.PowershellExpr != "", r.PendingRebootClear} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("exactly one of service, port, file_exists, powershell_expr " +
+			"or pending_reboot_clear must be set")
+	}
+	if r.Port < 0 || r.Port > 65535 {
+		return fmt.Errorf("invalid port %d", r.Port)
+	}
+	if r.Timeout < 0 {
+		return errors.New("timeout must be positive")
+	}
+	return nil
+}
+
+// String describes the probe in the UI.
+func (r *ReadyProbe) String() string {
+	switch {
+	case r.Service != "":
+		return fmt.Sprintf("service %s running", r.Service)
+	case r.Port != 0:
+		return fmt.Sprintf("port %d open", r.Port)
+	case r.FileExists != "":
+		return fmt.Sprintf("file %s exists", r.FileExists)
+	case r.PowershellExpr != "":
+		return fmt.Sprintf("expression %s true", r.PowershellExpr)
+	default:
+		return "no pending reboot"
+	}
+}
+
+// expression returns the PowerShell expression that is true when the probe
+// succeeds.
+func (r *ReadyProbe) expression(registryKeys []string) string {
+	switch {
+	case r.Service != "":
+		return fmt.Sprintf("(Get-Service -Name %s -ErrorAction SilentlyContinue).Status -eq 'Running'", psQuote(r.Service))
+	case r.Port != 0:
+		return fmt.Sprintf("$c = New-Object Net.Sockets.TcpClient; "+
+			"try { $c.Connect('localhost', %d); $true } catch { $false } finally { $c.Dispose() }", r.Port)
+	case r.FileExists != "":
+		return fmt.Sprintf("Test-Path -LiteralPath %s", psQuote(r.FileExists))
+	case r.PowershellExpr != "":
+		return fmt.Sprintf("[bool](%s)", r.PowershellExpr)
+	default:
+		keys := append(append([]string{}, registryKeys...), PendingUpdateRebootKey)
+		tests := make([]string, len(keys))
+		for i, key := range keys {
+			tests[i] = fmt.Sprintf("(Test-Path %s)", psQuote(key))
+		}
+		return fmt.Sprintf("-not (%s)", strings.Join(tests, " -or "))
+	}
+}
+
+// waitForReadyProbes waits for each probe in turn, and reports how long
+// each one took.
+func (p *Provisioner) waitForReadyProbes(ctx context.Context) error {
+	if len(p.config.ReadyProbes) == 0 {
+		return nil
+	}
+
+	p.ui.Say(fmt.Sprintf("Waiting for %d readiness probe(s)...", len(p.config.ReadyProbes)))
+	for i := range p.config.ReadyProbes {
+		probe := &p.config.ReadyProbes[i]
+		start := time.Now()
+		if err := p.waitForReadyProbe(ctx, probe); err != nil {
+			p.ui.Error(fmt.Sprintf("Probe %q failed after %s: %s", probe, time.Since(start).Round(time.Second), err))
+			return fmt.Errorf("readiness probe %q failed: %s", probe, err)
+		}
+		p.ui.Say(fmt.Sprintf("Probe %q succeeded after %s", probe, time.Since(start).Round(time.Second)))
+	}
+	return nil
+}
+
+func (p *Provisioner) waitForReadyProbe(ctx context.Context, probe *ReadyProbe) error {
+	timeout := probe.Timeout
+	if timeout == 0 {
+		timeout = p.config.RestartTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	command := winrm.Powershell(probe.expression(p.config.RegistryKeys))
+	for {
+		var stdout bytes.Buffer
+		cmd := &packersdk.RemoteCmd{
+			Command: command,
+			Stdout:  &stdout,
+		}
+		err := p.comm.Start(ctx, cmd)
+		if err == nil {
+			cmd.Wait()
+			if strings.TrimSpace(stdout.String()) == "True" {
+				return nil
+			}
+			log.Printf("Probe %q not ready yet, got %q", probe, strings.TrimSpace(stdout.String()))
+		} else {
+			log.Printf("Communication connection err: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("not ready within %s", timeout)
+			}
+			return ctx.Err()
+		case <-time.After(retryableSleep):
+		}
+	}
+}
+
+// psQuote quotes s as a PowerShell string literal.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
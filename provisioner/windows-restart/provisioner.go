@@ -1,7 +1,7 @@
 // Copyright IBM Corp. 2024, 2025
 // SPDX-License-Identifier: BUSL-1.1
 
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,ReadyProbe
 
 package restart
 
@@ -57,6 +57,10 @@ type Config struct {
 	// custom keys to check for
 	RegistryKeys []string `mapstructure:"registry_keys"`
 
+	// Conditions to wait for, in order, once the machine restarted. See
+	// ReadyProbe.
+	ReadyProbes []ReadyProbe `mapstructure:"ready_probes"`
+
 	ctx interpolate.Context
 }
 
@@ -101,7 +105,14 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 		p.config.RegistryKeys = DefaultRegistryKeys
 	}
 
-	return nil
+	var errs error
+	for i, probe := range p.config.ReadyProbes {
+		if err := probe.validate(); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("ready_probes[%d]: %s", i, err))
+		}
+	}
+
+	return errs
 }
 
 func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator, _ map[string]interface{}) error {
@@ -128,7 +139,11 @@ func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, comm packe
 		return fmt.Errorf("Restart script exited with non-zero exit status: %d", cmd.ExitStatus())
 	}
 
-	return waitForRestart(ctx, p, comm)
+	if err := waitForRestart(ctx, p, comm); err != nil {
+		return err
+	}
+
+	return p.waitForReadyProbes(ctx)
 }
 
 var waitForRestart = func(ctx context.Context, p *Provisioner, comm packersdk.Communicator) error {
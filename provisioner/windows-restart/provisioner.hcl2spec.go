@@ -23,6 +23,7 @@ type FlatConfig struct {
 	RestartTimeout      *string           `mapstructure:"restart_timeout" cty:"restart_timeout" hcl:"restart_timeout"`
 	CheckKey            *bool             `mapstructure:"check_registry" cty:"check_registry" hcl:"check_registry"`
 	RegistryKeys        []string          `mapstructure:"registry_keys" cty:"registry_keys" hcl:"registry_keys"`
+	ReadyProbes         []FlatReadyProbe  `mapstructure:"ready_probes" cty:"ready_probes" hcl:"ready_probes"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -50,6 +51,40 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"restart_timeout":            &hcldec.AttrSpec{Name: "restart_timeout", Type: cty.String, Required: false},
 		"check_registry":             &hcldec.AttrSpec{Name: "check_registry", Type: cty.Bool, Required: false},
 		"registry_keys":              &hcldec.AttrSpec{Name: "registry_keys", Type: cty.List(cty.String), Required: false},
+		"ready_probes":               &hcldec.BlockListSpec{TypeName: "ready_probes", Nested: hcldec.ObjectSpec((*FlatReadyProbe)(nil).HCL2Spec())},
+	}
+	return s
+}
+
+// FlatReadyProbe is an auto-generated flat version of ReadyProbe.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatReadyProbe struct {
+	Service            *string `mapstructure:"service" cty:"service" hcl:"service"`
+	Port               *int    `mapstructure:"port" cty:"port" hcl:"port"`
+	FileExists         *string `mapstructure:"file_exists" cty:"file_exists" hcl:"file_exists"`
+	PowershellExpr     *string `mapstructure:"powershell_expr" cty:"powershell_expr" hcl:"powershell_expr"`
+	PendingRebootClear *bool   `mapstructure:"pending_reboot_clear" cty:"pending_reboot_clear" hcl:"pending_reboot_clear"`
+	Timeout            *string `mapstructure:"timeout" cty:"timeout" hcl:"timeout"`
+}
+
+// FlatMapstructure returns a new FlatReadyProbe.
+// FlatReadyProbe is an auto-generated flat version of ReadyProbe.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*ReadyProbe) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatReadyProbe)
+}
+
+// HCL2Spec returns the hcl spec of a ReadyProbe.
+// This spec is used by HCL to read the fields of ReadyProbe.
+// The decoded values from this spec will then be applied to a FlatReadyProbe.
+func (*FlatReadyProbe) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"service":              &hcldec.AttrSpec{Name: "service", Type: cty.String, Required: false},
+		"port":                 &hcldec.AttrSpec{Name: "port", Type: cty.Number, Required: false},
+		"file_exists":          &hcldec.AttrSpec{Name: "file_exists", Type: cty.String, Required: false},
+		"powershell_expr":      &hcldec.AttrSpec{Name: "powershell_expr", Type: cty.String, Required: false},
+		"pending_reboot_clear": &hcldec.AttrSpec{Name: "pending_reboot_clear", Type: cty.Bool, Required: false},
+		"timeout":              &hcldec.AttrSpec{Name: "timeout", Type: cty.String, Required: false},
 	}
 	return s
 }
@@ -7,6 +7,8 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"math"
+	"strings"
 	"testing"
 	"time"
 
@@ -336,3 +338,117 @@ func TestProvision_Cancel(t *testing.T) {
 		t.Fatal("should have error")
 	}
 }
+
+func TestProvisionerPrepare_ReadyProbes(t *testing.T) {
+	tests := []struct {
+		name    string
+		probes  []map[string]interface{}
+		wantErr bool
+	}{
+		{"valid", []map[string]interface{}{
+			{"service": "W3SVC"},
+			{"port": 443, "timeout": "2m"},
+			{"file_exists": `C:\ready`},
+			{"powershell_expr": "(Get-Date).Year -gt 2000"},
+			{"pending_reboot_clear": true},
+		}, false},
+		{"empty probe", []map[string]interface{}{{"timeout": "1m"}}, true},
+		{"two conditions", []map[string]interface{}{{"service": "W3SVC", "port": 80}}, true},
+		{"bad port", []map[string]interface{}{{"port": 70000}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Provisioner
+			config := testConfig()
+			config["ready_probes"] = tt.probes
+			err := p.Prepare(config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Prepare() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// probeComm answers readiness probes with False until it was asked
+// readyAfter times.
+type probeComm struct {
+	packersdk.MockCommunicator
+	readyAfter int
+	calls      int
+}
+
+func (c *probeComm) Start(ctx context.Context, cmd *packersdk.RemoteCmd) error {
+	c.calls++
+	answer := "False"
+	if c.calls > c.readyAfter {
+		answer = "True"
+	}
+	fmt.Fprintln(cmd.Stdout, answer)
+	cmd.SetExited(0)
+	return nil
+}
+
+func TestProvision_waitForReadyProbes(t *testing.T) {
+	retryableSleepOld := retryableSleep
+	retryableSleep = time.Millisecond
+	defer func() { retryableSleep = retryableSleepOld }()
+
+	ui := testUi()
+	p := new(Provisioner)
+	config := testConfig()
+	config["ready_probes"] = []map[string]interface{}{{"service": "W3SVC"}}
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	comm := &probeComm{readyAfter: 2}
+	p.comm = comm
+	p.ui = ui
+
+	if err := p.waitForReadyProbes(context.Background()); err != nil {
+		t.Fatalf("should not have error, got: %s", err)
+	}
+	if comm.calls != 3 {
+		t.Fatalf("expected the probe to run 3 times, got %d", comm.calls)
+	}
+	out := ui.Writer.(*bytes.Buffer).String()
+	if !strings.Contains(out, `Probe "service W3SVC running" succeeded`) {
+		t.Fatalf("expected the probe result to be reported, got: %s", out)
+	}
+}
+
+func TestProvision_waitForReadyProbesTimeout(t *testing.T) {
+	retryableSleepOld := retryableSleep
+	retryableSleep = time.Millisecond
+	defer func() { retryableSleep = retryableSleepOld }()
+
+	ui := testUi()
+	p := new(Provisioner)
+	config := testConfig()
+	config["ready_probes"] = []map[string]interface{}{
+		{"pending_reboot_clear": true, "timeout": "20ms"},
+		{"port": 443},
+	}
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	comm := &probeComm{readyAfter: math.MaxInt}
+	p.comm = comm
+	p.ui = ui
+
+	err := p.waitForReadyProbes(context.Background())
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	if !strings.Contains(err.Error(), "no pending reboot") {
+		t.Fatalf("expected the failing probe in the error, got: %s", err)
+	}
+}
+
+func TestReadyProbe_expression(t *testing.T) {
+	probe := ReadyProbe{PendingRebootClear: true}
+	expr := probe.expression([]string{`HKLM:SOFTWARE\Key's`})
+	expected := `-not ((Test-Path 'HKLM:SOFTWARE\Key''s') -or (Test-Path '` + PendingUpdateRebootKey + `'))`
+	if expr != expected {
+		t.Fatalf("expected %s, got %s", expected, expr)
+	}
+}